@@ -2,8 +2,12 @@ package config
 
 import (
 	"fmt"
+	"net"
 	"os"
+	"regexp"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
@@ -12,35 +16,327 @@ import (
 // Config holds all configuration for the intelligent router
 type Config struct {
 	// Server settings
-	RouterPort string
-	RouterHost string
+	RouterPort  string
+	RouterHost  string
+	TLSCertFile string
+	TLSKeyFile  string
+	H2CEnabled  bool
 
 	// ML Service settings
 	MLServiceURL       string
+	MLServiceURLs      []string // optional ensemble of ML services
+	MLEnsembleMethod   string
 	MLPredictEndpoint  string
 	MLQueryTimeout     time.Duration
+	ShadowMLServiceURL string // compared against production, never routed on
 
 	// Data Collector settings
-	DataCollectorURL string
-	MetricsEndpoint  string
-	HistoryLimit     int
+	DataCollectorURL  string
+	MetricsAPIVersion string // response format: v1 or v2
+	MetricsEndpoint   string
+	HistoryLimit      int
+
+	// Collector server-sent events stream of metric updates (polling when empty)
+	MetricsStreamURL       string
+	MetricsStreamReconnect time.Duration
 
 	// Fallback settings
 	FallbackRPCURL string
 	FallbackEnabled bool
 
+	// Last-resort node tried unconditionally once everything else has failed
+	LastResortNodeURL string
+
+	// Pause between failover attempts
+	FailoverBackoff time.Duration
+
+	// JSON-RPC error codes in a 200 response that are failed over to the
+	// next-best node
+	RetryableRPCCodes []int
+
+	// Path the JSON-RPC endpoint is served on, in addition to /
+	RPCPath string
+
 	// Request settings
 	RequestTimeout time.Duration
+	ValidateRPCID  bool
+
+	// Overall deadline shared by the ML query and the upstream call (0 disables)
+	TotalRequestBudget time.Duration
+
+	// Accept JSON-RPC over GET via the ?request= query parameter
+	AllowGetRPC bool
+
+	// Grace period for in-flight requests on shutdown
+	ShutdownTimeout time.Duration
+
+	// Probe the ML service and Data Collector before serving, for at most
+	// DependencyWaitTimeout
+	WaitForDependencies   bool
+	DependencyWaitTimeout time.Duration
+
+	// How long browsers may cache CORS preflight responses
+	CORSMaxAge time.Duration
+
+	// Upper bound on a streamed upstream response body (0 = unlimited)
+	MaxResponseBytes int64
+
+	// Add "_vigil" with the serving node to single JSON-RPC responses up to
+	// AnnotateResponseMaxBytes
+	AnnotateResponseNode     bool
+	AnnotateResponseMaxBytes int
+
+	// Gzip responses of at least CompressionMinBytes for clients that accept it
+	ResponseCompressionEnabled bool
+	CompressionMinBytes        int
+
+	// Upstream phase timeouts, each bounded by RequestTimeout (0 disables)
+	UpstreamDialTimeout           time.Duration
+	UpstreamTLSTimeout            time.Duration
+	UpstreamResponseHeaderTimeout time.Duration
+
+	// Upstream TLS settings
+	UpstreamInsecureSkipVerify bool
+	UpstreamCAFile             string
+	NodeInsecureSkipVerify     map[string]bool
+
+	// Keep connections to NodeKeepWarm nodes open with a request every
+	// KeepWarmInterval; ExcludeColdConnections leaves requests that had to
+	// open a new connection out of calibration and latency tracking
+	NodeKeepWarm           map[string]bool
+	KeepWarmInterval       time.Duration
+	ExcludeColdConnections bool
+
+	// Per-node basic-auth credentials ("user:pass")
+	NodeBasicAuth map[string]string
+
+	// Per-node cap on concurrent in-flight requests (0 = unlimited)
+	NodeMaxInflight map[string]int
+
+	// Cap on requests the router handles at once (0 = unlimited)
+	MaxGlobalInflight int
+
+	// Per-node cost per request, in (fractional) cents
+	NodeCost map[string]float64
+
+	// Per-node scheduled maintenance windows
+	NodeMaintenance map[string][]MaintenanceWindow
+
+	// Startup warm-up: route to a stable node while baseline samples accumulate
+	WarmupPeriod time.Duration
+	WarmupNode   string
+
+	// Routing strategy: "hybrid", or "priority" to always use the first
+	// available node in NodePriority
+	RoutingStrategy string
+	NodePriority    []string
+
+	// Scoring settings
+	SuccessRateWeight float64
+	TrustMLVerbatim   bool
+	OnDisagreement    string
+	MaxBlockHeightGap int
+	BlockGapPenalty   float64
+	UnhealthyPolicy   string
+	UnhealthyPenalty  float64
+	PredictionWeight  float64
+	RecentWeight      float64
+
+	// Latency SLA with automatic demotion (SLALatencyMS 0 disables)
+	SLALatencyMS          float64
+	SLAViolationThreshold int
+	SLAWindow             time.Duration
+	SLACooldown           time.Duration
+
+	// AIMD reliability weight applied to hybrid scores
+	ReliabilityWeighting      bool
+	ReliabilityDecreaseFactor float64
+	ReliabilityRecoveryStep   float64
+
+	// Drop duplicate-timestamp metrics per node
+	DedupMetrics bool
+
+	// Quick retries of a failed metrics fetch before scoring without it
+	MetricsFetchRetries      int
+	MetricsFetchRetryBackoff time.Duration
+
+	// Minimum hybrid score improvement needed to override the ML's pick
+	OverrideMinImprovementPercent float64
+
+	// Never let metrics-only fallback route to an unhealthy node
+	StrictFallback bool
+
+	// What to do when the ML flags every node anomalous
+	AllAnomalousPolicy string
+
+	// Debug-log full ML request and response bodies, capped at LogMLIOMaxBytes
+	LogMLIO         bool
+	LogMLIOMaxBytes int
+
+	// Reject ML responses not served as JSON, with the content type and the
+	// start of the body in the error
+	MLRequireJSON bool
+
+	// Adaptive ML timeout: p99 of recent ML latency times the factor, bounded
+	// below by the minimum and above by MLQueryTimeout
+	MLAdaptiveTimeout       bool
+	MLAdaptiveTimeoutFactor float64
+	MLAdaptiveTimeoutMin    time.Duration
+
+	// Calibration offset averaging: "window" or "ema" (smoothed by CalibrationAlpha)
+	CalibrationMode  string
+	CalibrationAlpha float64
+
+	// Pseudo-sample count blending sparse per-node offsets toward the global one
+	CalibrationShrinkage float64
+
+	// Warn (and optionally skip calibration) when a node's offset exceeds
+	// this fraction of its prediction (0 disables)
+	CalibrationMaxOffsetFraction float64
+	CalibrationSkipOutOfBounds   bool
+
+	// Fraction of requests recorded for calibration
+	CalibrationSampleRate float64
+
+	// Routing hysteresis
+	StickinessBonus float64
+	SwitchMargin    float64
+
+	// Soft preference for one node (e.g. self-hosted) within a score tolerance
+	PreferredNode                 string
+	PreferredNodeTolerancePercent float64
+
+	// Request hedging: race read methods across the top HedgingFanout nodes
+	HedgingEnabled bool
+	HedgingFanout  int
+	HedgingMethods []string
+
+	// Canary: send a fixed share of traffic to a node under evaluation
+	CanaryNode    string
+	CanaryPercent float64
+
+	// Shadow forwarding: copy a sampled share of read requests to a node that
+	// is never routed to, to evaluate it without client impact
+	ShadowForwardNode    string
+	ShadowForwardPercent float64
+
+	// Node pools: NODE_POOL_<NODE_ID> assigns a pool, NodePoolOrder lists pools
+	// by preference, and a pool whose best node is predicted slower than the
+	// threshold is skipped
+	NodePools                  map[string]string
+	NodePoolOrder              []string
+	NodePoolLatencyThresholdMS float64
 
 	// Logging
 	LogLevel  string
 	LogFormat string
 
-	// Health check
+	// Log file rotation (stdout when LogFile is empty)
+	LogFile       string
+	LogMaxSizeMB  int
+	LogMaxBackups int
+	LogMaxAgeDays int
+
+	// Repeats of a hot-path warning within this interval are summarized (0 disables)
+	LogRateLimitInterval time.Duration
+
+	// Health check; HealthIncludeNodes adds a node summary to /health
 	HealthCheckEnabled bool
+	HealthIncludeNodes bool
+
+	// Route a real getHealth from /health, at most once per interval
+	DeepHealthCheck         bool
+	DeepHealthCheckInterval time.Duration
+
+	// Readiness needs at least MinHealthyNodes healthy nodes (0 disables);
+	// MinHealthyNodesReject also refuses requests below the threshold
+	MinHealthyNodes       int
+	MinHealthyNodesReject bool
+
+	// Append-only routing audit log (disabled when empty), fsynced on an interval
+	AuditLogFile       string
+	AuditFsyncInterval time.Duration
+
+	// Routing decision export to Kafka (disabled when KafkaBrokers is empty)
+	KafkaBrokers       []string
+	KafkaTopic         string
+	KafkaBufferSize    int
+	KafkaFlushInterval time.Duration
+
+	// Routing decision log file (disabled when empty), alongside or instead
+	// of Kafka. DecisionLogFormat is "json" or "influx" (line protocol) for
+	// both sinks.
+	DecisionLogFile       string
+	DecisionLogFormat     string
+	DecisionBufferSize    int
+	DecisionFlushInterval time.Duration
+
+	// StatsD metrics push (disabled when StatsDAddr is empty)
+	StatsDAddr          string
+	StatsDPrefix        string
+	StatsDFlushInterval time.Duration
+
+	// Admin API (disabled when empty)
+	AdminToken string
+
+	// Expose /debug/pprof/ (requires AdminToken)
+	PprofEnabled bool
+
+	// Peers allowed to set X-Forwarded-For / X-Real-IP
+	TrustedProxies []*net.IPNet
+
+	// Randomize background task intervals by up to ±this percent
+	IntervalJitterPercent float64
 
 	// Node URL mappings
 	NodeURLMap map[string]string
+
+	// Serve the last good response to a read-only request when every upstream fails
+	StaleOnErrorEnabled    bool
+	StaleOnErrorTTL        time.Duration
+	StaleOnErrorMaxEntries int
+
+	// Route signature status queries to the node the transaction was sent to
+	SignatureAffinityEnabled bool
+	SignatureAffinityTTL     time.Duration
+
+	// Send the request's X-Request-ID to the ML service and Data Collector
+	PropagateRequestID bool
+
+	// Forward GET requests upstream as GET instead of converting them to POST
+	PreserveMethod bool
+
+	// Fail startup when no node has a URL instead of falling back on every request
+	RequireNodeMap bool
+
+	// Per-method node pins (JSON-RPC method -> node ID)
+	MethodNodeOverrides map[string]string
+
+	// Learned per-method routing: the node fastest for a method wins when it
+	// scores within MethodAffinityBonus of the best node (0 disables)
+	MethodAffinityBonus      float64
+	MethodAffinityMinSamples int
+
+	// Minimum node version per JSON-RPC method, checked via getVersion
+	MethodMinVersions    map[string]string
+	VersionCheckInterval time.Duration
+
+	// JSON-RPC method allow/deny lists; MethodBatchPolicy is "reject" (whole
+	// batch) or "partial" (forward only the permitted elements)
+	AllowedMethods    []string
+	DeniedMethods     []string
+	MethodBatchPolicy string
+
+	// Upstream response headers passed to (allowlist) or stripped from
+	// (denylist) client responses; a trailing '*' matches a prefix
+	ResponseHeaderAllowlist []string
+	ResponseHeaderDenylist  []string
+
+	// Per-tenant routing policies, keyed by lower-cased tenant name
+	Tenants map[string]TenantPolicy
+
+	// ML explanation categories tallied in /stats, keyed by lower-cased name
+	ExplanationCategories map[string]*regexp.Regexp
 }
 
 // Load loads configuration from environment variables
@@ -48,23 +344,208 @@ func Load() (*Config, error) {
 	// Try to load .env file (ignore error if it doesn't exist)
 	_ = godotenv.Load()
 
+	// Optional structured config file; environment variables take precedence
+	if err := loadConfigFile(); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+
 	config := &Config{
 		RouterPort:         getEnv("ROUTER_PORT", "8080"),
 		RouterHost:         getEnv("ROUTER_HOST", "0.0.0.0"),
+		TLSCertFile:        os.Getenv("TLS_CERT_FILE"),
+		TLSKeyFile:         os.Getenv("TLS_KEY_FILE"),
+		H2CEnabled:         getEnvBool("H2C_ENABLED", false),
 		MLServiceURL:       getEnv("ML_SERVICE_URL", "http://localhost:8001"),
 		MLPredictEndpoint:  getEnv("ML_PREDICT_ENDPOINT", "/predict"),
+		MLServiceURLs:      getEnvList("ML_SERVICE_URLS"),
+		ShadowMLServiceURL: os.Getenv("SHADOW_ML_SERVICE_URL"),
+		MLEnsembleMethod:   getEnv("ML_ENSEMBLE_METHOD", "average"),
 		DataCollectorURL:   getEnv("DATA_COLLECTOR_URL", "http://localhost:8000"),
-		MetricsEndpoint:    getEnv("METRICS_ENDPOINT", "/api/v1/metrics/history"),
+		MetricsAPIVersion:  getEnv("METRICS_API_VERSION", "v1"),
+		MetricsEndpoint:    os.Getenv("METRICS_ENDPOINT"),
 		HistoryLimit:       20,
+		MetricsStreamURL:       os.Getenv("METRICS_STREAM_URL"),
+		MetricsStreamReconnect: getEnvDuration("METRICS_STREAM_RECONNECT_SECONDS", 5),
 		FallbackRPCURL:     getEnv("FALLBACK_RPC_URL", "https://api.devnet.solana.com"),
 		FallbackEnabled:    getEnvBool("FALLBACK_ENABLED", true),
+		LastResortNodeURL:  os.Getenv("LAST_RESORT_NODE_URL"),
+		FailoverBackoff:    time.Duration(getEnvInt("FAILOVER_BACKOFF_MS", 0)) * time.Millisecond,
+		RPCPath:            getEnv("RPC_PATH", "/rpc"),
 		RequestTimeout:     getEnvDuration("REQUEST_TIMEOUT_SECONDS", 30),
+		TotalRequestBudget: getEnvDuration("TOTAL_REQUEST_BUDGET_SECONDS", 0),
+		ValidateRPCID:      getEnvBool("VALIDATE_RPC_ID", false),
+		ShutdownTimeout:    getEnvDuration("SHUTDOWN_TIMEOUT_SECONDS", 15),
+		WaitForDependencies:   getEnvBool("WAIT_FOR_DEPENDENCIES", false),
+		DependencyWaitTimeout: getEnvDuration("DEPENDENCY_WAIT_TIMEOUT_SECONDS", 60),
+		CORSMaxAge:         getEnvDuration("CORS_MAX_AGE_SECONDS", 86400),
+		AllowGetRPC:        getEnvBool("ALLOW_GET_RPC", false),
+		MaxResponseBytes:   int64(getEnvInt("MAX_RESPONSE_BYTES", 0)),
+		AnnotateResponseNode:     getEnvBool("ANNOTATE_RESPONSE_NODE", false),
+		AnnotateResponseMaxBytes: getEnvInt("ANNOTATE_RESPONSE_MAX_BYTES", 65536),
+		ResponseCompressionEnabled: getEnvBool("RESPONSE_COMPRESSION_ENABLED", false),
+		CompressionMinBytes:        getEnvInt("COMPRESSION_MIN_BYTES", 1024),
 		MLQueryTimeout:     getEnvDuration("ML_QUERY_TIMEOUT_SECONDS", 5),
+		WarmupPeriod:       getEnvDuration("WARMUP_SECONDS", 0),
+		WarmupNode:         os.Getenv("WARMUP_NODE"),
+		RoutingStrategy:    getEnv("ROUTING_STRATEGY", "hybrid"),
+		NodePriority:       getEnvList("NODE_PRIORITY"),
+		SuccessRateWeight:  getEnvFloat("SUCCESS_RATE_WEIGHT", 0),
+		TrustMLVerbatim:    getEnvBool("TRUST_ML_VERBATIM", false),
+		OnDisagreement:     getEnv("ON_DISAGREEMENT", "prefer_hybrid"),
+		MaxBlockHeightGap:  getEnvInt("MAX_BLOCK_HEIGHT_GAP", 0),
+		BlockGapPenalty:    getEnvFloat("BLOCK_GAP_PENALTY", 0),
+		UnhealthyPolicy:    getEnv("UNHEALTHY_NODE_POLICY", "exclude"),
+		UnhealthyPenalty:   getEnvFloat("UNHEALTHY_PENALTY", 1000),
+		PredictionWeight:   getEnvFloat("PREDICTION_WEIGHT", 0.7),
+		RecentWeight:       getEnvFloat("RECENT_WEIGHT", 0.3),
+		SLALatencyMS:       getEnvFloat("NODE_SLA_LATENCY_MS", 0),
+		SLAWindow:          getEnvDuration("NODE_SLA_WINDOW_SECONDS", 60),
+		SLACooldown:        getEnvDuration("NODE_SLA_COOLDOWN_SECONDS", 300),
+		SLAViolationThreshold: getEnvInt("NODE_SLA_VIOLATIONS_THRESHOLD", 5),
+		CalibrationSampleRate: getEnvFloat("CALIBRATION_SAMPLE_RATE", 1.0),
+		CalibrationMode:       getEnv("CALIBRATION_MODE", "window"),
+		CalibrationAlpha:      getEnvFloat("CALIBRATION_ALPHA", 0.1),
+		CalibrationShrinkage:  getEnvFloat("CALIBRATION_SHRINKAGE", 0),
+		CalibrationMaxOffsetFraction: getEnvFloat("CALIBRATION_MAX_OFFSET_FRACTION", 1.0),
+		CalibrationSkipOutOfBounds:   getEnvBool("CALIBRATION_SKIP_OUT_OF_BOUNDS", false),
+		DedupMetrics:          getEnvBool("METRICS_DEDUP_ENABLED", true),
+		MetricsFetchRetries:      getEnvInt("METRICS_FETCH_RETRIES", 0),
+		MetricsFetchRetryBackoff: time.Duration(getEnvInt("METRICS_FETCH_RETRY_BACKOFF_MS", 50)) * time.Millisecond,
+		OverrideMinImprovementPercent: getEnvFloat("OVERRIDE_MIN_IMPROVEMENT_PERCENT", 0),
+		StrictFallback:        getEnvBool("STRICT_FALLBACK", false),
+		AllAnomalousPolicy:    getEnv("ALL_ANOMALOUS_POLICY", "proceed"),
+		LogMLIO:               getEnvBool("LOG_ML_IO", false),
+		LogMLIOMaxBytes:       getEnvInt("LOG_ML_IO_MAX_BYTES", 65536),
+		MLRequireJSON:         getEnvBool("ML_REQUIRE_JSON", false),
+		MLAdaptiveTimeout:       getEnvBool("ML_ADAPTIVE_TIMEOUT_ENABLED", false),
+		MLAdaptiveTimeoutFactor: getEnvFloat("ML_ADAPTIVE_TIMEOUT_FACTOR", 3),
+		MLAdaptiveTimeoutMin:    time.Duration(getEnvInt("ML_ADAPTIVE_TIMEOUT_MIN_MS", 200)) * time.Millisecond,
+		ReliabilityWeighting:      getEnvBool("RELIABILITY_WEIGHTING_ENABLED", false),
+		ReliabilityDecreaseFactor: getEnvFloat("RELIABILITY_DECREASE_FACTOR", 0.5),
+		ReliabilityRecoveryStep:   getEnvFloat("RELIABILITY_RECOVERY_STEP", 0.05),
+		PreferredNodeTolerancePercent: getEnvFloat("PREFERRED_NODE_TOLERANCE_PERCENT", 10),
+		StickinessBonus:    getEnvFloat("STICKINESS_BONUS", 0),
+		SwitchMargin:       getEnvFloat("SWITCH_MARGIN", 0),
+		PreferredNode:      os.Getenv("PREFERRED_NODE"),
+		HedgingEnabled:     getEnvBool("HEDGING_ENABLED", false),
+		HedgingFanout:      getEnvInt("HEDGING_FANOUT", 2),
+		HedgingMethods:     getEnvList("HEDGING_METHODS"),
+		CanaryNode:         os.Getenv("CANARY_NODE"),
+		CanaryPercent:      getEnvFloat("CANARY_PERCENT", 0),
+		ShadowForwardNode:    os.Getenv("SHADOW_FORWARD_NODE"),
+		ShadowForwardPercent: getEnvFloat("SHADOW_FORWARD_PERCENT", 0),
+		NodePoolOrder:      getEnvList("NODE_POOL_ORDER"),
+		NodePoolLatencyThresholdMS: getEnvFloat("NODE_POOL_LATENCY_THRESHOLD_MS", 0),
 		LogLevel:           getEnv("LOG_LEVEL", "info"),
 		LogFormat:          getEnv("LOG_FORMAT", "json"),
+		LogFile:            os.Getenv("LOG_FILE"),
+		LogMaxSizeMB:       getEnvInt("LOG_MAX_SIZE_MB", 100),
+		LogMaxBackups:      getEnvInt("LOG_MAX_BACKUPS", 5),
+		LogMaxAgeDays:      getEnvInt("LOG_MAX_AGE_DAYS", 30),
+		LogRateLimitInterval: getEnvDuration("LOG_RATE_LIMIT_SECONDS", 10),
 		HealthCheckEnabled: getEnvBool("HEALTH_CHECK_ENABLED", true),
+		HealthIncludeNodes: getEnvBool("HEALTH_INCLUDE_NODES", false),
+		DeepHealthCheck:         getEnvBool("DEEP_HEALTH_CHECK", false),
+		DeepHealthCheckInterval: getEnvDuration("DEEP_HEALTH_CHECK_INTERVAL_SECONDS", 10),
+		MinHealthyNodes:       getEnvInt("MIN_HEALTHY_NODES", 0),
+		MinHealthyNodesReject: getEnvBool("MIN_HEALTHY_NODES_REJECT", false),
+		AuditLogFile:        os.Getenv("AUDIT_LOG_FILE"),
+		AuditFsyncInterval:  getEnvDuration("AUDIT_FSYNC_INTERVAL_SECONDS", 1),
+		KafkaBrokers:        getEnvList("KAFKA_BROKERS"),
+		KafkaTopic:          os.Getenv("KAFKA_TOPIC"),
+		KafkaBufferSize:     getEnvInt("KAFKA_BUFFER_SIZE", 1000),
+		KafkaFlushInterval:  getEnvDuration("KAFKA_FLUSH_INTERVAL_SECONDS", 1),
+		DecisionLogFile:     os.Getenv("DECISION_LOG_FILE"),
+		DecisionLogFormat:   getEnv("DECISION_LOG_FORMAT", "json"),
+		DecisionBufferSize:  getEnvInt("DECISION_BUFFER_SIZE", 1000),
+		DecisionFlushInterval: getEnvDuration("DECISION_FLUSH_INTERVAL_SECONDS", 1),
+		StatsDAddr:          os.Getenv("STATSD_ADDR"),
+		StatsDPrefix:        getEnv("STATSD_PREFIX", "vigil"),
+		StatsDFlushInterval: getEnvDuration("STATSD_FLUSH_INTERVAL_SECONDS", 10),
+		AdminToken:         os.Getenv("ADMIN_TOKEN"),
+		PprofEnabled:       getEnvBool("PPROF_ENABLED", false),
+		IntervalJitterPercent: getEnvFloat("INTERVAL_JITTER_PERCENT", 0),
 		NodeURLMap:         loadNodeURLMap(),
+		RequireNodeMap:     getEnvBool("REQUIRE_NODE_MAP", false),
+		MaxGlobalInflight:  getEnvInt("MAX_GLOBAL_INFLIGHT", 0),
+		PreserveMethod:     getEnvBool("PRESERVE_METHOD", false),
+		PropagateRequestID: getEnvBool("PROPAGATE_REQUEST_ID", false),
+		StaleOnErrorEnabled:    getEnvBool("STALE_ON_ERROR_ENABLED", false),
+		StaleOnErrorTTL:        getEnvDuration("STALE_ON_ERROR_TTL_SECONDS", 30),
+		StaleOnErrorMaxEntries: getEnvInt("STALE_ON_ERROR_MAX_ENTRIES", 1000),
+		SignatureAffinityEnabled: getEnvBool("SIGNATURE_AFFINITY_ENABLED", false),
+		SignatureAffinityTTL:     getEnvDuration("SIGNATURE_AFFINITY_TTL_SECONDS", 120),
+		MethodNodeOverrides: loadPrefixedEnv("METHOD_NODE_"),
+		MethodAffinityBonus:      getEnvFloat("METHOD_AFFINITY_BONUS", 0),
+		MethodAffinityMinSamples: getEnvInt("METHOD_AFFINITY_MIN_SAMPLES", 20),
+		MethodMinVersions:   loadPrefixedEnv("METHOD_MIN_VERSION_"),
+		VersionCheckInterval: getEnvDuration("VERSION_CHECK_INTERVAL_SECONDS", 300),
+		AllowedMethods:      getEnvList("ALLOWED_METHODS"),
+		DeniedMethods:       getEnvList("DENIED_METHODS"),
+		ResponseHeaderAllowlist: getEnvList("RESPONSE_HEADER_ALLOWLIST"),
+		ResponseHeaderDenylist:  getEnvList("RESPONSE_HEADER_DENYLIST"),
+		MethodBatchPolicy:   getEnv("METHOD_BATCH_POLICY", "reject"),
+		UpstreamInsecureSkipVerify: getEnvBool("UPSTREAM_INSECURE_SKIP_VERIFY", false),
+		UpstreamCAFile:             os.Getenv("UPSTREAM_CA_FILE"),
+		UpstreamDialTimeout:           getEnvTimeout("UPSTREAM_DIAL_TIMEOUT", 5*time.Second),
+		UpstreamTLSTimeout:            getEnvTimeout("UPSTREAM_TLS_TIMEOUT", 10*time.Second),
+		UpstreamResponseHeaderTimeout: getEnvTimeout("UPSTREAM_RESPONSE_HEADER_TIMEOUT", 0),
+		KeepWarmInterval:              getEnvDuration("KEEP_WARM_INTERVAL_SECONDS", 30),
+		ExcludeColdConnections:        getEnvBool("EXCLUDE_COLD_CONNECTIONS", false),
+	}
+	// A shorter REQUEST_TIMEOUT_SECONDS pulls in the default phase timeouts;
+	// explicitly set ones are left for Validate to check
+	if config.RequestTimeout > 0 {
+		for key, timeout := range map[string]*time.Duration{
+			"UPSTREAM_DIAL_TIMEOUT": &config.UpstreamDialTimeout,
+			"UPSTREAM_TLS_TIMEOUT":  &config.UpstreamTLSTimeout,
+		} {
+			if os.Getenv(key) == "" && *timeout > config.RequestTimeout {
+				*timeout = config.RequestTimeout
+			}
+		}
+	}
+	config.NodeInsecureSkipVerify = loadPerNodeBool("NODE_INSECURE_SKIP_VERIFY_", config.NodeURLMap)
+	config.NodeBasicAuth = loadPerNodeString("NODE_BASIC_AUTH_", config.NodeURLMap)
+	config.NodeKeepWarm = loadPerNodeBool("NODE_KEEP_WARM_", config.NodeURLMap)
+	config.NodeMaxInflight = loadPerNodeInt("NODE_MAX_INFLIGHT_", config.NodeURLMap)
+	config.NodeCost = loadPerNodeFloat("NODE_COST_", config.NodeURLMap)
+	config.NodePools = loadPerNodeString("NODE_POOL_", config.NodeURLMap)
+
+	// Each collector API version has its own default path
+	if config.MetricsEndpoint == "" {
+		config.MetricsEndpoint = defaultMetricsEndpoints[config.MetricsAPIVersion]
+	}
+
+	maintenance, err := loadMaintenanceWindows(config.NodeURLMap)
+	if err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+	config.NodeMaintenance = maintenance
+
+	retryableCodes, err := parseIntList(getEnvList("RETRYABLE_RPC_CODES"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid configuration: RETRYABLE_RPC_CODES: %w", err)
+	}
+	config.RetryableRPCCodes = retryableCodes
+
+	trustedProxies, err := parseCIDRList(getEnvList("TRUSTED_PROXIES"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid configuration: TRUSTED_PROXIES: %w", err)
+	}
+	config.TrustedProxies = trustedProxies
+
+	tenants, err := loadTenants()
+	if err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
 	}
+	config.Tenants = tenants
+
+	categories, err := loadExplanationCategories()
+	if err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+	config.ExplanationCategories = categories
 
 	if err := config.Validate(); err != nil {
 		return nil, fmt.Errorf("invalid configuration: %w", err)
@@ -73,6 +554,17 @@ func Load() (*Config, error) {
 	return config, nil
 }
 
+// configuredNodeCount returns how many nodes have a non-empty URL
+func (c *Config) configuredNodeCount() int {
+	count := 0
+	for _, url := range c.NodeURLMap {
+		if url != "" {
+			count++
+		}
+	}
+	return count
+}
+
 // loadNodeURLMap loads node ID to RPC URL mappings from environment
 func loadNodeURLMap() map[string]string {
 	nodeMap := make(map[string]string)
@@ -110,33 +602,519 @@ func loadNodeURLMap() map[string]string {
 		nodeMap["agave_self_hosted"] = getEnv("AGAVE_SELF_HOSTED_RPC_URL", "https://api.devnet.solana.com")
 	}
 	
+	// Any other NODE_URL_<NODE_ID> adds a node
+	loadExtraNodeURLs(nodeMap)
+	
 	return nodeMap
 }
 
+// loadPrefixedEnv collects all environment variables starting with prefix into a
+// map keyed by the remainder of the name, e.g. METHOD_NODE_getBlock=helius_devnet
+// yields {"getBlock": "helius_devnet"}. Keys keep their original case.
+func loadPrefixedEnv(prefix string) map[string]string {
+	values := make(map[string]string)
+	for _, kv := range os.Environ() {
+		key, value, found := strings.Cut(kv, "=")
+		if !found || !strings.HasPrefix(key, prefix) || value == "" {
+			continue
+		}
+		values[strings.TrimPrefix(key, prefix)] = value
+	}
+	return values
+}
+
+// loadPerNodeString reads <prefix><NODE_ID> for every configured node, where
+// NODE_ID is the upper-cased node ID. Unset values are skipped.
+func loadPerNodeString(prefix string, nodeMap map[string]string) map[string]string {
+	values := make(map[string]string)
+	for nodeID := range nodeMap {
+		if value := os.Getenv(prefix + strings.ToUpper(nodeID)); value != "" {
+			values[nodeID] = value
+		}
+	}
+	return values
+}
+
+// parseIntList parses a list of integers such as JSON-RPC error codes
+func parseIntList(entries []string) ([]int, error) {
+	values := make([]int, 0, len(entries))
+	for _, entry := range entries {
+		value, err := strconv.Atoi(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid integer %q", entry)
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+// loadExplanationCategories compiles EXPLANATION_CATEGORY_<NAME>=<regex> entries
+func loadExplanationCategories() (map[string]*regexp.Regexp, error) {
+	categories := make(map[string]*regexp.Regexp)
+	for name, pattern := range loadPrefixedEnv("EXPLANATION_CATEGORY_") {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("EXPLANATION_CATEGORY_%s: %w", name, err)
+		}
+		categories[strings.ToLower(name)] = re
+	}
+	return categories, nil
+}
+
+// parseCIDRList parses CIDR ranges, accepting bare IPs as single-host ranges
+func parseCIDRList(entries []string) ([]*net.IPNet, error) {
+	networks := make([]*net.IPNet, 0, len(entries))
+	for _, entry := range entries {
+		if !strings.Contains(entry, "/") {
+			ip := net.ParseIP(entry)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid IP %q", entry)
+			}
+			bits := 128
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 32
+			}
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR %q", entry)
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
+
+// loadPerNodeFloat reads <prefix><NODE_ID> for every configured node, where
+// NODE_ID is the upper-cased node ID. Unset or unparsable values are skipped.
+func loadPerNodeFloat(prefix string, nodeMap map[string]string) map[string]float64 {
+	values := make(map[string]float64)
+	for nodeID := range nodeMap {
+		value := os.Getenv(prefix + strings.ToUpper(nodeID))
+		if value == "" {
+			continue
+		}
+		if floatVal, err := strconv.ParseFloat(value, 64); err == nil {
+			values[nodeID] = floatVal
+		}
+	}
+	return values
+}
+
+// loadPerNodeInt reads <prefix><NODE_ID> for every configured node, where
+// NODE_ID is the upper-cased node ID. Unset or unparsable values are skipped.
+func loadPerNodeInt(prefix string, nodeMap map[string]string) map[string]int {
+	values := make(map[string]int)
+	for nodeID := range nodeMap {
+		value := os.Getenv(prefix + strings.ToUpper(nodeID))
+		if value == "" {
+			continue
+		}
+		if intVal, err := strconv.Atoi(value); err == nil {
+			values[nodeID] = intVal
+		}
+	}
+	return values
+}
+
+// loadPerNodeBool reads <prefix><NODE_ID> for every configured node, where
+// NODE_ID is the upper-cased node ID. Unset or unparsable values are skipped.
+func loadPerNodeBool(prefix string, nodeMap map[string]string) map[string]bool {
+	values := make(map[string]bool)
+	for nodeID := range nodeMap {
+		value := os.Getenv(prefix + strings.ToUpper(nodeID))
+		if value == "" {
+			continue
+		}
+		if boolVal, err := strconv.ParseBool(value); err == nil {
+			values[nodeID] = boolVal
+		}
+	}
+	return values
+}
+
 // Validate checks if the configuration is valid
 func (c *Config) Validate() error {
 	if c.MLServiceURL == "" {
 		return fmt.Errorf("ML_SERVICE_URL is required")
 	}
+	if _, ok := defaultMetricsEndpoints[c.MetricsAPIVersion]; !ok {
+		return fmt.Errorf("METRICS_API_VERSION must be v1 or v2")
+	}
+	if c.MLEnsembleMethod != "average" && c.MLEnsembleMethod != "vote" {
+		return fmt.Errorf("ML_ENSEMBLE_METHOD must be average or vote")
+	}
 	if c.DataCollectorURL == "" {
 		return fmt.Errorf("DATA_COLLECTOR_URL is required")
 	}
+	if c.RequireNodeMap && c.configuredNodeCount() == 0 {
+		return fmt.Errorf("no nodes are configured: set NODE_URL_<NODE_ID> for at least one node, or unset REQUIRE_NODE_MAP")
+	}
+	if c.StaleOnErrorEnabled && c.StaleOnErrorMaxEntries <= 0 {
+		return fmt.Errorf("STALE_ON_ERROR_MAX_ENTRIES must be positive")
+	}
+	if c.SignatureAffinityEnabled && c.SignatureAffinityTTL <= 0 {
+		return fmt.Errorf("SIGNATURE_AFFINITY_TTL_SECONDS must be positive")
+	}
+	if c.MetricsFetchRetries < 0 || c.MetricsFetchRetryBackoff < 0 {
+		return fmt.Errorf("METRICS_FETCH_RETRIES and METRICS_FETCH_RETRY_BACKOFF_MS must not be negative")
+	}
+	if len(c.NodeKeepWarm) > 0 && c.KeepWarmInterval <= 0 {
+		return fmt.Errorf("KEEP_WARM_INTERVAL_SECONDS must be positive")
+	}
+	if c.DeepHealthCheck && c.DeepHealthCheckInterval <= 0 {
+		return fmt.Errorf("DEEP_HEALTH_CHECK_INTERVAL_SECONDS must be positive")
+	}
+	if c.MaxGlobalInflight < 0 {
+		return fmt.Errorf("MAX_GLOBAL_INFLIGHT must not be negative")
+	}
+	if c.IntervalJitterPercent < 0 || c.IntervalJitterPercent >= 100 {
+		return fmt.Errorf("INTERVAL_JITTER_PERCENT must be in [0, 100)")
+	}
 	if c.FallbackEnabled && c.FallbackRPCURL == "" {
 		return fmt.Errorf("FALLBACK_RPC_URL is required when fallback is enabled")
 	}
+	if !strings.HasPrefix(c.RPCPath, "/") || c.RPCPath == "/" {
+		return fmt.Errorf("RPC_PATH must start with / and not be / itself")
+	}
+	if reservedPath(c.RPCPath) {
+		return fmt.Errorf("RPC_PATH %s is reserved for a built-in endpoint", c.RPCPath)
+	}
+	if c.CompressionMinBytes < 0 {
+		return fmt.Errorf("COMPRESSION_MIN_BYTES must not be negative")
+	}
+	if c.FailoverBackoff < 0 {
+		return fmt.Errorf("FAILOVER_BACKOFF_MS must not be negative")
+	}
+	if c.ShutdownTimeout <= 0 {
+		return fmt.Errorf("SHUTDOWN_TIMEOUT_SECONDS must be positive")
+	}
+	if c.SuccessRateWeight < 0 || c.SuccessRateWeight > 1 {
+		return fmt.Errorf("SUCCESS_RATE_WEIGHT must be between 0 and 1")
+	}
+	if c.MaxBlockHeightGap < 0 || c.BlockGapPenalty < 0 {
+		return fmt.Errorf("MAX_BLOCK_HEIGHT_GAP and BLOCK_GAP_PENALTY must not be negative")
+	}
+	if c.PredictionWeight < 0 || c.RecentWeight < 0 || c.PredictionWeight+c.RecentWeight == 0 {
+		return fmt.Errorf("PREDICTION_WEIGHT and RECENT_WEIGHT must not be negative or both zero")
+	}
+	switch c.UnhealthyPolicy {
+	case "exclude", "penalize":
+	default:
+		return fmt.Errorf("UNHEALTHY_NODE_POLICY must be exclude or penalize")
+	}
+	if c.SLALatencyMS < 0 || c.SLAViolationThreshold < 0 {
+		return fmt.Errorf("NODE_SLA_LATENCY_MS and NODE_SLA_VIOLATIONS_THRESHOLD must not be negative")
+	}
+	if c.SLALatencyMS > 0 && (c.SLAWindow <= 0 || c.SLACooldown <= 0) {
+		return fmt.Errorf("NODE_SLA_WINDOW_SECONDS and NODE_SLA_COOLDOWN_SECONDS must be positive")
+	}
+	if c.CalibrationSampleRate < 0 || c.CalibrationSampleRate > 1 {
+		return fmt.Errorf("CALIBRATION_SAMPLE_RATE must be between 0 and 1")
+	}
+	if c.UnhealthyPenalty < 0 {
+		return fmt.Errorf("UNHEALTHY_PENALTY must not be negative")
+	}
+	if c.StickinessBonus < 0 || c.SwitchMargin < 0 {
+		return fmt.Errorf("STICKINESS_BONUS and SWITCH_MARGIN must not be negative")
+	}
+	switch c.OnDisagreement {
+	case "prefer_hybrid", "prefer_ml", "log_only":
+	default:
+		return fmt.Errorf("ON_DISAGREEMENT must be prefer_hybrid, prefer_ml or log_only")
+	}
+	switch c.RoutingStrategy {
+	case "hybrid":
+	case "priority":
+		if len(c.NodePriority) == 0 {
+			return fmt.Errorf("NODE_PRIORITY is required when ROUTING_STRATEGY is priority")
+		}
+		for _, nodeID := range c.NodePriority {
+			if _, ok := c.NodeURLMap[nodeID]; !ok {
+				return fmt.Errorf("NODE_PRIORITY references unknown node %q", nodeID)
+			}
+		}
+	default:
+		return fmt.Errorf("ROUTING_STRATEGY must be hybrid or priority")
+	}
+	switch c.AllAnomalousPolicy {
+	case "proceed", "fallback", "degraded":
+	default:
+		return fmt.Errorf("ALL_ANOMALOUS_POLICY must be proceed, fallback or degraded")
+	}
+	if c.WarmupNode != "" {
+		if _, ok := c.NodeURLMap[c.WarmupNode]; !ok {
+			return fmt.Errorf("WARMUP_NODE references unknown node %q", c.WarmupNode)
+		}
+	}
+	if c.PreferredNode != "" {
+		if _, ok := c.NodeURLMap[c.PreferredNode]; !ok {
+			return fmt.Errorf("PREFERRED_NODE references unknown node %q", c.PreferredNode)
+		}
+	}
+	if c.PreferredNodeTolerancePercent < 0 {
+		return fmt.Errorf("PREFERRED_NODE_TOLERANCE_PERCENT must not be negative")
+	}
+	if c.HedgingEnabled && c.HedgingFanout < 2 {
+		return fmt.Errorf("HEDGING_FANOUT must be at least 2 when hedging is enabled")
+	}
+	if c.CanaryPercent < 0 || c.CanaryPercent > 100 {
+		return fmt.Errorf("CANARY_PERCENT must be between 0 and 100")
+	}
+	if c.CanaryNode != "" {
+		if _, ok := c.NodeURLMap[c.CanaryNode]; !ok {
+			return fmt.Errorf("CANARY_NODE references unknown node %q", c.CanaryNode)
+		}
+	}
+	if c.ShadowForwardPercent < 0 || c.ShadowForwardPercent > 100 {
+		return fmt.Errorf("SHADOW_FORWARD_PERCENT must be between 0 and 100")
+	}
+	if c.ShadowForwardNode != "" {
+		if _, ok := c.NodeURLMap[c.ShadowForwardNode]; !ok {
+			return fmt.Errorf("SHADOW_FORWARD_NODE references unknown node %q", c.ShadowForwardNode)
+		}
+		if c.ShadowForwardNode == c.CanaryNode {
+			return fmt.Errorf("SHADOW_FORWARD_NODE cannot also be the CANARY_NODE")
+		}
+	}
+	for nodeID, pool := range c.NodePools {
+		if !slices.Contains(c.NodePoolOrder, pool) {
+			return fmt.Errorf("NODE_POOL_%s references pool %q missing from NODE_POOL_ORDER", strings.ToUpper(nodeID), pool)
+		}
+	}
+	if c.NodePoolLatencyThresholdMS < 0 {
+		return fmt.Errorf("NODE_POOL_LATENCY_THRESHOLD_MS must not be negative")
+	}
+	for name, tenant := range c.Tenants {
+		if tenant.RateLimitRPS < 0 || tenant.RateLimitBurst < 0 {
+			return fmt.Errorf("tenant %q rate limits must not be negative", name)
+		}
+		if tenant.Scoring != nil {
+			if err := tenant.Scoring.validate(c); err != nil {
+				return fmt.Errorf("tenant %q scoring: %w", name, err)
+			}
+		}
+	}
+	if c.ReliabilityDecreaseFactor <= 0 || c.ReliabilityDecreaseFactor > 1 {
+		return fmt.Errorf("RELIABILITY_DECREASE_FACTOR must be in (0, 1]")
+	}
+	if c.ReliabilityRecoveryStep < 0 {
+		return fmt.Errorf("RELIABILITY_RECOVERY_STEP must not be negative")
+	}
+	if c.MLAdaptiveTimeoutFactor < 1 {
+		return fmt.Errorf("ML_ADAPTIVE_TIMEOUT_FACTOR must be at least 1")
+	}
+	if c.MLAdaptiveTimeoutMin < 0 {
+		return fmt.Errorf("ML_ADAPTIVE_TIMEOUT_MIN_MS must not be negative")
+	}
+	if c.CalibrationMode != "window" && c.CalibrationMode != "ema" {
+		return fmt.Errorf("CALIBRATION_MODE must be window or ema")
+	}
+	if c.CalibrationAlpha <= 0 || c.CalibrationAlpha > 1 {
+		return fmt.Errorf("CALIBRATION_ALPHA must be in (0, 1]")
+	}
+	if c.OverrideMinImprovementPercent < 0 {
+		return fmt.Errorf("OVERRIDE_MIN_IMPROVEMENT_PERCENT must not be negative")
+	}
+	if c.CalibrationShrinkage < 0 {
+		return fmt.Errorf("CALIBRATION_SHRINKAGE must not be negative")
+	}
+	if c.CalibrationMaxOffsetFraction < 0 {
+		return fmt.Errorf("CALIBRATION_MAX_OFFSET_FRACTION must not be negative")
+	}
+	if c.MinHealthyNodes < 0 {
+		return fmt.Errorf("MIN_HEALTHY_NODES must not be negative")
+	}
+	if c.MinHealthyNodes > len(c.NodeURLMap) {
+		return fmt.Errorf("MIN_HEALTHY_NODES (%d) exceeds the number of configured nodes (%d)", c.MinHealthyNodes, len(c.NodeURLMap))
+	}
+	if c.MethodAffinityBonus < 0 {
+		return fmt.Errorf("METHOD_AFFINITY_BONUS must not be negative")
+	}
+	if c.MetricsStreamURL != "" && c.MetricsStreamReconnect <= 0 {
+		return fmt.Errorf("METRICS_STREAM_RECONNECT_SECONDS must be positive")
+	}
+	if c.TotalRequestBudget < 0 {
+		return fmt.Errorf("TOTAL_REQUEST_BUDGET_SECONDS must not be negative")
+	}
+	if c.AuditLogFile != "" && c.AuditFsyncInterval <= 0 {
+		return fmt.Errorf("AUDIT_FSYNC_INTERVAL_SECONDS must be positive")
+	}
+	if len(c.KafkaBrokers) > 0 {
+		if c.KafkaTopic == "" {
+			return fmt.Errorf("KAFKA_TOPIC is required when KAFKA_BROKERS is set")
+		}
+		if c.KafkaBufferSize <= 0 || c.KafkaFlushInterval <= 0 {
+			return fmt.Errorf("KAFKA_BUFFER_SIZE and KAFKA_FLUSH_INTERVAL_SECONDS must be positive")
+		}
+	}
+	if c.DecisionLogFile != "" && (c.DecisionBufferSize <= 0 || c.DecisionFlushInterval <= 0) {
+		return fmt.Errorf("DECISION_BUFFER_SIZE and DECISION_FLUSH_INTERVAL_SECONDS must be positive")
+	}
+	if c.DecisionLogFormat != "json" && c.DecisionLogFormat != "influx" {
+		return fmt.Errorf("DECISION_LOG_FORMAT must be json or influx")
+	}
+	if c.StatsDAddr != "" && c.StatsDFlushInterval <= 0 {
+		return fmt.Errorf("STATSD_FLUSH_INTERVAL_SECONDS must be positive")
+	}
+	if len(c.MethodMinVersions) > 0 && c.VersionCheckInterval <= 0 {
+		return fmt.Errorf("VERSION_CHECK_INTERVAL_SECONDS must be positive")
+	}
+	if c.MethodBatchPolicy != "reject" && c.MethodBatchPolicy != "partial" {
+		return fmt.Errorf("METHOD_BATCH_POLICY must be reject or partial")
+	}
+	for method, nodeID := range c.MethodNodeOverrides {
+		if _, ok := c.NodeURLMap[nodeID]; !ok {
+			return fmt.Errorf("METHOD_NODE_%s references unknown node %q", method, nodeID)
+		}
+	}
+	for nodeID, creds := range c.NodeBasicAuth {
+		// Don't echo the value, it holds a password
+		if !strings.Contains(creds, ":") {
+			return fmt.Errorf("NODE_BASIC_AUTH_%s must be in user:pass form", strings.ToUpper(nodeID))
+		}
+	}
+	if err := c.validateSharedNodeURLs(); err != nil {
+		return err
+	}
+	if c.MaxResponseBytes < 0 {
+		return fmt.Errorf("MAX_RESPONSE_BYTES must not be negative")
+	}
+	if c.AnnotateResponseNode && c.AnnotateResponseMaxBytes <= 0 {
+		return fmt.Errorf("ANNOTATE_RESPONSE_MAX_BYTES must be positive")
+	}
+	if c.UpstreamDialTimeout < 0 || c.UpstreamTLSTimeout < 0 || c.UpstreamResponseHeaderTimeout < 0 {
+		return fmt.Errorf("upstream timeouts must not be negative")
+	}
+	if c.RequestTimeout > 0 {
+		phases := []struct {
+			name    string
+			timeout time.Duration
+		}{
+			{"UPSTREAM_DIAL_TIMEOUT", c.UpstreamDialTimeout},
+			{"UPSTREAM_TLS_TIMEOUT", c.UpstreamTLSTimeout},
+			{"UPSTREAM_RESPONSE_HEADER_TIMEOUT", c.UpstreamResponseHeaderTimeout},
+		}
+		for _, phase := range phases {
+			if phase.timeout > c.RequestTimeout {
+				return fmt.Errorf("%s (%v) must not exceed REQUEST_TIMEOUT_SECONDS (%v)", phase.name, phase.timeout, c.RequestTimeout)
+			}
+		}
+	}
+	if c.LogFile != "" && c.LogMaxSizeMB <= 0 {
+		return fmt.Errorf("LOG_MAX_SIZE_MB must be positive")
+	}
+	if c.LogRateLimitInterval < 0 {
+		return fmt.Errorf("LOG_RATE_LIMIT_SECONDS must not be negative")
+	}
+	if c.UpstreamCAFile != "" {
+		if _, err := os.Stat(c.UpstreamCAFile); err != nil {
+			return fmt.Errorf("UPSTREAM_CA_FILE is not readable: %w", err)
+		}
+	}
+	if (c.TLSCertFile == "") != (c.TLSKeyFile == "") {
+		return fmt.Errorf("TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	}
+	return nil
+}
+
+// validateSharedNodeURLs rejects nodes that share a URL but differ in TLS
+// verification or basic-auth settings. Upstream clients and credentials are
+// looked up by URL, so one node would silently get the other's settings.
+func (c *Config) validateSharedNodeURLs() error {
+	nodeIDs := make([]string, 0, len(c.NodeURLMap))
+	for nodeID := range c.NodeURLMap {
+		nodeIDs = append(nodeIDs, nodeID)
+	}
+	slices.Sort(nodeIDs)
+
+	firstByURL := make(map[string]string)
+	for _, nodeID := range nodeIDs {
+		url := c.NodeURLMap[nodeID]
+		first, seen := firstByURL[url]
+		if !seen {
+			firstByURL[url] = nodeID
+			continue
+		}
+		if c.nodeSkipVerify(nodeID) != c.nodeSkipVerify(first) || c.NodeBasicAuth[nodeID] != c.NodeBasicAuth[first] {
+			return fmt.Errorf("nodes %s and %s share a URL but have different NODE_INSECURE_SKIP_VERIFY_* or NODE_BASIC_AUTH_* settings", first, nodeID)
+		}
+	}
 	return nil
 }
 
+// nodeSkipVerify returns whether TLS verification is skipped for nodeID
+func (c *Config) nodeSkipVerify(nodeID string) bool {
+	if skip, ok := c.NodeInsecureSkipVerify[nodeID]; ok {
+		return skip
+	}
+	return c.UpstreamInsecureSkipVerify
+}
+
 // GetMLPredictURL returns the full URL for ML predictions
 func (c *Config) GetMLPredictURL() string {
 	return c.MLServiceURL + c.MLPredictEndpoint
 }
 
+// GetMLPredictURLs returns the prediction URL of every configured ML service.
+// ML_SERVICE_URLS takes precedence over ML_SERVICE_URL when set.
+func (c *Config) GetMLPredictURLs() []string {
+	if len(c.MLServiceURLs) == 0 {
+		return []string{c.GetMLPredictURL()}
+	}
+	urls := make([]string, 0, len(c.MLServiceURLs))
+	for _, serviceURL := range c.MLServiceURLs {
+		urls = append(urls, serviceURL+c.MLPredictEndpoint)
+	}
+	return urls
+}
+
+// GetShadowMLPredictURL returns the shadow ML predict URL, or "" when unset
+func (c *Config) GetShadowMLPredictURL() string {
+	if c.ShadowMLServiceURL == "" {
+		return ""
+	}
+	return c.ShadowMLServiceURL + c.MLPredictEndpoint
+}
+
+// reservedPaths are the router's own endpoints; reservedPrefixes hold the
+// admin and debug endpoints. RPC_PATH must not collide with either.
+var (
+	reservedPaths    = []string{"/health", "/ready", "/stats", "/version", "/cost", "/calibration", "/predict", "/admin", "/debug"}
+	reservedPrefixes = []string{"/admin/", "/debug/"}
+)
+
+func reservedPath(path string) bool {
+	for _, reserved := range reservedPaths {
+		if path == reserved {
+			return true
+		}
+	}
+	for _, prefix := range reservedPrefixes {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// defaultMetricsEndpoints is the metrics path for each METRICS_API_VERSION
+var defaultMetricsEndpoints = map[string]string{
+	"v1": "/api/v1/metrics/history",
+	"v2": "/api/v2/metrics/history",
+}
+
 // GetMetricsURL returns the full URL for fetching metrics with history limit
 func (c *Config) GetMetricsURL() string {
 	return fmt.Sprintf("%s%s?limit=%d", c.DataCollectorURL, c.MetricsEndpoint, c.HistoryLimit)
 }
 
+// TLSEnabled reports whether the server should terminate TLS itself
+func (c *Config) TLSEnabled() bool {
+	return c.TLSCertFile != "" && c.TLSKeyFile != ""
+}
+
 // GetListenAddr returns the address to listen on
 func (c *Config) GetListenAddr() string {
 	return c.RouterHost + ":" + c.RouterPort
@@ -171,3 +1149,47 @@ func getEnvDuration(key string, defaultSeconds int) time.Duration {
 	return time.Duration(defaultSeconds) * time.Second
 }
 
+func getEnvFloat(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		floatVal, err := strconv.ParseFloat(value, 64)
+		if err == nil {
+			return floatVal
+		}
+	}
+	return defaultValue
+}
+
+func getEnvInt(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		intVal, err := strconv.Atoi(value)
+		if err == nil {
+			return intVal
+		}
+	}
+	return defaultValue
+}
+
+// getEnvTimeout parses a Go duration string ("500ms", "2s"), treating a bare
+// integer as seconds for consistency with the *_SECONDS settings
+func getEnvTimeout(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if seconds, err := strconv.Atoi(value); err == nil {
+			return time.Duration(seconds) * time.Second
+		}
+		if d, err := time.ParseDuration(value); err == nil {
+			return d
+		}
+	}
+	return defaultValue
+}
+
+// getEnvList splits a comma-separated variable, dropping empty entries
+func getEnvList(key string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
@@ -0,0 +1,294 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// loadWithEnv loads the configuration with the given variables set
+func loadWithEnv(t *testing.T, env map[string]string) (*Config, error) {
+	t.Helper()
+	for key, value := range env {
+		t.Setenv(key, value)
+	}
+	return Load()
+}
+
+func TestShutdownTimeout(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"default", "", 15 * time.Second, false},
+		{"configured", "3", 3 * time.Second, false},
+		{"zero", "0", 0, true},
+		{"negative", "-5", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := loadWithEnv(t, map[string]string{"SHUTDOWN_TIMEOUT_SECONDS": tt.value})
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "SHUTDOWN_TIMEOUT_SECONDS") {
+					t.Fatalf("err = %v, want a SHUTDOWN_TIMEOUT_SECONDS error", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load: %v", err)
+			}
+			if cfg.ShutdownTimeout != tt.want {
+				t.Fatalf("ShutdownTimeout = %s, want %s", cfg.ShutdownTimeout, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequireNodeMap(t *testing.T) {
+	tests := []struct {
+		name    string
+		require string
+		nodes   map[string]string
+		wantErr bool
+	}{
+		{"lenient by default with no nodes", "", map[string]string{}, false},
+		{"required with no nodes", "true", map[string]string{}, true},
+		{"required with only empty URLs", "true", map[string]string{"a": ""}, true},
+		{"required with a node", "true", map[string]string{"a": "https://a.example"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := loadWithEnv(t, map[string]string{"REQUIRE_NODE_MAP": tt.require})
+			if err != nil {
+				t.Fatalf("Load: %v", err)
+			}
+			cfg.NodeURLMap = tt.nodes
+
+			err = cfg.Validate()
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "no nodes are configured") {
+					t.Fatalf("Validate = %v, want a no nodes error", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Validate: %v", err)
+			}
+		})
+	}
+}
+
+func TestRequireNodeMapWithDefaultNodes(t *testing.T) {
+	cfg, err := loadWithEnv(t, map[string]string{"REQUIRE_NODE_MAP": "true"})
+	if err != nil {
+		t.Fatalf("Load with default nodes failed: %v", err)
+	}
+	if !cfg.RequireNodeMap {
+		t.Fatal("REQUIRE_NODE_MAP=true not applied")
+	}
+}
+
+func TestTenantScoring(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  string
+		wantErr bool
+	}{
+		{"partial preset", `{"scoring": {"recent_weight": 0.6}}`, false},
+		{"no preset", `{"rate_limit_rps": 10}`, false},
+		{"success rate out of range", `{"scoring": {"success_rate_weight": 1.5}}`, true},
+		{"negative penalty", `{"scoring": {"unhealthy_penalty": -1}}`, true},
+		{"both latency weights zero", `{"scoring": {"prediction_weight": 0, "recent_weight": 0}}`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := loadWithEnv(t, map[string]string{"TENANT_CONFIG_WALLET": tt.policy})
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), `tenant "wallet" scoring`) {
+					t.Fatalf("err = %v, want a tenant scoring error", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load: %v", err)
+			}
+			if _, ok := cfg.Tenants["wallet"]; !ok {
+				t.Fatal("tenant wallet not loaded")
+			}
+		})
+	}
+}
+
+func TestDecisionExportSinks(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		wantErr string
+	}{
+		{"kafka and file together", map[string]string{
+			"KAFKA_BROKERS": "localhost:9092", "KAFKA_TOPIC": "decisions", "DECISION_LOG_FILE": "/tmp/decisions.log",
+		}, ""},
+		{"file ignores kafka settings", map[string]string{
+			"DECISION_LOG_FILE": "/tmp/decisions.log", "KAFKA_BUFFER_SIZE": "0",
+		}, ""},
+		{"file buffer", map[string]string{
+			"DECISION_LOG_FILE": "/tmp/decisions.log", "DECISION_BUFFER_SIZE": "0",
+		}, "DECISION_BUFFER_SIZE"},
+		{"file flush interval", map[string]string{
+			"DECISION_LOG_FILE": "/tmp/decisions.log", "DECISION_FLUSH_INTERVAL_SECONDS": "0",
+		}, "DECISION_FLUSH_INTERVAL_SECONDS"},
+		{"kafka buffer", map[string]string{
+			"KAFKA_BROKERS": "localhost:9092", "KAFKA_TOPIC": "decisions", "KAFKA_BUFFER_SIZE": "0",
+		}, "KAFKA_BUFFER_SIZE"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := loadWithEnv(t, tt.env)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Load: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("err = %v, want a %s error", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMLRequireJSON(t *testing.T) {
+	for value, want := range map[string]bool{"": false, "true": true, "false": false} {
+		cfg, err := loadWithEnv(t, map[string]string{"ML_REQUIRE_JSON": value})
+		if err != nil {
+			t.Fatalf("Load: %v", err)
+		}
+		if cfg.MLRequireJSON != want {
+			t.Errorf("ML_REQUIRE_JSON=%q: MLRequireJSON = %v, want %v", value, cfg.MLRequireJSON, want)
+		}
+	}
+}
+
+func TestPropagateRequestIDDefault(t *testing.T) {
+	cfg, err := loadWithEnv(t, nil)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.PropagateRequestID {
+		t.Error("PROPAGATE_REQUEST_ID defaults to true, want false")
+	}
+}
+
+func TestUpstreamTimeoutsBoundedByRequestTimeout(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		wantErr string
+	}{
+		{"defaults", nil, ""},
+		{"dial past request timeout", map[string]string{"REQUEST_TIMEOUT_SECONDS": "2", "UPSTREAM_DIAL_TIMEOUT": "3s"}, "UPSTREAM_DIAL_TIMEOUT"},
+		{"TLS past request timeout", map[string]string{"REQUEST_TIMEOUT_SECONDS": "5", "UPSTREAM_TLS_TIMEOUT": "6s"}, "UPSTREAM_TLS_TIMEOUT"},
+		{"defaults under a short request timeout", map[string]string{"REQUEST_TIMEOUT_SECONDS": "3"}, ""},
+		{"headers past request timeout", map[string]string{"UPSTREAM_RESPONSE_HEADER_TIMEOUT": "31s"}, "UPSTREAM_RESPONSE_HEADER_TIMEOUT"},
+		{"equal to request timeout", map[string]string{"REQUEST_TIMEOUT_SECONDS": "10", "UPSTREAM_TLS_TIMEOUT": "10s"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := loadWithEnv(t, tt.env)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Load: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Load = %v, want an error about %s", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSharedNodeURLs(t *testing.T) {
+	tests := []struct {
+		name       string
+		skipVerify map[string]bool
+		basicAuth  map[string]string
+		wantErr    bool
+	}{
+		{"same settings", map[string]bool{"a": true, "b": true}, map[string]string{"a": "u:p", "b": "u:p"}, false},
+		{"different TLS verification", map[string]bool{"a": true}, nil, true},
+		{"different credentials", nil, map[string]string{"a": "u:p", "b": "v:q"}, true},
+		{"credentials on one node only", nil, map[string]string{"b": "u:p"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := loadWithEnv(t, nil)
+			if err != nil {
+				t.Fatalf("Load: %v", err)
+			}
+			cfg.NodeURLMap = map[string]string{"a": "https://rpc.example", "b": "https://rpc.example", "c": "https://other.example"}
+			cfg.NodeInsecureSkipVerify = tt.skipVerify
+			cfg.NodeBasicAuth = tt.basicAuth
+
+			err = cfg.Validate()
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "nodes a and b share a URL") {
+					t.Fatalf("Validate = %v, want a shared URL error", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Validate: %v", err)
+			}
+		})
+	}
+}
+
+func TestSuccessRateWeightDefault(t *testing.T) {
+	cfg, err := loadWithEnv(t, nil)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.SuccessRateWeight != 0 {
+		t.Errorf("SUCCESS_RATE_WEIGHT defaults to %v, want 0 so scoring is unchanged unless enabled", cfg.SuccessRateWeight)
+	}
+}
+
+func TestRPCPath(t *testing.T) {
+	tests := []struct {
+		path    string
+		wantErr bool
+	}{
+		{"/rpc", false},
+		{"/solana/rpc", false},
+		{"/healthz", false},
+		{"/", true},
+		{"rpc", true},
+		{"/health", true},
+		{"/ready", true},
+		{"/stats", true},
+		{"/version", true},
+		{"/cost", true},
+		{"/calibration", true},
+		{"/predict", true},
+		{"/admin/drain", true},
+		{"/admin/rpc", true},
+		{"/debug/pprof/", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			_, err := loadWithEnv(t, map[string]string{"RPC_PATH": tt.path})
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "RPC_PATH") {
+					t.Fatalf("err = %v, want an RPC_PATH error", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// loadConfigFile applies CONFIG_FILE, a YAML or JSON document whose top-level
+// keys are environment variable names (case-insensitive), plus an optional
+// "nodes" section of per-node settings:
+//
+//	ml_service_url: http://ml:8000
+//	denied_methods: [sendTransaction]
+//	nodes:
+//	  helius_devnet:
+//	    url: https://devnet.helius-rpc.com
+//	    cost: 0.02
+//	    max_inflight: 50
+//
+// Per-node keys map to NODE_<KEY>_<NODE_ID>, e.g. NODE_COST_HELIUS_DEVNET.
+// Values are only set when the variable isn't already in the environment, so
+// env always wins, mirroring how the .env file is loaded.
+func loadConfigFile() error {
+	path := os.Getenv("CONFIG_FILE")
+	if path == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read CONFIG_FILE: %w", err)
+	}
+
+	var doc map[string]interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("failed to parse CONFIG_FILE: %w", err)
+	}
+
+	for key, value := range doc {
+		if strings.EqualFold(key, "nodes") {
+			nodes, ok := value.(map[string]interface{})
+			if !ok {
+				return fmt.Errorf("CONFIG_FILE: nodes must be a map of node ID to settings")
+			}
+			if err := applyNodeSettings(nodes); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := setDefaultEnv(strings.ToUpper(key), value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyNodeSettings maps per-node settings onto NODE_<KEY>_<NODE_ID> variables
+func applyNodeSettings(nodes map[string]interface{}) error {
+	for nodeID, raw := range nodes {
+		settings, ok := raw.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("CONFIG_FILE: settings for node %q must be a map", nodeID)
+		}
+		for key, value := range settings {
+			name := "NODE_" + strings.ToUpper(key) + "_" + strings.ToUpper(nodeID)
+			if err := setDefaultEnv(name, value); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// setDefaultEnv sets name to value unless it is already set. Lists become
+// comma-separated values.
+func setDefaultEnv(name string, value interface{}) error {
+	if _, exists := os.LookupEnv(name); exists {
+		return nil
+	}
+
+	var str string
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case []interface{}:
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			items = append(items, fmt.Sprint(item))
+		}
+		str = strings.Join(items, ",")
+	case map[string]interface{}:
+		return fmt.Errorf("CONFIG_FILE: %s must be a scalar or list", name)
+	default:
+		str = fmt.Sprint(v)
+	}
+	return os.Setenv(name, str)
+}
+
+// loadExtraNodeURLs adds nodes defined only through NODE_URL_<NODE_ID>, e.g.
+// from CONFIG_FILE, to the node map. Node IDs are lower-cased.
+func loadExtraNodeURLs(nodeMap map[string]string) {
+	extra := loadPrefixedEnv("NODE_URL_")
+	keys := make([]string, 0, len(extra))
+	for key := range extra {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		nodeID := strings.ToLower(key)
+		if _, exists := nodeMap[nodeID]; !exists {
+			nodeMap[nodeID] = extra[key]
+		}
+	}
+}
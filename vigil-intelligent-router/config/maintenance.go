@@ -0,0 +1,135 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// MaintenanceWindow is a period during which a node must not receive traffic.
+// It is either a one-off range (Start/End) or a daily UTC time range,
+// optionally limited to one weekday.
+type MaintenanceWindow struct {
+	Start time.Time
+	End   time.Time
+
+	Weekday  *time.Weekday
+	FromMins int // minutes after midnight UTC
+	ToMins   int
+}
+
+// Contains reports whether t falls inside the window
+func (w MaintenanceWindow) Contains(t time.Time) bool {
+	if !w.Start.IsZero() {
+		return !t.Before(w.Start) && t.Before(w.End)
+	}
+
+	t = t.UTC()
+	mins := t.Hour()*60 + t.Minute()
+	today := t.Weekday()
+	yesterday := (today + 6) % 7
+
+	onDay := func(day time.Weekday) bool {
+		return w.Weekday == nil || *w.Weekday == day
+	}
+
+	if w.FromMins < w.ToMins {
+		return onDay(today) && mins >= w.FromMins && mins < w.ToMins
+	}
+	// Window wraps past midnight; the early-morning part belongs to the
+	// previous day's window
+	return (onDay(today) && mins >= w.FromMins) || (onDay(yesterday) && mins < w.ToMins)
+}
+
+// InMaintenance reports whether nodeID is inside one of its maintenance windows at t
+func (c *Config) InMaintenance(nodeID string, t time.Time) bool {
+	for _, window := range c.NodeMaintenance[nodeID] {
+		if window.Contains(t) {
+			return true
+		}
+	}
+	return false
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// parseMaintenanceWindows parses a comma-separated list of windows. Each entry
+// is "HH:MM-HH:MM" (daily, UTC), "Sat HH:MM-HH:MM" (weekly, UTC) or
+// "<RFC3339>/<RFC3339>" (one-off).
+func parseMaintenanceWindows(spec string) ([]MaintenanceWindow, error) {
+	var windows []MaintenanceWindow
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		if start, end, ok := strings.Cut(entry, "/"); ok {
+			startTime, err := time.Parse(time.RFC3339, start)
+			if err != nil {
+				return nil, fmt.Errorf("invalid window start %q", start)
+			}
+			endTime, err := time.Parse(time.RFC3339, end)
+			if err != nil {
+				return nil, fmt.Errorf("invalid window end %q", end)
+			}
+			if !endTime.After(startTime) {
+				return nil, fmt.Errorf("window %q ends before it starts", entry)
+			}
+			windows = append(windows, MaintenanceWindow{Start: startTime, End: endTime})
+			continue
+		}
+
+		var window MaintenanceWindow
+		if day, rest, ok := strings.Cut(entry, " "); ok {
+			weekday, known := weekdays[strings.ToLower(day)[:min(3, len(day))]]
+			if !known {
+				return nil, fmt.Errorf("invalid weekday %q", day)
+			}
+			window.Weekday = &weekday
+			entry = strings.TrimSpace(rest)
+		}
+
+		from, to, ok := strings.Cut(entry, "-")
+		if !ok {
+			return nil, fmt.Errorf("invalid window %q, expected HH:MM-HH:MM", entry)
+		}
+		var err error
+		if window.FromMins, err = parseClock(from); err != nil {
+			return nil, err
+		}
+		if window.ToMins, err = parseClock(to); err != nil {
+			return nil, err
+		}
+		if window.FromMins == window.ToMins {
+			return nil, fmt.Errorf("window %q is empty", entry)
+		}
+		windows = append(windows, window)
+	}
+	return windows, nil
+}
+
+// parseClock converts "HH:MM" to minutes after midnight
+func parseClock(value string) (int, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(value))
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", value)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// loadMaintenanceWindows reads NODE_MAINTENANCE_<NODE_ID> for every configured node
+func loadMaintenanceWindows(nodeMap map[string]string) (map[string][]MaintenanceWindow, error) {
+	windows := make(map[string][]MaintenanceWindow)
+	for nodeID, spec := range loadPerNodeString("NODE_MAINTENANCE_", nodeMap) {
+		parsed, err := parseMaintenanceWindows(spec)
+		if err != nil {
+			return nil, fmt.Errorf("NODE_MAINTENANCE_%s: %w", strings.ToUpper(nodeID), err)
+		}
+		windows[nodeID] = parsed
+	}
+	return windows, nil
+}
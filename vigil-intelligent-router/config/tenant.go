@@ -0,0 +1,93 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// TenantPolicy overrides routing settings for requests from one tenant.
+// Zero values fall back to the default policy.
+type TenantPolicy struct {
+	// APIKey identifies the tenant via X-API-Key. When set, requests naming
+	// the tenant in X-Vigil-Tenant must carry it too.
+	APIKey string `json:"api_key"`
+
+	// FallbackRPCURL replaces FALLBACK_RPC_URL for this tenant
+	FallbackRPCURL string `json:"fallback_rpc_url"`
+
+	// Token-bucket rate limit (0 = unlimited); burst defaults to the rate
+	RateLimitRPS   float64 `json:"rate_limit_rps"`
+	RateLimitBurst int     `json:"rate_limit_burst"`
+
+	// Scoring overrides hybrid scoring weights for this tenant's requests
+	Scoring *TenantScoring `json:"scoring"`
+}
+
+// TenantScoring is a scoring preset; unset weights keep their global values
+type TenantScoring struct {
+	SuccessRateWeight *float64 `json:"success_rate_weight"`
+	BlockGapPenalty   *float64 `json:"block_gap_penalty"`
+	UnhealthyPenalty  *float64 `json:"unhealthy_penalty"`
+	PredictionWeight  *float64 `json:"prediction_weight"`
+	RecentWeight      *float64 `json:"recent_weight"`
+}
+
+// validate checks the preset as it applies on top of the global weights in c
+func (s *TenantScoring) validate(c *Config) error {
+	pick := func(override *float64, global float64) float64 {
+		if override != nil {
+			return *override
+		}
+		return global
+	}
+	successRate := pick(s.SuccessRateWeight, c.SuccessRateWeight)
+	predictionWeight := pick(s.PredictionWeight, c.PredictionWeight)
+	recentWeight := pick(s.RecentWeight, c.RecentWeight)
+
+	if successRate < 0 || successRate > 1 {
+		return fmt.Errorf("success_rate_weight must be between 0 and 1")
+	}
+	if pick(s.BlockGapPenalty, c.BlockGapPenalty) < 0 || pick(s.UnhealthyPenalty, c.UnhealthyPenalty) < 0 {
+		return fmt.Errorf("block_gap_penalty and unhealthy_penalty must not be negative")
+	}
+	if predictionWeight < 0 || recentWeight < 0 || predictionWeight+recentWeight == 0 {
+		return fmt.Errorf("prediction_weight and recent_weight must not be negative or both zero")
+	}
+	return nil
+}
+
+// loadTenants reads tenant policies from TENANT_CONFIG_FILE (a JSON object of
+// name -> policy) and from TENANT_CONFIG_<NAME> variables holding one JSON
+// policy each. Variables win over the file. Tenant names are lower-cased.
+func loadTenants() (map[string]TenantPolicy, error) {
+	tenants := make(map[string]TenantPolicy)
+
+	if path := os.Getenv("TENANT_CONFIG_FILE"); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read TENANT_CONFIG_FILE: %w", err)
+		}
+		var fromFile map[string]TenantPolicy
+		if err := json.Unmarshal(data, &fromFile); err != nil {
+			return nil, fmt.Errorf("failed to parse TENANT_CONFIG_FILE: %w", err)
+		}
+		for name, policy := range fromFile {
+			tenants[strings.ToLower(name)] = policy
+		}
+	}
+
+	for name, raw := range loadPrefixedEnv("TENANT_CONFIG_") {
+		if name == "FILE" {
+			continue
+		}
+		var policy TenantPolicy
+		if err := json.Unmarshal([]byte(raw), &policy); err != nil {
+			return nil, fmt.Errorf("failed to parse TENANT_CONFIG_%s: %w", name, err)
+		}
+		tenants[strings.ToLower(name)] = policy
+	}
+
+	return tenants, nil
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/project-vigil/vigil-intelligent-router/config"
+	"go.uber.org/zap"
+)
+
+// dependencyProbeInterval is the pause between rounds of dependency probes
+const dependencyProbeInterval = 2 * time.Second
+
+// dependencyHealthURLs returns the /health URL of every ML service and the
+// Data Collector
+func dependencyHealthURLs(cfg *config.Config) []string {
+	services := cfg.MLServiceURLs
+	if len(services) == 0 {
+		services = []string{cfg.MLServiceURL}
+	}
+	urls := make([]string, 0, len(services)+1)
+	for _, service := range append(services, cfg.DataCollectorURL) {
+		urls = append(urls, strings.TrimSuffix(service, "/")+"/health")
+	}
+	return urls
+}
+
+// waitForDependencies polls the ML services and Data Collector until they all
+// report healthy or DEPENDENCY_WAIT_TIMEOUT_SECONDS elapses. On timeout the
+// router starts anyway, relying on fallback routing until they come up.
+func waitForDependencies(cfg *config.Config, logger *zap.Logger) {
+	pending := dependencyHealthURLs(cfg)
+	deadline := time.Now().Add(cfg.DependencyWaitTimeout)
+	client := &http.Client{Timeout: dependencyProbeInterval}
+
+	logger.Info("Waiting for dependencies",
+		zap.Strings("urls", pending),
+		zap.Duration("timeout", cfg.DependencyWaitTimeout))
+
+	for {
+		var stillPending []string
+		for _, url := range pending {
+			if dependencyReady(client, url) {
+				logger.Info("Dependency ready", zap.String("url", url))
+				continue
+			}
+			stillPending = append(stillPending, url)
+		}
+		pending = stillPending
+
+		if len(pending) == 0 {
+			logger.Info("All dependencies ready")
+			return
+		}
+		if time.Now().Add(dependencyProbeInterval).After(deadline) {
+			logger.Warn("Timed out waiting for dependencies, starting anyway",
+				zap.Strings("not_ready", pending))
+			return
+		}
+		time.Sleep(dependencyProbeInterval)
+	}
+}
+
+// dependencyReady reports whether url answers with a 2xx status
+func dependencyReady(client *http.Client, url string) bool {
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, url, nil)
+	if err != nil {
+		return false
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return false
+	}
+	resp.Body.Close()
+	return resp.StatusCode >= 200 && resp.StatusCode < 300
+}
@@ -5,7 +5,12 @@ go 1.21
 require (
 	github.com/joho/godotenv v1.5.1
 	go.uber.org/zap v1.26.0
+	golang.org/x/net v0.33.0
+	gopkg.in/natefinch/lumberjack.v2 v2.2.1
+	gopkg.in/yaml.v3 v3.0.1
 )
 
-require go.uber.org/multierr v1.10.0 // indirect
-
+require (
+	go.uber.org/multierr v1.10.0 // indirect
+	golang.org/x/text v0.21.0 // indirect
+)
@@ -5,8 +5,11 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"strconv"
 	"syscall"
 	"time"
 
@@ -15,6 +18,9 @@ import (
 	"github.com/project-vigil/vigil-intelligent-router/proxy"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	"gopkg.in/natefinch/lumberjack.v2"
 )
 
 func main() {
@@ -26,27 +32,76 @@ func main() {
 	}
 
 	// Initialize logger
-	logger, err := initLogger(cfg.LogLevel, cfg.LogFormat)
+	logger, logLevel, err := initLogger(cfg)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Failed to initialize logger: %v\n", err)
 		os.Exit(1)
 	}
 	defer logger.Sync()
 
+	build := buildInfo()
 	logger.Info("Starting Vigil Intelligent Router",
-		zap.String("version", "1.0.0"),
+		zap.String("version", build.Version),
+		zap.String("git_commit", build.GitCommit),
 		zap.String("listen_addr", cfg.GetListenAddr()),
-		zap.String("ml_service", cfg.MLServiceURL),
+		zap.Strings("ml_services", cfg.GetMLPredictURLs()),
+		zap.String("shadow_ml_service", cfg.GetShadowMLPredictURL()),
 		zap.String("data_collector", cfg.DataCollectorURL),
 		zap.String("fallback_rpc", cfg.FallbackRPCURL),
 		zap.Bool("fallback_enabled", cfg.FallbackEnabled))
 
 	// Initialize ML client
 	mlClient := ml.NewClient(
-		cfg.GetMLPredictURL(),
+		cfg.GetMLPredictURLs(),
 		cfg.GetMetricsURL(),
 		cfg.MLQueryTimeout,
 		cfg.NodeURLMap,
+		ml.Options{
+			SuccessRateWeight:             cfg.SuccessRateWeight,
+			TrustMLVerbatim:               cfg.TrustMLVerbatim,
+			OnDisagreement:                cfg.OnDisagreement,
+			MaxBlockHeightGap:             cfg.MaxBlockHeightGap,
+			BlockGapPenalty:               cfg.BlockGapPenalty,
+			UnhealthyPolicy:               cfg.UnhealthyPolicy,
+			UnhealthyPenalty:              cfg.UnhealthyPenalty,
+			PredictionWeight:              cfg.PredictionWeight,
+			RecentWeight:                  cfg.RecentWeight,
+			EnsembleMethod:                cfg.MLEnsembleMethod,
+			ShadowPredictURL:              cfg.GetShadowMLPredictURL(),
+			InMaintenance:                 cfg.InMaintenance,
+			CalibrationSampleRate:         cfg.CalibrationSampleRate,
+			CalibrationMode:               cfg.CalibrationMode,
+			CalibrationAlpha:              cfg.CalibrationAlpha,
+			CalibrationShrinkage:          cfg.CalibrationShrinkage,
+			CalibrationMaxOffsetFraction:  cfg.CalibrationMaxOffsetFraction,
+			CalibrationSkipOutOfBounds:    cfg.CalibrationSkipOutOfBounds,
+			PreferredNode:                 cfg.PreferredNode,
+			PreferredNodeTolerancePercent: cfg.PreferredNodeTolerancePercent,
+			SLALatencyMS:                  cfg.SLALatencyMS,
+			SLAViolationThreshold:         cfg.SLAViolationThreshold,
+			SLAWindow:                     cfg.SLAWindow,
+			SLACooldown:                   cfg.SLACooldown,
+			DedupMetrics:                  cfg.DedupMetrics,
+			RoutingStrategy:               cfg.RoutingStrategy,
+			NodePriority:                  cfg.NodePriority,
+			ShadowNode:                    cfg.ShadowForwardNode,
+			MetricsAPIVersion:             cfg.MetricsAPIVersion,
+			MetricsFetchRetries:           cfg.MetricsFetchRetries,
+			MetricsFetchRetryBackoff:      cfg.MetricsFetchRetryBackoff,
+			OverrideMinImprovementPercent: cfg.OverrideMinImprovementPercent,
+			StrictFallback:                cfg.StrictFallback,
+			AllAnomalousPolicy:            cfg.AllAnomalousPolicy,
+			LogRateLimitInterval:          cfg.LogRateLimitInterval,
+			LogMLIO:                       cfg.LogMLIO,
+			LogMLIOMaxBytes:               cfg.LogMLIOMaxBytes,
+			RequireJSONResponse:           cfg.MLRequireJSON,
+			AdaptiveTimeout:               cfg.MLAdaptiveTimeout,
+			AdaptiveTimeoutFactor:         cfg.MLAdaptiveTimeoutFactor,
+			AdaptiveTimeoutMin:            cfg.MLAdaptiveTimeoutMin,
+			ReliabilityWeighting:          cfg.ReliabilityWeighting,
+			ReliabilityDecreaseFactor:     cfg.ReliabilityDecreaseFactor,
+			ReliabilityRecoveryStep:       cfg.ReliabilityRecoveryStep,
+		},
 		logger,
 	)
 	
@@ -56,17 +111,65 @@ func main() {
 	// Create proxy handler
 	proxyHandler := proxy.NewHandler(mlClient, cfg, logger)
 
+	// Background work stops when the server shuts down
+	backgroundCtx, stopBackground := context.WithCancel(context.Background())
+	defer stopBackground()
+	tasks := proxy.NewTaskManager(backgroundCtx, cfg.IntervalJitterPercent, logger)
+	proxyHandler.StartVersionChecks(tasks)
+	proxyHandler.StartKeepWarm(tasks)
+	proxyHandler.StartMetricsPush(tasks)
+	proxyHandler.StartDecisionExport(tasks)
+	if err := proxyHandler.OpenAuditLog(tasks); err != nil {
+		logger.Fatal("Failed to set up audit log", zap.Error(err))
+	}
+	if cfg.MetricsStreamURL != "" {
+		tasks.Go("metrics_stream", func(ctx context.Context) {
+			mlClient.StreamMetrics(ctx, cfg.MetricsStreamURL, cfg.HistoryLimit, cfg.MetricsStreamReconnect)
+		})
+	}
+
 	// Set up HTTP router
 	mux := http.NewServeMux()
 	
 	// Main RPC endpoint
-	mux.Handle("/rpc", proxyHandler)
+	mux.Handle(cfg.RPCPath, proxyHandler)
 	
 	// Health check endpoint
 	if cfg.HealthCheckEnabled {
-		mux.HandleFunc("/health", proxy.HealthCheckHandler(logger))
+		mux.HandleFunc("/health", proxy.HealthCheckHandler(cfg, logger, proxyHandler.NodeSummary, proxyHandler.DeepHealth))
+	}
+	
+	// Readiness probe, gated on MIN_HEALTHY_NODES
+	mux.HandleFunc("/ready", proxy.ReadinessHandler(mlClient, cfg, logger))
+	
+	// Admin endpoints require a token
+	if cfg.AdminToken != "" {
+		mux.HandleFunc("/admin/blocklist", proxy.BlocklistHandler(mlClient, cfg, logger))
+		mux.HandleFunc("/admin/calibration/reset", proxy.CalibrationResetHandler(mlClient, cfg, logger))
+		mux.HandleFunc("/admin/drain", proxyHandler.DrainHandler())
+		mux.HandleFunc("/admin/score-preview", proxy.ScorePreviewHandler(mlClient, cfg, logger))
+		mux.HandleFunc("/admin/loglevel", proxy.LogLevelHandler(logLevel, cfg, logger))
+		mux.HandleFunc("/debug/method-affinity", proxyHandler.MethodAffinityHandler())
+		if cfg.PprofEnabled {
+			proxy.RegisterPprof(mux, cfg)
+			logger.Info("pprof endpoints enabled at /debug/pprof/")
+		}
+	} else {
+		logger.Info("ADMIN_TOKEN not set, admin endpoints disabled")
+		if cfg.PprofEnabled {
+			logger.Warn("PPROF_ENABLED requires ADMIN_TOKEN, pprof endpoints disabled")
+		}
 	}
 	
+	// Routing stats endpoint
+	mux.HandleFunc("/stats", proxyHandler.StatsHandler())
+	
+	// Build information
+	mux.HandleFunc("/version", proxy.VersionHandler(cfg, build))
+	
+	// Estimated per-node spend
+	mux.HandleFunc("/cost", proxyHandler.CostHandler())
+	
 	// Calibration stats endpoint
 	mux.HandleFunc("/calibration", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
@@ -75,7 +178,7 @@ func main() {
 		w.Header().Set("Content-Type", "application/json")
 		
 		if r.Method == http.MethodOptions {
-			w.WriteHeader(http.StatusOK)
+			proxy.WritePreflight(w, r, cfg.CORSMaxAge)
 			return
 		}
 		
@@ -91,7 +194,7 @@ func main() {
 		w.Header().Set("Content-Type", "application/json")
 		
 		if r.Method == http.MethodOptions {
-			w.WriteHeader(http.StatusOK)
+			proxy.WritePreflight(w, r, cfg.CORSMaxAge)
 			return
 		}
 		
@@ -120,9 +223,10 @@ func main() {
 			return
 		}
 		
-		// If it's a POST request or OPTIONS (CORS preflight), treat it as RPC
+		// If it's a POST request, OPTIONS (CORS preflight) or a GET carrying
+		// ?request=, treat it as RPC
 		// The proxy handler will set CORS headers
-		if r.Method == http.MethodPost || r.Method == http.MethodOptions {
+		if r.Method == http.MethodPost || r.Method == http.MethodOptions || proxy.IsGetRPC(cfg, r) {
 			proxyHandler.ServeHTTP(w, r)
 			return
 		}
@@ -135,21 +239,29 @@ func main() {
 		
 		fmt.Fprintf(w, `{
   "service": "Vigil Intelligent Router",
-  "version": "1.0.0",
+  "version": %q,
   "endpoints": {
-    "rpc": "/rpc",
+    "rpc": %q,
     "root": "/",
-    "health": "/health"
+    "health": "/health",
+    "version": "/version"
   },
   "description": "ML-powered intelligent routing for Solana RPC requests",
-  "note": "POST JSON-RPC requests to / or /rpc"
-}`)
+  "note": "POST JSON-RPC requests to / or %s"
+}`, build.Version, cfg.RPCPath, cfg.RPCPath)
 	})
 
+	handler := serverHandler(cfg, mux)
+
+	// Hold off serving until the ML service and Data Collector are up
+	if cfg.WaitForDependencies {
+		waitForDependencies(cfg, logger)
+	}
+
 	// Create HTTP server
 	server := &http.Server{
 		Addr:         cfg.GetListenAddr(),
-		Handler:      mux,
+		Handler:      handler,
 		ReadTimeout:  15 * time.Second,
 		WriteTimeout: cfg.RequestTimeout + (5 * time.Second), // Buffer for processing
 		IdleTimeout:  60 * time.Second,
@@ -160,7 +272,14 @@ func main() {
 
 	// Start HTTP server in a goroutine
 	go func() {
-		logger.Info("HTTP server listening", zap.String("addr", server.Addr))
+		logger.Info("HTTP server listening",
+			zap.String("addr", server.Addr),
+			zap.Bool("tls", cfg.TLSEnabled()),
+			zap.Bool("h2c", cfg.H2CEnabled))
+		if cfg.TLSEnabled() {
+			serverErrors <- server.ListenAndServeTLS(cfg.TLSCertFile, cfg.TLSKeyFile)
+			return
+		}
 		serverErrors <- server.ListenAndServe()
 	}()
 
@@ -176,31 +295,54 @@ func main() {
 	case sig := <-shutdown:
 		logger.Info("Shutdown signal received",
 			zap.String("signal", sig.String()))
+		stopBackground()
 
 		// Give outstanding requests some time to complete
-		ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
-		defer cancel()
-
-		if err := server.Shutdown(ctx); err != nil {
+		if err := shutdownServer(server, cfg.ShutdownTimeout); err != nil {
 			logger.Error("Graceful shutdown failed", zap.Error(err))
 			if err := server.Close(); err != nil {
 				logger.Fatal("Failed to close server", zap.Error(err))
 			}
 		}
 
+		// Let background tasks finish their current run
+		tasks.Wait()
+		if err := proxyHandler.CloseAuditLog(); err != nil {
+			logger.Error("Failed to close audit log", zap.Error(err))
+		}
+
 		logger.Info("Server stopped gracefully")
 	}
 }
 
-// initLogger initializes the zap logger based on configuration
-func initLogger(level, format string) (*zap.Logger, error) {
+// shutdownServer stops accepting connections and waits up to timeout for
+// outstanding requests to complete
+func shutdownServer(server *http.Server, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return server.Shutdown(ctx)
+}
+
+// serverHandler wraps mux for the server. It serves HTTP/2 over plaintext
+// (prior knowledge or Upgrade) when h2c is enabled; HTTP/2 over TLS is
+// negotiated automatically by net/http via ALPN.
+func serverHandler(cfg *config.Config, mux http.Handler) http.Handler {
+	if cfg.H2CEnabled {
+		return h2c.NewHandler(mux, &http2.Server{})
+	}
+	return mux
+}
+
+// initLogger initializes the zap logger based on configuration. The returned
+// level can be changed at runtime through /admin/loglevel.
+func initLogger(cfg *config.Config) (*zap.Logger, zap.AtomicLevel, error) {
 	var zapLevel zapcore.Level
-	if err := zapLevel.UnmarshalText([]byte(level)); err != nil {
-		return nil, fmt.Errorf("invalid log level %q: %w", level, err)
+	if err := zapLevel.UnmarshalText([]byte(cfg.LogLevel)); err != nil {
+		return nil, zap.AtomicLevel{}, fmt.Errorf("invalid log level %q: %w", cfg.LogLevel, err)
 	}
 
 	var config zap.Config
-	if format == "json" {
+	if cfg.LogFormat == "json" {
 		config = zap.NewProductionConfig()
 	} else {
 		config = zap.NewDevelopmentConfig()
@@ -209,6 +351,72 @@ func initLogger(level, format string) (*zap.Logger, error) {
 
 	config.Level = zap.NewAtomicLevelAt(zapLevel)
 
-	return config.Build()
+	// Write to a size-rotated file instead of stdout. Only the output changes;
+	// sampling, stack traces and internal error output stay as configured.
+	if cfg.LogFile != "" {
+		path, err := filepath.Abs(cfg.LogFile)
+		if err != nil {
+			return nil, zap.AtomicLevel{}, fmt.Errorf("invalid log file %q: %w", cfg.LogFile, err)
+		}
+		query := url.Values{}
+		query.Set("max_size", strconv.Itoa(cfg.LogMaxSizeMB))
+		query.Set("max_backups", strconv.Itoa(cfg.LogMaxBackups))
+		query.Set("max_age", strconv.Itoa(cfg.LogMaxAgeDays))
+		sink := url.URL{Scheme: lumberjackScheme, Path: path, RawQuery: query.Encode()}
+		config.OutputPaths = []string{sink.String()}
+		// No ANSI colors in files
+		if cfg.LogFormat != "json" {
+			config.EncoderConfig.EncodeLevel = zapcore.CapitalLevelEncoder
+		}
+	}
+
+	logger, err := config.Build()
+	return logger, config.Level, err
+}
+
+// lumberjackScheme is the zap sink URL scheme for size-rotated log files:
+// lumberjack:///path/to/file?max_size=MB&max_backups=N&max_age=DAYS
+const lumberjackScheme = "lumberjack"
+
+func init() {
+	if err := zap.RegisterSink(lumberjackScheme, newLumberjackSink); err != nil {
+		panic(err)
+	}
+}
+
+// lumberjackSink adapts a rotating file to zap.Sink; writes go straight to
+// the file, so there is nothing to sync
+type lumberjackSink struct {
+	*lumberjack.Logger
 }
 
+func (lumberjackSink) Sync() error { return nil }
+
+func newLumberjackSink(u *url.URL) (zap.Sink, error) {
+	query := u.Query()
+	setting := func(key string) (int, error) {
+		value, err := strconv.Atoi(query.Get(key))
+		if err != nil {
+			return 0, fmt.Errorf("invalid %s in log sink %q: %w", key, u, err)
+		}
+		return value, nil
+	}
+	maxSize, err := setting("max_size")
+	if err != nil {
+		return nil, err
+	}
+	maxBackups, err := setting("max_backups")
+	if err != nil {
+		return nil, err
+	}
+	maxAge, err := setting("max_age")
+	if err != nil {
+		return nil, err
+	}
+	return lumberjackSink{&lumberjack.Logger{
+		Filename:   u.Path,
+		MaxSize:    maxSize,
+		MaxBackups: maxBackups,
+		MaxAge:     maxAge,
+	}}, nil
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/project-vigil/vigil-intelligent-router/config"
+	"go.uber.org/zap/zapcore"
+	"golang.org/x/net/http2"
+)
+
+// h2cClient speaks HTTP/2 with prior knowledge over plaintext
+func h2cClient() *http.Client {
+	return &http.Client{
+		Transport: &http2.Transport{
+			AllowHTTP: true,
+			DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, network, addr)
+			},
+		},
+	}
+}
+
+func TestServerHandlerH2C(t *testing.T) {
+	mux := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.Proto)
+	})
+
+	tests := []struct {
+		name    string
+		enabled bool
+		wantErr bool
+	}{
+		{"enabled", true, false},
+		{"disabled", false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(serverHandler(&config.Config{H2CEnabled: tt.enabled}, mux))
+			defer server.Close()
+
+			resp, err := h2cClient().Get(server.URL)
+			if tt.wantErr {
+				if err == nil {
+					resp.Body.Close()
+					t.Fatal("h2c request succeeded with h2c disabled")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("h2c request failed: %v", err)
+			}
+			defer resp.Body.Close()
+			body, _ := io.ReadAll(resp.Body)
+			if string(body) != "HTTP/2.0" {
+				t.Fatalf("served as %q, want HTTP/2.0", body)
+			}
+		})
+	}
+}
+
+func TestShutdownServerRespectsTimeout(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+	})}
+	go server.Serve(listener)
+	defer server.Close()
+
+	go http.Get("http://" + listener.Addr().String())
+	<-started
+
+	const timeout = 100 * time.Millisecond
+	start := time.Now()
+	err = shutdownServer(server, timeout)
+	elapsed := time.Since(start)
+
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("shutdown error = %v, want deadline exceeded", err)
+	}
+	if elapsed < timeout || elapsed > timeout+time.Second {
+		t.Fatalf("shutdown took %s, want about %s", elapsed, timeout)
+	}
+}
+
+func TestShutdownServerIdle(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &http.Server{Handler: http.NotFoundHandler()}
+	go server.Serve(listener)
+
+	if err := shutdownServer(server, time.Second); err != nil {
+		t.Fatalf("idle shutdown failed: %v", err)
+	}
+}
+
+func TestInitLoggerFile(t *testing.T) {
+	for _, format := range []string{"json", "console"} {
+		t.Run(format, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "router.log")
+			cfg := &config.Config{
+				LogLevel:      "info",
+				LogFormat:     format,
+				LogFile:       path,
+				LogMaxSizeMB:  1,
+				LogMaxBackups: 1,
+				LogMaxAgeDays: 1,
+			}
+			logger, level, err := initLogger(cfg)
+			if err != nil {
+				t.Fatalf("initLogger: %v", err)
+			}
+			defer logger.Sync()
+
+			logger.Debug("below the level")
+			for i := 0; i < 300; i++ {
+				logger.Info("repeated")
+			}
+			level.SetLevel(zapcore.DebugLevel)
+			logger.Debug("after raising the level")
+
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			log := string(data)
+			// The production (json) config samples repeats beyond the first
+			// 100 per second, as it does on stdout; the development one doesn't
+			lines := strings.Count(log, "\n")
+			if format == "json" && (lines < 100 || lines >= 300) {
+				t.Errorf("wrote %d lines, want sampling to drop most of 300 repeats", lines)
+			}
+			if format == "console" && lines != 301 {
+				t.Errorf("wrote %d lines, want all 301", lines)
+			}
+			if strings.Contains(log, "below the level") || !strings.Contains(log, "after raising the level") {
+				t.Error("file output does not follow the atomic level")
+			}
+			if strings.Contains(log, "\x1b[") {
+				t.Error("file output contains ANSI colors")
+			}
+			if !strings.Contains(log, "main_test.go") {
+				t.Error("file output is missing the caller")
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package ml
+
+import (
+	"math"
+
+	"go.uber.org/zap"
+)
+
+// accuracyCheckInterval is how many new calibration records trigger an accuracy check
+const accuracyCheckInterval = 20
+
+// calibrationMAE returns the mean absolute error of predictions against actuals
+// before and after calibration. Caller must hold calibrationMutex.
+func (c *Client) calibrationMAE() (pre, post float64) {
+	if c.usesEMA() {
+		return c.ema.maePre, c.ema.maePost
+	}
+	if len(c.calibrationData) == 0 {
+		return 0, 0
+	}
+	for _, record := range c.calibrationData {
+		pre += math.Abs(record.RawPredictedLatency - record.ActualLatency)
+		post += math.Abs(record.PredictedLatency - record.ActualLatency)
+	}
+	n := float64(len(c.calibrationData))
+	return pre / n, post / n
+}
+
+// checkCalibrationAccuracy warns when calibration is making predictions worse.
+// Caller must hold calibrationMutex.
+func (c *Client) checkCalibrationAccuracy() {
+	pre, post := c.calibrationMAE()
+	if post >= pre && pre > 0 {
+		c.logger.Warn("Calibration is not improving prediction accuracy, it may be miscalibrated",
+			zap.Float64("mae_pre_calibration", pre),
+			zap.Float64("mae_post_calibration", post),
+			zap.Int("records", c.calibrationRecords()))
+		return
+	}
+	c.logger.Debug("Calibration accuracy",
+		zap.Float64("mae_pre_calibration", pre),
+		zap.Float64("mae_post_calibration", post))
+}
+
+// RawPredictedLatency returns the pre-calibration predicted latency for nodeID,
+// falling back to the current prediction when no breakdown was captured
+func (p *PredictionResponse) RawPredictedLatency(nodeID string) float64 {
+	if p.Decision != nil {
+		if breakdown, ok := p.Decision.Nodes[nodeID]; ok {
+			return breakdown.PredictedLatencyMS
+		}
+	}
+	for _, node := range p.AllPredictions {
+		if node.NodeID == nodeID {
+			return node.PredictedLatencyMS
+		}
+	}
+	return p.RecommendationDetails.PredictedLatencyMS
+}
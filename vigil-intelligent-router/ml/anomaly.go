@@ -0,0 +1,14 @@
+package ml
+
+import "errors"
+
+// Policies for when the ML flags every node anomalous
+const (
+	AllAnomalousProceed  = "proceed"
+	AllAnomalousFallback = "fallback"
+	AllAnomalousDegraded = "degraded"
+)
+
+// ErrAllNodesAnomalous is returned under the degraded policy when the ML
+// flagged every node anomalous
+var ErrAllNodesAnomalous = errors.New("all nodes flagged anomalous")
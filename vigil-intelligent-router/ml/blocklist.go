@@ -0,0 +1,79 @@
+package ml
+
+import (
+	"fmt"
+	"sort"
+
+	"go.uber.org/zap"
+)
+
+// Block excludes nodeID from selection until it is unblocked. Node IDs missing
+// from the node URL map are rejected, since a typo would otherwise block nothing.
+func (c *Client) Block(nodeID string) error {
+	if _, ok := c.nodeURLMap[nodeID]; !ok {
+		return fmt.Errorf("unknown node ID: %s (not found in configuration)", nodeID)
+	}
+
+	c.blocklistMutex.Lock()
+	c.blocklist[nodeID] = struct{}{}
+	c.blocklistMutex.Unlock()
+
+	c.logger.Warn("Node added to blocklist", zap.String("node", nodeID))
+	return nil
+}
+
+// Unblock makes nodeID eligible for selection again
+func (c *Client) Unblock(nodeID string) {
+	c.blocklistMutex.Lock()
+	delete(c.blocklist, nodeID)
+	c.blocklistMutex.Unlock()
+
+	c.logger.Info("Node removed from blocklist", zap.String("node", nodeID))
+}
+
+// Blocklist returns the currently blocked node IDs in sorted order
+func (c *Client) Blocklist() []string {
+	c.blocklistMutex.RLock()
+	defer c.blocklistMutex.RUnlock()
+
+	nodes := make([]string, 0, len(c.blocklist))
+	for nodeID := range c.blocklist {
+		nodes = append(nodes, nodeID)
+	}
+	sort.Strings(nodes)
+	return nodes
+}
+
+func (c *Client) isBlocked(nodeID string) bool {
+	c.blocklistMutex.RLock()
+	defer c.blocklistMutex.RUnlock()
+	_, blocked := c.blocklist[nodeID]
+	return blocked
+}
+
+// ExclusionReason returns why nodeID must not be selected (blocklisted,
+// draining, in maintenance, SLA-demoted or the shadow node), or "" if it is
+// eligible. Routes that bypass ML selection must check it too.
+func (c *Client) ExclusionReason(nodeID string) string {
+	return c.exclusionReason(nodeID)
+}
+
+// exclusionReason returns why nodeID must not be selected, or "" if it is eligible
+func (c *Client) exclusionReason(nodeID string) string {
+	if c.isBlocked(nodeID) {
+		return "blocklisted"
+	}
+	if c.isDraining(nodeID) {
+		return "draining"
+	}
+	if c.underMaintenance(nodeID) {
+		return "maintenance"
+	}
+	if c.slaDemoted(nodeID) {
+		return "sla_demoted"
+	}
+	if nodeID == c.options.ShadowNode {
+		return "shadow"
+	}
+	return ""
+}
@@ -0,0 +1,21 @@
+package ml
+
+import (
+	"reflect"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestBlockUnknownNode(t *testing.T) {
+	c := NewClient(nil, "", 0, map[string]string{"a": "http://a.invalid"}, Options{}, zap.NewNop())
+	if err := c.Block("typo"); err == nil {
+		t.Error("Block accepted a node ID with no URL mapping")
+	}
+	if err := c.Block("a"); err != nil {
+		t.Errorf("Block(a): %v", err)
+	}
+	if got := c.Blocklist(); !reflect.DeepEqual(got, []string{"a"}) {
+		t.Errorf("blocklist = %v, want [a]", got)
+	}
+}
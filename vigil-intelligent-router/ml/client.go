@@ -6,8 +6,11 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"math"
+	"math/rand"
 	"net/http"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"go.uber.org/zap"
@@ -15,44 +18,250 @@ import (
 
 // CalibrationRecord tracks a single prediction vs actual measurement
 type CalibrationRecord struct {
-	NodeID           string
-	PredictedLatency float64
-	ActualLatency    float64
-	Timestamp        time.Time
+	NodeID              string
+	RawPredictedLatency float64 // ML prediction before calibration was applied
+	PredictedLatency    float64
+	ActualLatency       float64
+	Timestamp           time.Time
 }
 
 // Client handles communication with the ML prediction service
 type Client struct {
 	httpClient       *http.Client
-	predictURL       string
+	predictURLs      []string
 	metricsURL       string
 	nodeURLMap       map[string]string
 	logger           *zap.Logger
+	warnings         *RateLimitedLogger // for warnings repeated per request
+	options          Options
 	
 	// Auto-calibration
 	calibrationMutex sync.RWMutex
 	calibrationData  []CalibrationRecord
+	ema              emaCalibration
 	calibrationLimit int
+	accuracyCheckDue int // records since the last accuracy check
+
+	// Observed request outcomes per node, and the AIMD weights they drive
+	outcomeMutex       sync.RWMutex
+	outcomes           map[string][]bool
+	reliabilityWeights map[string]float64
+
+	// Manually excluded nodes
+	blocklistMutex sync.RWMutex
+	blocklist      map[string]struct{}
+
+	// Nodes drained via the admin API
+	drainMutex sync.RWMutex
+	draining   map[string]struct{}
+
+	// Latency SLA violations and demotions
+	slaMutex        sync.Mutex
+	slaViolations   map[string][]time.Time
+	slaDemotedUntil map[string]time.Time
+
+	// Nodes last seen inside a maintenance window, for entry/exit logging
+	maintenanceMutex sync.Mutex
+	inMaintenance    map[string]bool
+
+	// ML vs hybrid disagreement counters
+	decisions     atomic.Int64
+	disagreements atomic.Int64
+
+	// Shadow ML model comparisons
+	shadow shadowCounters
+
+	// Inputs to the latest hybrid scoring, for score previews
+	lastScoring atomic.Pointer[scoringInput]
+
+	// Healthy node count in the latest recommendation's metrics (-1 until known)
+	lastHealthy atomic.Int64
+
+	// Per-node health flags from the latest recommendation's metrics
+	lastHealth atomic.Pointer[map[string]bool]
+
+	// Outcome of the latest ML prediction: 1 ok, 0 failed, -1 not yet tried
+	mlReachable atomic.Int32
+
+	// Recent recommendation durations for the adaptive query timeout
+	latencies mlLatencyTracker
+
+	// Metrics pushed by the collector stream, when subscribed
+	streamClient *http.Client
+	stream       metricsSnapshot
+}
+
+// Options holds tunables for how the client scores nodes
+type Options struct {
+	// SuccessRateWeight blends the observed failure rate into the ML failure
+	// probability: 0 uses the ML value only, 1 uses observations only
+	SuccessRateWeight float64
+
+	// TrustMLVerbatim skips hybrid scoring and calibration and routes to the
+	// ML service's recommended_node as-is
+	TrustMLVerbatim bool
+
+	// OnDisagreement decides between the ML recommendation and the hybrid
+	// winner when they differ: prefer_hybrid, prefer_ml or log_only
+	OnDisagreement string
+
+	// RoutingStrategy is hybrid (ML plus recent metrics) or priority (the
+	// first available node in NodePriority)
+	RoutingStrategy string
+	NodePriority    []string
+
+	// CalibrationMaxOffsetFraction warns when a node's calibration offset
+	// exceeds this fraction of its prediction (0 disables);
+	// CalibrationSkipOutOfBounds also leaves that prediction uncalibrated
+	CalibrationMaxOffsetFraction float64
+	CalibrationSkipOutOfBounds   bool
+
+	// MetricsAPIVersion selects the Data Collector response format: v1 or v2
+	MetricsAPIVersion string
+
+	// MetricsFetchRetries retries a failed metrics fetch this many times,
+	// starting MetricsFetchRetryBackoff apart
+	MetricsFetchRetries      int
+	MetricsFetchRetryBackoff time.Duration
+
+	// LogRateLimitInterval summarizes repeats of per-request warnings
+	// logged within it (0 logs every one)
+	LogRateLimitInterval time.Duration
+
+	// AllAnomalousPolicy decides what happens when the ML flags every node
+	// anomalous: proceed, fallback or degraded
+	AllAnomalousPolicy string
+
+	// MaxBlockHeightGap excludes nodes lagging more blocks than this (0 disables)
+	MaxBlockHeightGap int
+
+	// BlockGapPenalty is added to the hybrid score per block of lag
+	BlockGapPenalty float64
+
+	// UnhealthyPolicy handles nodes the collector flags unhealthy: "exclude"
+	// removes them from selection, "penalize" adds UnhealthyPenalty to their score
+	UnhealthyPolicy  string
+	UnhealthyPenalty float64
+
+	// PredictionWeight and RecentWeight blend the ML predicted latency with
+	// the recent observed average; both 0 uses 0.7 and 0.3
+	PredictionWeight float64
+	RecentWeight     float64
+
+	// CalibrationMode is "window" (mean over the last 100 records) or "ema"
+	// (exponential moving average with weight CalibrationAlpha per record)
+	CalibrationMode  string
+	CalibrationAlpha float64
+
+	// CalibrationSampleRate is the fraction of requests whose actual latency
+	// is recorded for calibration (1 records every request)
+	CalibrationSampleRate float64
+
+	// PreferredNode is chosen whenever its score is within
+	// PreferredNodeTolerancePercent of the best node's
+	PreferredNode                 string
+	PreferredNodeTolerancePercent float64
+
+	// SLALatencyMS is the per-request latency SLA (0 disables). Breaching it
+	// more than SLAViolationThreshold times within SLAWindow excludes the node
+	// for SLACooldown.
+	SLALatencyMS          float64
+	SLAViolationThreshold int
+	SLAWindow             time.Duration
+	SLACooldown           time.Duration
+
+	// ReliabilityWeighting divides each node's hybrid score by an AIMD weight:
+	// every failure multiplies it by ReliabilityDecreaseFactor and every success
+	// adds ReliabilityRecoveryStep, back up to 1
+	ReliabilityWeighting      bool
+	ReliabilityDecreaseFactor float64
+	ReliabilityRecoveryStep   float64
+
+	// AdaptiveTimeout bounds each ML prediction call by the p99 of recent
+	// prediction round-trips times AdaptiveTimeoutFactor, never below
+	// AdaptiveTimeoutMin (see QueryTimeout)
+	AdaptiveTimeout       bool
+	AdaptiveTimeoutFactor float64
+	AdaptiveTimeoutMin    time.Duration
+
+	// LogMLIO debug-logs every ML request payload and response body, each
+	// cut to LogMLIOMaxBytes (0 logs them whole)
+	LogMLIO         bool
+	LogMLIOMaxBytes int
+
+	// RequireJSONResponse rejects prediction responses whose Content-Type is
+	// not JSON before decoding them
+	RequireJSONResponse bool
+
+	// CalibrationShrinkage pulls per-node calibration offsets toward the
+	// global offset; it acts like this many extra samples at the global value
+	CalibrationShrinkage float64
+
+	// StrictFallback makes metrics-only fallback fail rather than pick a node
+	// whose latest metrics report it unhealthy
+	StrictFallback bool
+
+	// OverrideMinImprovementPercent keeps the ML's recommended_node unless the
+	// hybrid winner scores at least this much better (prefer_hybrid only)
+	OverrideMinImprovementPercent float64
+
+	// DedupMetrics drops repeated scrapes (same node and timestamp) before
+	// averaging and prediction
+	DedupMetrics bool
+
+	// InMaintenance reports whether a node is inside a scheduled maintenance
+	// window; nil disables maintenance checks
+	InMaintenance func(nodeID string, at time.Time) bool
+
+	// ShadowPredictURL is a second ML predict endpoint queried in the
+	// background for comparison only (empty disables)
+	ShadowPredictURL string
+
+	// EnsembleMethod combines responses from multiple ML endpoints:
+	// "average" or "vote"
+	EnsembleMethod string
+
+	// ShadowNode only receives shadow copies of requests and is never
+	// routed to (empty disables)
+	ShadowNode string
 }
 
 // NewClient creates a new ML client
-func NewClient(predictURL, metricsURL string, timeout time.Duration, nodeURLMap map[string]string, logger *zap.Logger) *Client {
-	return &Client{
+func NewClient(predictURLs []string, metricsURL string, timeout time.Duration, nodeURLMap map[string]string, opts Options, logger *zap.Logger) *Client {
+	if opts.PredictionWeight == 0 && opts.RecentWeight == 0 {
+		opts.PredictionWeight, opts.RecentWeight = 0.7, 0.3
+	}
+	c := &Client{
 		httpClient: &http.Client{
 			Timeout: timeout,
 			Transport: &http.Transport{
 				MaxIdleConns:        100,
 				MaxIdleConnsPerHost: 10,
 				IdleConnTimeout:     90 * time.Second,
+				ForceAttemptHTTP2:   true,
 			},
 		},
-		predictURL:       predictURL,
-		metricsURL:       metricsURL,
-		nodeURLMap:       nodeURLMap,
-		logger:           logger,
-		calibrationData:  make([]CalibrationRecord, 0, 100),
-		calibrationLimit: 100,
+		streamClient:       &http.Client{},
+		predictURLs:        predictURLs,
+		metricsURL:         metricsURL,
+		nodeURLMap:         nodeURLMap,
+		logger:             logger,
+		warnings:           NewRateLimitedLogger(logger, opts.LogRateLimitInterval),
+		options:            opts,
+		calibrationData:    make([]CalibrationRecord, 0, 100),
+		calibrationLimit:   100,
+		ema:                newEMACalibration(),
+		outcomes:           make(map[string][]bool),
+		reliabilityWeights: make(map[string]float64),
+		blocklist:          make(map[string]struct{}),
+		draining:           make(map[string]struct{}),
+		inMaintenance:      make(map[string]bool),
+		slaViolations:      make(map[string][]time.Time),
+		slaDemotedUntil:    make(map[string]time.Time),
 	}
+	c.lastHealthy.Store(-1)
+	c.mlReachable.Store(-1)
+	return c
 }
 
 // MetricData represents a single metric data point
@@ -89,17 +298,49 @@ type PredictionResponse struct {
 	Timestamp              string           `json:"timestamp"`
 	AllPredictions         []NodePrediction `json:"all_predictions"`
 	RecommendationDetails  NodePrediction   `json:"recommendation_details"`
+
+	// Decision is the router-side scoring breakdown; it is not part of the ML API
+	Decision *DecisionBreakdown `json:"-"`
+
+	// ComputedAt is when the router produced this recommendation
+	ComputedAt time.Time `json:"-"`
+
+	// decisionAge is the age at which routing settled on the recommended node
+	decisionAge time.Duration
+	decided     bool
 }
 
-// GetRecommendation fetches metrics and gets a routing recommendation with hybrid scoring
+// GetRecommendation fetches metrics and gets a routing recommendation with
+// hybrid scoring, stamped with the time it was computed
 func (c *Client) GetRecommendation(ctx context.Context) (*PredictionResponse, error) {
+	prediction, err := c.recommend(ctx)
+	if err != nil {
+		return nil, err
+	}
+	prediction.ComputedAt = time.Now()
+	return prediction, nil
+}
+
+// recommend computes a recommendation for GetRecommendation
+func (c *Client) recommend(ctx context.Context) (*PredictionResponse, error) {
 	// Step 1: Fetch metrics from Data Collector
-	metrics, err := c.fetchMetrics(ctx)
+	metrics, err := c.fetchMetricsWithRetry(ctx)
 	if err != nil {
-		c.logger.Warn("Failed to fetch metrics, will try ML service anyway", zap.Error(err))
+		c.warnings.Warn("Failed to fetch metrics, will try ML service anyway", zap.Error(err))
 		
 	}
 
+	// Drop or clamp nonsensical values before they reach scoring or the ML payload
+	metrics = c.sanitizeMetrics(metrics)
+	if c.options.DedupMetrics {
+		metrics = c.dedupMetrics(metrics)
+	}
+	if len(metrics) > 0 {
+		health := latestHealth(metrics)
+		c.lastHealth.Store(&health)
+		c.lastHealthy.Store(int64(c.countHealthy(health)))
+	}
+
 	
 	recentAvgs := calculateRecentAverages(metrics)
 	
@@ -107,20 +348,71 @@ func (c *Client) GetRecommendation(ctx context.Context) (*PredictionResponse, er
 		zap.Int("node_count", len(recentAvgs)),
 		zap.Any("sample_avgs", recentAvgs))
 
+	// Priority routing follows NODE_PRIORITY and never asks the ML
+	if c.options.RoutingStrategy == StrategyPriority {
+		return c.priorityRecommendation(metrics, recentAvgs)
+	}
+
 	// Step 2: Try to get ML prediction
 	prediction, err := c.getPrediction(ctx, metrics)
 	if err != nil {
-		c.logger.Warn("ML prediction failed, falling back to metrics-only routing", zap.Error(err))
+		c.mlReachable.Store(0)
+		c.warnings.Warn("ML prediction failed, falling back to metrics-only routing", zap.Error(err))
 		// Fallback: Use recent metrics to pick best node
 		return c.fallbackToMetricsOnly(metrics, recentAvgs)
 	}
+	c.mlReachable.Store(1)
+
+	prediction.Decision = &DecisionBreakdown{
+		Source:            "hybrid",
+		MLRecommendedNode: prediction.RecommendedNode,
+		RecentAverages:    recentAvgs,
+	}
+
+	if c.options.TrustMLVerbatim {
+		prediction.Decision.Source = "ml_verbatim"
+		if reason := c.exclusionReason(prediction.RecommendedNode); reason != "" {
+			return nil, fmt.Errorf("ML recommended node %s is not eligible: %s", prediction.RecommendedNode, reason)
+		}
+		c.logger.Info("Using ML recommendation verbatim",
+			zap.String("recommended_node", prediction.RecommendedNode))
+		return prediction, nil
+	}
 
 	// Step 3: Apply hybrid scoring (combine ML prediction with recent actual latency)
-	prediction = c.applyHybridScoring(prediction, recentAvgs)
+	signals := nodeSignals{
+		recentAvgs: recentAvgs,
+		blockGaps:  latestBlockGaps(metrics),
+		health:     latestHealth(metrics),
+	}
+	c.rememberScoringInput(prediction, signals)
+	prediction = c.applyHybridScoring(prediction, signals, c.scoringWeightsFor(ctx))
+	if prediction.Decision.AllAnomalous {
+		switch c.options.AllAnomalousPolicy {
+		case AllAnomalousFallback:
+			c.warnings.Warn("ML flagged every node anomalous, falling back to metrics-only routing")
+			return c.fallbackToMetricsOnly(metrics, recentAvgs)
+		case AllAnomalousDegraded:
+			c.warnings.Warn("ML flagged every node anomalous, refusing to route")
+			return nil, ErrAllNodesAnomalous
+		default:
+			c.warnings.Warn("ML flagged every node anomalous, proceeding with least-bad node",
+				zap.String("recommended_node", prediction.RecommendedNode))
+		}
+	}
+	c.resolveDisagreement(prediction)
 
 	// Step 4: Apply auto-calibration to correct for environment-specific offsets
 	prediction = c.applyCalibration(prediction)
 
+	// Hybrid scoring only keeps the ML pick when no node was eligible
+	if reason := c.ineligibleReason(prediction); reason != "" {
+		return nil, &NoEligibleNodesError{Reasons: c.RejectionReasons(prediction)}
+	}
+
+	// Soft preference for a designated node when it is nearly as good
+	c.applyPreferredNode(prediction)
+
 	c.logger.Info("Hybrid recommendation selected",
 		zap.String("recommended_node", prediction.RecommendedNode),
 		zap.Float64("hybrid_score", prediction.RecommendationDetails.CostScore))
@@ -160,15 +452,20 @@ func calculateRecentAverages(metrics []MetricData) map[string]float64 {
 	return averages
 }
 
-// applyHybridScoring combines ML prediction with recent actual latency
-func (c *Client) applyHybridScoring(prediction *PredictionResponse, recentAvgs map[string]float64) *PredictionResponse {
-	const (
-		predictionWeight = 0.7  // Weight for ML prediction
-		recentWeight     = 0.3  // Weight for recent actual latency
-	)
+// applyHybridScoring combines ML prediction with recent actual latency and node freshness
+func (c *Client) applyHybridScoring(prediction *PredictionResponse, signals nodeSignals, weights ScoringWeights) *PredictionResponse {
+	recentAvgs := signals.recentAvgs
+	predictionWeight := weights.PredictionWeight // Weight for ML prediction
+	recentWeight := weights.RecentWeight         // Weight for recent actual latency
 	
 	bestNode := ""
 	bestScore := float64(999999) 
+	anomalous := 0
+	successRates := c.GetSuccessRates()
+	reliabilityWeights := c.GetReliabilityWeights()
+	if prediction.Decision == nil {
+		prediction.Decision = &DecisionBreakdown{Source: "hybrid", RecentAverages: recentAvgs}
+	}
 	
 	// Recalculate scores for all nodes using hybrid approach
 	for i := range prediction.AllPredictions {
@@ -178,27 +475,80 @@ func (c *Client) applyHybridScoring(prediction *PredictionResponse, recentAvgs m
 		predictedLatency := node.PredictedLatencyMS
 		recentAvg, hasRecent := recentAvgs[nodeID]
 		
+		breakdown := prediction.Decision.node(nodeID)
+		breakdown.PredictedLatencyMS = predictedLatency
+		breakdown.MLFailureProb = node.FailureProb
+		
 		var hybridScore float64
 		if hasRecent {
 			
 			hybridScore = (predictionWeight * predictedLatency) + (recentWeight * recentAvg)
+			breakdown.PredictionTerm = predictionWeight * predictedLatency
+			breakdown.RecentTerm = recentWeight * recentAvg
+			breakdown.RecentAvgMS = &recentAvg
 		} else {
 			
 			hybridScore = predictedLatency
+			breakdown.PredictionTerm = predictedLatency
 		}
 		
 		
-		failurePenalty := node.FailureProb * 1000 // High penalty for risky nodes
+		// Blend in the failure rate we have observed ourselves, if any
+		successRate, hasObserved := successRates[nodeID]
+		failureProb := blendFailureProb(weights.SuccessRateWeight, node.FailureProb, successRate, hasObserved)
+		
+		failurePenalty := failureProb * 1000 // High penalty for risky nodes
 		hybridScore += failurePenalty
+		breakdown.FailureProb = failureProb
+		breakdown.FailurePenalty = failurePenalty
 		
+		// Penalize nodes lagging behind the chain tip
+		blockGap, hasGap := signals.blockGaps[nodeID]
+		if hasGap && blockGap > 0 {
+			breakdown.BlockGap = blockGap
+			breakdown.BlockGapPenalty = float64(blockGap) * weights.BlockGapPenalty
+			hybridScore += breakdown.BlockGapPenalty
+		}
+		
+		// Penalize nodes the collector currently flags as unhealthy
+		healthy, hasHealth := signals.health[nodeID]
+		unhealthy := hasHealth && !healthy
+		if unhealthy && c.options.UnhealthyPolicy == "penalize" {
+			breakdown.UnhealthyPenalty = weights.UnhealthyPenalty
+			hybridScore += breakdown.UnhealthyPenalty
+		}
 		
 		if node.AnomalyDetected {
 			hybridScore *= 1.2 
+			breakdown.AnomalyMultiplier = 1.2
+			anomalous++
+		}
+		
+		// Nodes that have been failing lately score worse until they recover
+		if weight, ok := reliabilityWeights[nodeID]; ok && weight < 1 {
+			hybridScore /= weight
+			breakdown.ReliabilityWeight = weight
 		}
 		
 		
 		node.CostScore = hybridScore
+		breakdown.HybridScore = hybridScore
 		
+		// Excluded nodes are scored for visibility but never chosen
+		reason := c.exclusionReason(nodeID)
+		if reason == "" && hasGap && c.options.MaxBlockHeightGap > 0 && blockGap > c.options.MaxBlockHeightGap {
+			reason = "block_height_lag"
+		}
+		if reason == "" && unhealthy && c.options.UnhealthyPolicy != "penalize" {
+			reason = "unhealthy"
+		}
+		if reason != "" {
+			breakdown.Excluded = reason
+			c.logger.Debug("Node excluded from selection",
+				zap.String("node", nodeID),
+				zap.String("reason", reason))
+			continue
+		}
 		
 		if bestNode == "" || hybridScore < bestScore {
 			bestNode = nodeID
@@ -209,10 +559,13 @@ func (c *Client) applyHybridScoring(prediction *PredictionResponse, recentAvgs m
 			zap.String("node", nodeID),
 			zap.Float64("predicted", predictedLatency),
 			zap.Float64("recent_avg", recentAvg),
+			zap.Float64("failure_prob", failureProb),
 			zap.Float64("hybrid_score", hybridScore),
 			zap.Bool("has_recent", hasRecent))
 	}
 	
+	// The anomaly multiplier can't tell nodes apart when every one is flagged
+	prediction.Decision.AllAnomalous = anomalous > 0 && anomalous == len(prediction.AllPredictions)
 	
 	if bestNode != "" {
 		prediction.RecommendedNode = bestNode
@@ -227,54 +580,30 @@ func (c *Client) applyHybridScoring(prediction *PredictionResponse, recentAvgs m
 	return prediction
 }
 
+// offsetOutOfBounds reports whether a calibration offset is larger than
+// CalibrationMaxOffsetFraction of the prediction it would correct
+func (c *Client) offsetOutOfBounds(offset, predicted float64) bool {
+	bound := c.options.CalibrationMaxOffsetFraction
+	return bound > 0 && math.Abs(offset) > bound*predicted
+}
+
 // applyCalibration adjusts predictions based on learned offset between predictions and actuals
 func (c *Client) applyCalibration(prediction *PredictionResponse) *PredictionResponse {
 	c.calibrationMutex.RLock()
 	defer c.calibrationMutex.RUnlock()
 	
-	if len(c.calibrationData) < 5 {
+	if c.calibrationRecords() < 5 {
 		// Not enough data for calibration yet
 		c.logger.Debug("Insufficient calibration data",
-			zap.Int("records", len(c.calibrationData)))
+			zap.Int("records", c.calibrationRecords()))
 		return prediction
 	}
 	
-	// Calculate per-node offsets (predicted - actual)
-	nodeOffsets := make(map[string][]float64)
-	for _, record := range c.calibrationData {
-		offset := record.PredictedLatency - record.ActualLatency
-		nodeOffsets[record.NodeID] = append(nodeOffsets[record.NodeID], offset)
-	}
-	
-	// Calculate average offset per node
-	nodeAvgOffsets := make(map[string]float64)
-	for nodeID, offsets := range nodeOffsets {
-		if len(offsets) == 0 {
-			continue
-		}
-		sum := 0.0
-		for _, offset := range offsets {
-			sum += offset
-		}
-		nodeAvgOffsets[nodeID] = sum / float64(len(offsets))
-	}
-	
-	// Calculate global offset as fallback
-	globalOffset := 0.0
-	totalRecords := 0
-	for _, offsets := range nodeOffsets {
-		for _, offset := range offsets {
-			globalOffset += offset
-			totalRecords++
-		}
-	}
-	if totalRecords > 0 {
-		globalOffset /= float64(totalRecords)
-	}
+	nodeAvgOffsets, globalOffset := c.calibrationOffsets()
 	
 	c.logger.Info("Calibration offsets calculated",
 		zap.Float64("global_offset", globalOffset),
-		zap.Int("total_records", len(c.calibrationData)))
+		zap.Int("total_records", c.calibrationRecords()))
 	
 	// Apply calibration to all predictions
 	for i := range prediction.AllPredictions {
@@ -286,9 +615,25 @@ func (c *Client) applyCalibration(prediction *PredictionResponse) *PredictionRes
 			offset = globalOffset
 		}
 		
-		// Apply calibration
+		// An offset this large points at a broken model or bad actuals
 		originalLatency := node.PredictedLatencyMS
+		if c.offsetOutOfBounds(offset, originalLatency) {
+			c.warnings.Warn("Calibration offset exceeds sanity bound, model or actuals may be broken",
+				zap.String("node", node.NodeID),
+				zap.Float64("offset", offset),
+				zap.Float64("predicted", originalLatency),
+				zap.Float64("max_offset_fraction", c.options.CalibrationMaxOffsetFraction),
+				zap.Bool("skipped", c.options.CalibrationSkipOutOfBounds))
+			if c.options.CalibrationSkipOutOfBounds {
+				offset = 0
+			}
+		}
+		
+		// Apply calibration
 		node.PredictedLatencyMS = originalLatency - offset
+		if prediction.Decision != nil {
+			prediction.Decision.node(node.NodeID).CalibrationOffset = offset
+		}
 		
 		// Ensure non-negative
 		if node.PredictedLatencyMS < 0 {
@@ -313,23 +658,100 @@ func (c *Client) applyCalibration(prediction *PredictionResponse) *PredictionRes
 	return prediction
 }
 
-// RecordActual records actual latency for calibration learning
-func (c *Client) RecordActual(nodeID string, predictedLatency, actualLatency float64) {
+// shrinkOffset blends a node's offset toward the global offset in proportion
+// to how few samples back it: with shrinkage k and n samples the node's own
+// offset gets weight n/(n+k). A shrinkage of 0 uses the node offset as-is.
+func (c *Client) shrinkOffset(nodeOffset, globalOffset float64, samples int) float64 {
+	k := c.options.CalibrationShrinkage
+	if k <= 0 {
+		return nodeOffset
+	}
+	n := float64(samples)
+	return (n*nodeOffset + k*globalOffset) / (n + k)
+}
+
+// calibrationRecords returns how many records calibration has learned from.
+// Caller must hold calibrationMutex.
+func (c *Client) calibrationRecords() int {
+	if c.usesEMA() {
+		return c.ema.records
+	}
+	return len(c.calibrationData)
+}
+
+// calibrationOffsets returns the per-node and global offsets (predicted - actual)
+// to subtract from predictions, with per-node offsets shrunk toward the global
+// one by CalibrationShrinkage. Caller must hold calibrationMutex.
+func (c *Client) calibrationOffsets() (map[string]float64, float64) {
+	if c.usesEMA() {
+		nodeOffsets := make(map[string]float64, len(c.ema.offsets))
+		for nodeID, offset := range c.ema.offsets {
+			nodeOffsets[nodeID] = c.shrinkOffset(offset, c.ema.global, c.ema.samples[nodeID])
+		}
+		return nodeOffsets, c.ema.global
+	}
+
+	// Calculate per-node offsets (predicted - actual)
+	nodeOffsets := make(map[string][]float64)
+	for _, record := range c.calibrationData {
+		offset := record.PredictedLatency - record.ActualLatency
+		nodeOffsets[record.NodeID] = append(nodeOffsets[record.NodeID], offset)
+	}
+	
+	// Calculate average offset per node, and the global offset as fallback
+	nodeAvgOffsets := make(map[string]float64)
+	globalOffset := 0.0
+	for nodeID, offsets := range nodeOffsets {
+		sum := 0.0
+		for _, offset := range offsets {
+			sum += offset
+		}
+		nodeAvgOffsets[nodeID] = sum / float64(len(offsets))
+		globalOffset += sum
+	}
+	if len(c.calibrationData) > 0 {
+		globalOffset /= float64(len(c.calibrationData))
+	}
+	for nodeID, offset := range nodeAvgOffsets {
+		nodeAvgOffsets[nodeID] = c.shrinkOffset(offset, globalOffset, len(nodeOffsets[nodeID]))
+	}
+	return nodeAvgOffsets, globalOffset
+}
+
+// RecordActual records actual latency for calibration learning. rawPredictedLatency
+// is the ML prediction before calibration, used to track calibration accuracy.
+// Only CalibrationSampleRate of calls are kept; it reports whether this one was.
+func (c *Client) RecordActual(nodeID string, rawPredictedLatency, predictedLatency, actualLatency float64) bool {
+	if rate := c.options.CalibrationSampleRate; rate < 1 && rand.Float64() >= rate {
+		return false
+	}
+
 	c.calibrationMutex.Lock()
 	defer c.calibrationMutex.Unlock()
 	
 	record := CalibrationRecord{
-		NodeID:           nodeID,
-		PredictedLatency: predictedLatency,
-		ActualLatency:    actualLatency,
-		Timestamp:        time.Now(),
+		NodeID:              nodeID,
+		RawPredictedLatency: rawPredictedLatency,
+		PredictedLatency:    predictedLatency,
+		ActualLatency:       actualLatency,
+		Timestamp:           time.Now(),
 	}
 	
-	c.calibrationData = append(c.calibrationData, record)
+	if c.usesEMA() {
+		c.recordEMA(record)
+	} else {
+		c.calibrationData = append(c.calibrationData, record)
+		
+		// Keep only recent records
+		if len(c.calibrationData) > c.calibrationLimit {
+			c.calibrationData = c.calibrationData[len(c.calibrationData)-c.calibrationLimit:]
+		}
+	}
 	
-	// Keep only recent records
-	if len(c.calibrationData) > c.calibrationLimit {
-		c.calibrationData = c.calibrationData[len(c.calibrationData)-c.calibrationLimit:]
+	c.accuracyCheckDue++
+	if c.accuracyCheckDue >= accuracyCheckInterval {
+		c.accuracyCheckDue = 0
+		c.checkCalibrationAccuracy()
 	}
 	
 	c.logger.Debug("Recorded calibration data",
@@ -337,7 +759,8 @@ func (c *Client) RecordActual(nodeID string, predictedLatency, actualLatency flo
 		zap.Float64("predicted", predictedLatency),
 		zap.Float64("actual", actualLatency),
 		zap.Float64("offset", predictedLatency-actualLatency),
-		zap.Int("total_records", len(c.calibrationData)))
+		zap.Int("total_records", c.calibrationRecords()))
+	return true
 }
 
 // GetCalibrationStats returns current calibration statistics
@@ -345,48 +768,46 @@ func (c *Client) GetCalibrationStats() map[string]interface{} {
 	c.calibrationMutex.RLock()
 	defer c.calibrationMutex.RUnlock()
 	
-	if len(c.calibrationData) == 0 {
+	if c.calibrationRecords() == 0 {
 		return map[string]interface{}{
 			"records": 0,
 			"status":  "no_data",
 		}
 	}
 	
-	// Calculate per-node offsets
-	nodeOffsets := make(map[string][]float64)
-	for _, record := range c.calibrationData {
-		offset := record.PredictedLatency - record.ActualLatency
-		nodeOffsets[record.NodeID] = append(nodeOffsets[record.NodeID], offset)
-	}
-	
-	// Calculate average offset per node
-	nodeAvgOffsets := make(map[string]float64)
-	for nodeID, offsets := range nodeOffsets {
-		if len(offsets) == 0 {
-			continue
-		}
-		sum := 0.0
-		for _, offset := range offsets {
-			sum += offset
-		}
-		nodeAvgOffsets[nodeID] = sum / float64(len(offsets))
-	}
-	
-	// Global offset
-	globalOffset := 0.0
-	for _, record := range c.calibrationData {
-		globalOffset += record.PredictedLatency - record.ActualLatency
-	}
-	globalOffset /= float64(len(c.calibrationData))
+	nodeAvgOffsets, globalOffset := c.calibrationOffsets()
+	maePre, maePost := c.calibrationMAE()
 	
 	return map[string]interface{}{
-		"records":       len(c.calibrationData),
-		"global_offset": globalOffset,
-		"node_offsets":  nodeAvgOffsets,
-		"status":        "active",
+		"records":              c.calibrationRecords(),
+		"global_offset":        globalOffset,
+		"node_offsets":         nodeAvgOffsets,
+		"mae_pre_calibration":  maePre,
+		"mae_post_calibration": maePost,
+		"status":               "active",
 	}
 }
 
+// ResetCalibration discards all learned calibration records and observed
+// success rates, returning the number of calibration records cleared. Use it
+// after the ML model or node infrastructure changes.
+func (c *Client) ResetCalibration() int {
+	c.calibrationMutex.Lock()
+	cleared := c.calibrationRecords()
+	c.calibrationData = make([]CalibrationRecord, 0, c.calibrationLimit)
+	c.ema = newEMACalibration()
+	c.accuracyCheckDue = 0
+	c.calibrationMutex.Unlock()
+
+	c.outcomeMutex.Lock()
+	c.outcomes = make(map[string][]bool)
+	c.reliabilityWeights = make(map[string]float64)
+	c.outcomeMutex.Unlock()
+
+	c.logger.Info("Calibration data reset", zap.Int("records_cleared", cleared))
+	return cleared
+}
+
 func (c *Client) fallbackToMetricsOnly(metrics []MetricData, recentAvgs map[string]float64) (*PredictionResponse, error) {
 	if len(recentAvgs) == 0 {
 		return nil, fmt.Errorf("no metrics available for fallback routing")
@@ -397,6 +818,9 @@ func (c *Client) fallbackToMetricsOnly(metrics []MetricData, recentAvgs map[stri
 	bestLatency := float64(999999)
 	
 	for nodeID, avgLatency := range recentAvgs {
+		if c.exclusionReason(nodeID) != "" {
+			continue
+		}
 		
 		isHealthy := false
 		for i := len(metrics) - 1; i >= 0; i-- {
@@ -418,9 +842,13 @@ func (c *Client) fallbackToMetricsOnly(metrics []MetricData, recentAvgs map[stri
 		}
 	}
 	
-	if bestNode == "" {
+	// Lenient mode settles for an unhealthy node rather than failing
+	if bestNode == "" && !c.options.StrictFallback {
 		
 		for nodeID, avgLatency := range recentAvgs {
+			if c.exclusionReason(nodeID) != "" {
+				continue
+			}
 			if bestNode == "" || avgLatency < bestLatency {
 				bestNode = nodeID
 				bestLatency = avgLatency
@@ -429,7 +857,14 @@ func (c *Client) fallbackToMetricsOnly(metrics []MetricData, recentAvgs map[stri
 	}
 	
 	if bestNode == "" {
-		return nil, fmt.Errorf("no viable nodes found")
+		reasons := make(map[string]string, len(recentAvgs))
+		for nodeID := range recentAvgs {
+			reasons[nodeID] = c.exclusionReason(nodeID)
+			if reasons[nodeID] == "" {
+				reasons[nodeID] = "unhealthy"
+			}
+		}
+		return nil, &NoEligibleNodesError{Reasons: reasons}
 	}
 	
 	c.logger.Info("Fallback routing selected",
@@ -438,6 +873,10 @@ func (c *Client) fallbackToMetricsOnly(metrics []MetricData, recentAvgs map[stri
 	
 	
 	return &PredictionResponse{
+		Decision: &DecisionBreakdown{
+			Source:         "metrics_fallback",
+			RecentAverages: recentAvgs,
+		},
 		RecommendedNode: bestNode,
 		Explanation:     fmt.Sprintf("Fallback routing: selected %s based on recent metrics (avg: %.1fms)", bestNode, bestLatency),
 		Timestamp:       time.Now().Format(time.RFC3339),
@@ -462,10 +901,16 @@ func (c *Client) fallbackToMetricsOnly(metrics []MetricData, recentAvgs map[stri
 
 // fetchMetrics retrieves current metrics from the Data Collector
 func (c *Client) fetchMetrics(ctx context.Context) ([]MetricData, error) {
+	// Prefer the live snapshot from the metrics stream
+	if metrics, ok := c.stream.current(); ok {
+		return metrics, nil
+	}
+
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.metricsURL, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
+	setRequestID(req)
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
@@ -478,8 +923,12 @@ func (c *Client) fetchMetrics(ctx context.Context) ([]MetricData, error) {
 		return nil, fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, string(body))
 	}
 
-	var metrics []MetricData
-	if err := json.NewDecoder(resp.Body).Decode(&metrics); err != nil {
+	decode, err := decoderFor(c.options.MetricsAPIVersion)
+	if err != nil {
+		return nil, err
+	}
+	metrics, err := decode(resp.Body)
+	if err != nil {
 		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
 
@@ -496,6 +945,35 @@ func (c *Client) fetchMetrics(ctx context.Context) ([]MetricData, error) {
 	return metrics, nil
 }
 
+// fetchMetricsWithRetry retries a failed metrics fetch up to
+// MetricsFetchRetries times, doubling the backoff each time. It gives up early
+// rather than sleep past ctx's deadline.
+func (c *Client) fetchMetricsWithRetry(ctx context.Context) ([]MetricData, error) {
+	metrics, err := c.fetchMetrics(ctx)
+	backoff := c.options.MetricsFetchRetryBackoff
+	for attempt := 1; err != nil && attempt <= c.options.MetricsFetchRetries; attempt++ {
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) <= backoff {
+			break
+		}
+		c.logger.Debug("Metrics fetch failed, retrying",
+			zap.Int("attempt", attempt),
+			zap.Duration("backoff", backoff),
+			zap.Error(err))
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, err
+		case <-timer.C:
+		}
+		backoff *= 2
+
+		metrics, err = c.fetchMetrics(ctx)
+	}
+	return metrics, err
+}
+
 // getPrediction sends metrics to ML service and gets a prediction
 func (c *Client) getPrediction(ctx context.Context, metrics []MetricData) (*PredictionResponse, error) {
 	// Ensure each metric has NodeID populated from NodeName if needed
@@ -519,11 +997,38 @@ func (c *Client) getPrediction(ctx context.Context, metrics []MetricData) (*Pred
 		zap.String("first_100_chars", string(jsonData[:min(100, len(jsonData))])))
 
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.predictURL, bytes.NewBuffer(jsonData))
+	predictCtx := ctx
+	if c.options.AdaptiveTimeout {
+		var cancel context.CancelFunc
+		predictCtx, cancel = context.WithTimeout(ctx, c.QueryTimeout(c.httpClient.Timeout))
+		defer cancel()
+	}
+
+	start := time.Now()
+	var prediction *PredictionResponse
+	if len(c.predictURLs) > 1 {
+		prediction, err = c.getEnsemblePrediction(predictCtx, jsonData)
+	} else {
+		prediction, err = c.queryPredictor(predictCtx, c.predictURLs[0], jsonData)
+	}
+	c.recordPredictionLatency(time.Since(start), err)
+	if err != nil {
+		return nil, err
+	}
+
+	c.startShadowComparison(RequestIDFrom(ctx), jsonData, prediction.RecommendedNode)
+	return prediction, nil
+}
+
+// queryPredictor posts the prepared payload to a single ML endpoint
+func (c *Client) queryPredictor(ctx context.Context, predictURL string, jsonData []byte) (*PredictionResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, predictURL, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 	req.Header.Set("Content-Type", "application/json")
+	setRequestID(req)
+	c.logMLIO(ctx, "ML request payload", predictURL, jsonData)
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
@@ -533,10 +1038,27 @@ func (c *Client) getPrediction(ctx context.Context, metrics []MetricData) (*Pred
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
+		c.logMLIO(ctx, "ML error response", predictURL, body)
 		return nil, fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, string(body))
 	}
+	if c.options.RequireJSONResponse {
+		if err := checkJSONResponse(resp); err != nil {
+			return nil, err
+		}
+	}
 
 	var prediction PredictionResponse
+	if c.options.LogMLIO {
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read response: %w", err)
+		}
+		c.logMLIO(ctx, "ML response body", predictURL, body)
+		if err := json.Unmarshal(body, &prediction); err != nil {
+			return nil, fmt.Errorf("failed to decode response: %w", err)
+		}
+		return &prediction, nil
+	}
 	if err := json.NewDecoder(resp.Body).Decode(&prediction); err != nil {
 		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
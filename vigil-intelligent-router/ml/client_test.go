@@ -0,0 +1,90 @@
+package ml
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestTrustMLVerbatim(t *testing.T) {
+	// The ML service prefers a, but a has been far slower than b lately
+	now := time.Now().UTC().Format(time.RFC3339)
+	slow, fast := 500.0, 20.0
+	stub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/metrics" {
+			json.NewEncoder(w).Encode([]MetricData{
+				{Timestamp: now, NodeID: "a", LatencyMS: &slow, IsHealthy: 1},
+				{Timestamp: now, NodeID: "b", LatencyMS: &fast, IsHealthy: 1},
+			})
+			return
+		}
+		json.NewEncoder(w).Encode(PredictionResponse{
+			RecommendedNode: "a",
+			AllPredictions: []NodePrediction{
+				{NodeID: "a", PredictedLatencyMS: 10},
+				{NodeID: "b", PredictedLatencyMS: 30},
+			},
+		})
+	}))
+	defer stub.Close()
+
+	tests := []struct {
+		name     string
+		verbatim bool
+		want     string
+	}{
+		{"hybrid scoring", false, "b"},
+		{"verbatim", true, "a"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewClient([]string{stub.URL + "/predict"}, stub.URL+"/metrics", time.Second,
+				map[string]string{"a": "http://a.invalid", "b": "http://b.invalid"},
+				Options{TrustMLVerbatim: tt.verbatim}, zap.NewNop())
+			prediction, err := c.GetRecommendation(context.Background())
+			if err != nil {
+				t.Fatalf("GetRecommendation: %v", err)
+			}
+			if prediction.RecommendedNode != tt.want {
+				t.Errorf("recommended %s, want %s", prediction.RecommendedNode, tt.want)
+			}
+			if got := prediction.Decision.Source == "ml_verbatim"; got != tt.verbatim {
+				t.Errorf("decision source = %q", prediction.Decision.Source)
+			}
+		})
+	}
+}
+
+func TestTrustMLVerbatimIneligibleNode(t *testing.T) {
+	stub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/metrics" {
+			json.NewEncoder(w).Encode([]MetricData{})
+			return
+		}
+		json.NewEncoder(w).Encode(PredictionResponse{
+			RecommendedNode: "a",
+			AllPredictions: []NodePrediction{
+				{NodeID: "a", PredictedLatencyMS: 10},
+				{NodeID: "b", PredictedLatencyMS: 30},
+			},
+		})
+	}))
+	defer stub.Close()
+
+	c := NewClient([]string{stub.URL + "/predict"}, stub.URL+"/metrics", time.Second,
+		map[string]string{"a": "http://a.invalid", "b": "http://b.invalid"},
+		Options{TrustMLVerbatim: true}, zap.NewNop())
+	if err := c.Block("a"); err != nil {
+		t.Fatal(err)
+	}
+	if prediction, err := c.GetRecommendation(context.Background()); err == nil {
+		t.Fatalf("recommended %s, want an error for the blocklisted node", prediction.RecommendedNode)
+	}
+}
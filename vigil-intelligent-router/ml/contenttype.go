@@ -0,0 +1,29 @@
+package ml
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// nonJSONSnippetBytes is how much of a non-JSON response body is quoted in
+// the error
+const nonJSONSnippetBytes = 200
+
+// checkJSONResponse returns an error naming the content type and quoting the
+// start of the body unless resp is declared as JSON. It catches error pages
+// (e.g. from a reverse proxy in front of the ML service) served with a 200,
+// which would otherwise fail later with an opaque decode error.
+func checkJSONResponse(resp *http.Response) error {
+	contentType := resp.Header.Get("Content-Type")
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err == nil && (mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")) {
+		return nil
+	}
+
+	snippet, _ := io.ReadAll(io.LimitReader(resp.Body, nonJSONSnippetBytes))
+	return fmt.Errorf("ML service returned non-JSON content type %q: %s",
+		contentType, strings.Join(strings.Fields(string(snippet)), " "))
+}
@@ -0,0 +1,30 @@
+package ml
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+)
+
+// truncateForLog returns payload as a string, cut to maxBytes when positive
+func truncateForLog(payload []byte, maxBytes int) (string, bool) {
+	if maxBytes > 0 && len(payload) > maxBytes {
+		return string(payload[:maxBytes]), true
+	}
+	return string(payload), false
+}
+
+// logMLIO logs one side of an ML service exchange at debug level when LogMLIO
+// is enabled
+func (c *Client) logMLIO(ctx context.Context, message, predictURL string, payload []byte) {
+	if !c.options.LogMLIO {
+		return
+	}
+	body, truncated := truncateForLog(payload, c.options.LogMLIOMaxBytes)
+	c.logger.Debug(message,
+		zap.String("url", predictURL),
+		zap.String("request_id", RequestIDFrom(ctx)),
+		zap.Int("size", len(payload)),
+		zap.Bool("truncated", truncated),
+		zap.String("body", body))
+}
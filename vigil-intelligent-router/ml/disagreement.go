@@ -0,0 +1,96 @@
+package ml
+
+import (
+	"go.uber.org/zap"
+)
+
+// Disagreement policies for when hybrid scoring picks a different node than the ML
+const (
+	DisagreementPreferHybrid = "prefer_hybrid"
+	DisagreementPreferML     = "prefer_ml"
+	DisagreementLogOnly      = "log_only"
+)
+
+// DisagreementStats summarizes how often hybrid scoring overrode the ML pick
+type DisagreementStats struct {
+	Decisions     int64   `json:"decisions"`
+	Disagreements int64   `json:"disagreements"`
+	Rate          float64 `json:"rate"`
+	Policy        string  `json:"policy"`
+}
+
+// resolveDisagreement counts disagreements between the ML's recommended_node
+// and the hybrid winner and applies the configured policy
+func (c *Client) resolveDisagreement(prediction *PredictionResponse) {
+	mlNode := prediction.Decision.MLRecommendedNode
+	hybridNode := prediction.RecommendedNode
+
+	c.decisions.Add(1)
+	if mlNode == "" || mlNode == hybridNode {
+		return
+	}
+	c.disagreements.Add(1)
+
+	switch c.options.OnDisagreement {
+	case DisagreementPreferML:
+		if c.NodeIneligibleReason(prediction, mlNode) == "" && prediction.SelectNode(mlNode) {
+			c.logger.Debug("Hybrid disagreed with ML, keeping ML recommendation",
+				zap.String("ml_node", mlNode),
+				zap.String("hybrid_node", hybridNode))
+			return
+		}
+	case DisagreementLogOnly:
+		c.logger.Info("Hybrid scoring disagreed with ML recommendation",
+			zap.String("ml_node", mlNode),
+			zap.String("hybrid_node", hybridNode),
+			zap.Float64("hybrid_score", prediction.RecommendationDetails.CostScore))
+		return
+	}
+
+	// A marginally better hybrid score is not worth overriding the model
+	if improvement, ok := c.overrideImprovement(prediction, mlNode); ok && improvement < c.options.OverrideMinImprovementPercent {
+		if prediction.SelectNode(mlNode) {
+			c.logger.Debug("Hybrid winner not better enough, keeping ML recommendation",
+				zap.String("ml_node", mlNode),
+				zap.String("hybrid_node", hybridNode),
+				zap.Float64("improvement_percent", improvement))
+			return
+		}
+	}
+
+	c.logger.Debug("Hybrid disagreed with ML, using hybrid winner",
+		zap.String("ml_node", mlNode),
+		zap.String("hybrid_node", hybridNode))
+}
+
+// overrideImprovement returns how much better, in percent, the hybrid winner
+// scores than the ML's pick. It reports false when no minimum is configured or
+// the ML's pick cannot be selected.
+func (c *Client) overrideImprovement(prediction *PredictionResponse, mlNode string) (float64, bool) {
+	if c.options.OverrideMinImprovementPercent <= 0 || c.NodeIneligibleReason(prediction, mlNode) != "" {
+		return 0, false
+	}
+	for _, node := range prediction.AllPredictions {
+		if node.NodeID != mlNode {
+			continue
+		}
+		if node.CostScore <= 0 {
+			return 0, false
+		}
+		return (node.CostScore - prediction.RecommendationDetails.CostScore) / node.CostScore * 100, true
+	}
+	return 0, false
+}
+
+// GetDisagreementStats returns the ML-vs-hybrid disagreement counters
+func (c *Client) GetDisagreementStats() DisagreementStats {
+	stats := DisagreementStats{
+		Decisions:     c.decisions.Load(),
+		Disagreements: c.disagreements.Load(),
+		Policy:        c.options.OnDisagreement,
+	}
+	if stats.Decisions > 0 {
+		stats.Rate = float64(stats.Disagreements) / float64(stats.Decisions)
+	}
+	return stats
+}
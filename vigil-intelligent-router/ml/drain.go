@@ -0,0 +1,45 @@
+package ml
+
+import (
+	"sort"
+
+	"go.uber.org/zap"
+)
+
+// Drain stops new selections of nodeID; requests already sent to it finish
+func (c *Client) Drain(nodeID string) {
+	c.drainMutex.Lock()
+	c.draining[nodeID] = struct{}{}
+	c.drainMutex.Unlock()
+
+	c.logger.Warn("Node draining", zap.String("node", nodeID))
+}
+
+// Undrain makes a drained nodeID eligible for selection again
+func (c *Client) Undrain(nodeID string) {
+	c.drainMutex.Lock()
+	delete(c.draining, nodeID)
+	c.drainMutex.Unlock()
+
+	c.logger.Info("Node no longer draining", zap.String("node", nodeID))
+}
+
+// Draining returns the currently draining node IDs in sorted order
+func (c *Client) Draining() []string {
+	c.drainMutex.RLock()
+	defer c.drainMutex.RUnlock()
+
+	nodes := make([]string, 0, len(c.draining))
+	for nodeID := range c.draining {
+		nodes = append(nodes, nodeID)
+	}
+	sort.Strings(nodes)
+	return nodes
+}
+
+func (c *Client) isDraining(nodeID string) bool {
+	c.drainMutex.RLock()
+	defer c.drainMutex.RUnlock()
+	_, draining := c.draining[nodeID]
+	return draining
+}
@@ -0,0 +1,52 @@
+package ml
+
+import (
+	"math"
+)
+
+// CalibrationModeEMA keeps an exponential moving average of each node's offset
+// instead of a window of records
+const CalibrationModeEMA = "ema"
+
+// emaCalibration holds the smoothed calibration state for CALIBRATION_MODE=ema
+type emaCalibration struct {
+	offsets map[string]float64 // per-node offset (predicted - actual)
+	samples map[string]int     // records folded into each node's offset
+	global  float64
+	maePre  float64
+	maePost float64
+	records int
+}
+
+func newEMACalibration() emaCalibration {
+	return emaCalibration{offsets: make(map[string]float64), samples: make(map[string]int)}
+}
+
+// emaStep moves current toward sample by alpha, starting at the first sample
+func emaStep(current, sample, alpha float64, first bool) float64 {
+	if first {
+		return sample
+	}
+	return current + alpha*(sample-current)
+}
+
+// recordEMA folds one prediction/actual pair into the moving averages.
+// Caller must hold calibrationMutex.
+func (c *Client) recordEMA(record CalibrationRecord) {
+	alpha := c.options.CalibrationAlpha
+	offset := record.PredictedLatency - record.ActualLatency
+	first := c.ema.records == 0
+
+	prev, seen := c.ema.offsets[record.NodeID]
+	c.ema.offsets[record.NodeID] = emaStep(prev, offset, alpha, !seen)
+	c.ema.samples[record.NodeID]++
+	c.ema.global = emaStep(c.ema.global, offset, alpha, first)
+	c.ema.maePre = emaStep(c.ema.maePre, math.Abs(record.RawPredictedLatency-record.ActualLatency), alpha, first)
+	c.ema.maePost = emaStep(c.ema.maePost, math.Abs(offset), alpha, first)
+	c.ema.records++
+}
+
+// usesEMA reports whether calibration runs in EMA mode
+func (c *Client) usesEMA() bool {
+	return c.options.CalibrationMode == CalibrationModeEMA
+}
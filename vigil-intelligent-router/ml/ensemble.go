@@ -0,0 +1,133 @@
+package ml
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+// Ensemble methods for combining multiple ML endpoints
+const (
+	EnsembleAverage = "average"
+	EnsembleVote    = "vote"
+)
+
+// getEnsemblePrediction queries every ML endpoint concurrently and combines the
+// responses that succeeded. It only fails when no endpoint responded.
+func (c *Client) getEnsemblePrediction(ctx context.Context, jsonData []byte) (*PredictionResponse, error) {
+	results := make([]*PredictionResponse, len(c.predictURLs))
+	errs := make([]error, len(c.predictURLs))
+
+	var wg sync.WaitGroup
+	for i, url := range c.predictURLs {
+		wg.Add(1)
+		go func(i int, url string) {
+			defer wg.Done()
+			results[i], errs[i] = c.queryPredictor(ctx, url, jsonData)
+		}(i, url)
+	}
+	wg.Wait()
+
+	responses := make([]*PredictionResponse, 0, len(results))
+	for i, result := range results {
+		if errs[i] != nil {
+			c.warnings.Warn("ML ensemble member failed",
+				zap.String("url", c.predictURLs[i]),
+				zap.Error(errs[i]))
+			continue
+		}
+		responses = append(responses, result)
+	}
+
+	if len(responses) == 0 {
+		return nil, fmt.Errorf("all %d ML endpoints failed: %w", len(c.predictURLs), errs[0])
+	}
+
+	combined := combinePredictions(responses, c.options.EnsembleMethod)
+	c.logger.Debug("Combined ML ensemble predictions",
+		zap.Int("responded", len(responses)),
+		zap.Int("endpoints", len(c.predictURLs)),
+		zap.String("method", c.options.EnsembleMethod),
+		zap.String("recommended_node", combined.RecommendedNode))
+
+	return combined, nil
+}
+
+// combinePredictions averages per-node predictions across responses. With the
+// vote method the recommended node is the most common recommendation; otherwise
+// it is the node with the lowest averaged cost score.
+func combinePredictions(responses []*PredictionResponse, method string) *PredictionResponse {
+	if len(responses) == 1 {
+		return responses[0]
+	}
+
+	type accumulator struct {
+		sum       NodePrediction
+		count     int
+		anomalies int
+	}
+	order := []string{}
+	nodes := make(map[string]*accumulator)
+	votes := make(map[string]int)
+
+	for _, resp := range responses {
+		votes[resp.RecommendedNode]++
+		for _, p := range resp.AllPredictions {
+			acc, ok := nodes[p.NodeID]
+			if !ok {
+				acc = &accumulator{}
+				nodes[p.NodeID] = acc
+				order = append(order, p.NodeID)
+			}
+			acc.sum.FailureProb += p.FailureProb
+			acc.sum.PredictedLatencyMS += p.PredictedLatencyMS
+			acc.sum.CostScore += p.CostScore
+			if p.AnomalyDetected {
+				acc.anomalies++
+			}
+			acc.count++
+		}
+	}
+
+	combined := &PredictionResponse{
+		Explanation: fmt.Sprintf("Ensemble (%s) of %d ML responses: %s", method, len(responses), responses[0].Explanation),
+		Timestamp:   responses[0].Timestamp,
+	}
+	for _, nodeID := range order {
+		acc := nodes[nodeID]
+		n := float64(acc.count)
+		combined.AllPredictions = append(combined.AllPredictions, NodePrediction{
+			NodeID:             nodeID,
+			FailureProb:        acc.sum.FailureProb / n,
+			PredictedLatencyMS: acc.sum.PredictedLatencyMS / n,
+			CostScore:          acc.sum.CostScore / n,
+			AnomalyDetected:    acc.anomalies*2 > acc.count, // majority flagged
+		})
+	}
+
+	recommended := ""
+	if method == EnsembleVote {
+		// Ties go to the earliest response's pick
+		best := 0
+		for _, resp := range responses {
+			if votes[resp.RecommendedNode] > best {
+				recommended = resp.RecommendedNode
+				best = votes[resp.RecommendedNode]
+			}
+		}
+	} else {
+		for i, p := range combined.AllPredictions {
+			if i == 0 || p.CostScore < combined.RecommendationDetails.CostScore {
+				recommended = p.NodeID
+				combined.RecommendationDetails = p
+			}
+		}
+	}
+
+	if !combined.SelectNode(recommended) {
+		combined.RecommendedNode = recommended
+	}
+	return combined
+}
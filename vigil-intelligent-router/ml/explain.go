@@ -0,0 +1,45 @@
+package ml
+
+// ScoreBreakdown captures the terms that made up a node's hybrid score
+type ScoreBreakdown struct {
+	NodeID             string   `json:"node_id"`
+	PredictedLatencyMS float64  `json:"predicted_latency_ms"`
+	RecentAvgMS        *float64 `json:"recent_avg_ms,omitempty"`
+	PredictionTerm     float64  `json:"prediction_term"`
+	RecentTerm         float64  `json:"recent_term"`
+	MLFailureProb      float64  `json:"ml_failure_prob"`
+	FailureProb        float64  `json:"failure_prob"`
+	FailurePenalty     float64  `json:"failure_penalty"`
+	BlockGap           int      `json:"block_gap,omitempty"`
+	BlockGapPenalty    float64  `json:"block_gap_penalty,omitempty"`
+	UnhealthyPenalty   float64  `json:"unhealthy_penalty,omitempty"`
+	AnomalyMultiplier  float64  `json:"anomaly_multiplier"`
+	ReliabilityWeight  float64  `json:"reliability_weight,omitempty"`
+	CalibrationOffset  float64  `json:"calibration_offset"`
+	HybridScore        float64  `json:"hybrid_score"`
+	Excluded           string   `json:"excluded,omitempty"`
+}
+
+// DecisionBreakdown records how a recommendation was reached
+type DecisionBreakdown struct {
+	// Source is "hybrid", "ml_verbatim", "metrics_fallback" or "priority"
+	Source            string                     `json:"source"`
+	MLRecommendedNode string                     `json:"ml_recommended_node,omitempty"`
+	RecentAverages    map[string]float64         `json:"recent_averages"`
+	Nodes             map[string]*ScoreBreakdown `json:"nodes,omitempty"`
+	// AllAnomalous is set when the ML flagged every scored node anomalous
+	AllAnomalous bool `json:"all_anomalous,omitempty"`
+}
+
+// node returns the breakdown entry for nodeID, creating it if needed
+func (d *DecisionBreakdown) node(nodeID string) *ScoreBreakdown {
+	if d.Nodes == nil {
+		d.Nodes = make(map[string]*ScoreBreakdown)
+	}
+	entry, ok := d.Nodes[nodeID]
+	if !ok {
+		entry = &ScoreBreakdown{NodeID: nodeID, AnomalyMultiplier: 1}
+		d.Nodes[nodeID] = entry
+	}
+	return entry
+}
@@ -0,0 +1,76 @@
+package ml
+
+import (
+	"context"
+)
+
+// latestHealth returns the most recent IsHealthy flag per node.
+// Metrics are ordered oldest to newest, so later entries win.
+func latestHealth(metrics []MetricData) map[string]bool {
+	health := make(map[string]bool)
+	for _, m := range metrics {
+		nodeID := m.NodeName
+		if nodeID == "" {
+			nodeID = m.NodeID
+		}
+		if nodeID == "" {
+			continue
+		}
+		health[nodeID] = m.IsHealthy == 1
+	}
+	return health
+}
+
+// GetNodeHealth fetches current metrics and returns the latest health flag per node
+func (c *Client) GetNodeHealth(ctx context.Context) (map[string]bool, error) {
+	metrics, err := c.fetchMetrics(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return latestHealth(metrics), nil
+}
+
+// countHealthy counts configured nodes whose latest health flag is set
+func (c *Client) countHealthy(health map[string]bool) int {
+	healthy := 0
+	for nodeID, ok := range health {
+		if _, mapped := c.nodeURLMap[nodeID]; mapped && ok {
+			healthy++
+		}
+	}
+	return healthy
+}
+
+// HealthyNodeCount fetches current metrics and counts configured nodes reported healthy
+func (c *Client) HealthyNodeCount(ctx context.Context) (int, error) {
+	health, err := c.GetNodeHealth(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return c.countHealthy(health), nil
+}
+
+// CachedNodeHealth returns the latest health flag per node seen by a
+// recommendation, without fetching metrics. It returns false before any
+// recommendation has had metrics.
+func (c *Client) CachedNodeHealth() (map[string]bool, bool) {
+	health := c.lastHealth.Load()
+	if health == nil {
+		return nil, false
+	}
+	return *health, true
+}
+
+// LastHealthyNodeCount returns the healthy node count seen by the most recent
+// recommendation, or false if no recommendation has had metrics yet
+func (c *Client) LastHealthyNodeCount() (int, bool) {
+	count := c.lastHealthy.Load()
+	return int(count), count >= 0
+}
+
+// MLServiceReachable reports whether the most recent ML prediction succeeded,
+// or false for known before any prediction was attempted
+func (c *Client) MLServiceReachable() (reachable, known bool) {
+	state := c.mlReachable.Load()
+	return state == 1, state >= 0
+}
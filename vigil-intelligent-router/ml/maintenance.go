@@ -0,0 +1,30 @@
+package ml
+
+import (
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// underMaintenance reports whether nodeID is inside a scheduled maintenance
+// window, logging when a node enters or leaves one
+func (c *Client) underMaintenance(nodeID string) bool {
+	if c.options.InMaintenance == nil {
+		return false
+	}
+	active := c.options.InMaintenance(nodeID, time.Now())
+
+	c.maintenanceMutex.Lock()
+	changed := c.inMaintenance[nodeID] != active
+	c.inMaintenance[nodeID] = active
+	c.maintenanceMutex.Unlock()
+
+	if changed && active {
+		c.logger.Info("Node entered maintenance window, excluding from selection",
+			zap.String("node", nodeID))
+	} else if changed {
+		c.logger.Info("Node left maintenance window, eligible again",
+			zap.String("node", nodeID))
+	}
+	return active
+}
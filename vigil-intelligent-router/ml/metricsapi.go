@@ -0,0 +1,78 @@
+package ml
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// metricsDecoder turns a Data Collector metrics response into MetricData
+type metricsDecoder func(body io.Reader) ([]MetricData, error)
+
+// metricsDecoders maps METRICS_API_VERSION to the decoder for that API
+var metricsDecoders = map[string]metricsDecoder{
+	"v1": decodeMetricsV1,
+	"v2": decodeMetricsV2,
+}
+
+// decoderFor returns the decoder for version, defaulting to v1
+func decoderFor(version string) (metricsDecoder, error) {
+	if version == "" {
+		version = "v1"
+	}
+	decoder, ok := metricsDecoders[version]
+	if !ok {
+		return nil, fmt.Errorf("unsupported metrics API version %q", version)
+	}
+	return decoder, nil
+}
+
+// decodeMetricsV1 reads the v1 shape: a bare array of MetricData
+func decodeMetricsV1(body io.Reader) ([]MetricData, error) {
+	var metrics []MetricData
+	if err := json.NewDecoder(body).Decode(&metrics); err != nil {
+		return nil, err
+	}
+	return metrics, nil
+}
+
+// metricV2 is one sample in the v2 collector API
+type metricV2 struct {
+	Node           string   `json:"node"`
+	Timestamp      string   `json:"timestamp"`
+	LatencyMS      *float64 `json:"latency_ms"`
+	CPUPercent     *float64 `json:"cpu_percent"`
+	MemoryPercent  *float64 `json:"memory_percent"`
+	DiskIO         *float64 `json:"disk_io"`
+	BlockHeightGap *int     `json:"block_height_gap"`
+	Healthy        bool     `json:"healthy"`
+}
+
+// decodeMetricsV2 reads the v2 shape: {"metrics": [...]} with a boolean
+// health flag and renamed resource fields
+func decodeMetricsV2(body io.Reader) ([]MetricData, error) {
+	var envelope struct {
+		Metrics []metricV2 `json:"metrics"`
+	}
+	if err := json.NewDecoder(body).Decode(&envelope); err != nil {
+		return nil, err
+	}
+
+	metrics := make([]MetricData, 0, len(envelope.Metrics))
+	for _, m := range envelope.Metrics {
+		metric := MetricData{
+			Timestamp:      m.Timestamp,
+			NodeName:       m.Node,
+			CPUUsage:       m.CPUPercent,
+			MemoryUsage:    m.MemoryPercent,
+			DiskIO:         m.DiskIO,
+			LatencyMS:      m.LatencyMS,
+			BlockHeightGap: m.BlockHeightGap,
+		}
+		if m.Healthy {
+			metric.IsHealthy = 1
+		}
+		metrics = append(metrics, metric)
+	}
+	return metrics, nil
+}
@@ -0,0 +1,35 @@
+package ml
+
+import (
+	"go.uber.org/zap"
+)
+
+// applyPreferredNode softly prefers the configured PreferredNode: it wins
+// whenever its cost score is within PreferredNodeTolerancePercent of the best
+// node's, so a clearly better node still takes over
+func (c *Client) applyPreferredNode(prediction *PredictionResponse) {
+	preferred := c.options.PreferredNode
+	if preferred == "" || prediction.RecommendedNode == preferred {
+		return
+	}
+	if c.NodeIneligibleReason(prediction, preferred) != "" {
+		return
+	}
+
+	best := prediction.RecommendationDetails.CostScore
+	for _, node := range prediction.AllPredictions {
+		if node.NodeID != preferred {
+			continue
+		}
+		limit := best * (1 + c.options.PreferredNodeTolerancePercent/100)
+		if node.CostScore <= limit {
+			c.logger.Debug("Preferring configured node within tolerance",
+				zap.String("node", preferred),
+				zap.String("best_node", prediction.RecommendedNode),
+				zap.Float64("preferred_score", node.CostScore),
+				zap.Float64("best_score", best))
+			prediction.SelectNode(preferred)
+		}
+		return
+	}
+}
@@ -0,0 +1,139 @@
+package ml
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ScoringWeights are the tunable inputs to hybrid scoring
+type ScoringWeights struct {
+	SuccessRateWeight float64 `json:"success_rate_weight"`
+	BlockGapPenalty   float64 `json:"block_gap_penalty"`
+	UnhealthyPenalty  float64 `json:"unhealthy_penalty"`
+	PredictionWeight  float64 `json:"prediction_weight"`
+	RecentWeight      float64 `json:"recent_weight"`
+}
+
+// Validate checks the weights are within the ranges the config accepts
+func (w ScoringWeights) Validate() error {
+	if w.SuccessRateWeight < 0 || w.SuccessRateWeight > 1 {
+		return fmt.Errorf("success_rate_weight must be between 0 and 1")
+	}
+	if w.BlockGapPenalty < 0 {
+		return fmt.Errorf("block_gap_penalty must not be negative")
+	}
+	if w.UnhealthyPenalty < 0 {
+		return fmt.Errorf("unhealthy_penalty must not be negative")
+	}
+	if w.PredictionWeight < 0 || w.RecentWeight < 0 || w.PredictionWeight+w.RecentWeight == 0 {
+		return fmt.Errorf("prediction_weight and recent_weight must not be negative or both zero")
+	}
+	return nil
+}
+
+// ScoringWeights returns the weights live routing uses
+func (c *Client) ScoringWeights() ScoringWeights {
+	return ScoringWeights{
+		SuccessRateWeight: c.options.SuccessRateWeight,
+		BlockGapPenalty:   c.options.BlockGapPenalty,
+		UnhealthyPenalty:  c.options.UnhealthyPenalty,
+		PredictionWeight:  c.options.PredictionWeight,
+		RecentWeight:      c.options.RecentWeight,
+	}
+}
+
+type scoringWeightsKey struct{}
+
+// WithScoringWeights makes recommendations computed with ctx use weights
+// instead of the live ones, e.g. for a tenant's scoring preset
+func WithScoringWeights(ctx context.Context, weights ScoringWeights) context.Context {
+	return context.WithValue(ctx, scoringWeightsKey{}, weights)
+}
+
+// scoringWeightsFor returns the weights attached to ctx, or the live weights
+func (c *Client) scoringWeightsFor(ctx context.Context) ScoringWeights {
+	if weights, ok := ctx.Value(scoringWeightsKey{}).(ScoringWeights); ok {
+		return weights
+	}
+	return c.ScoringWeights()
+}
+
+// scoringInput is an ML prediction as received, before hybrid scoring
+type scoringInput struct {
+	predictions []NodePrediction
+	mlNode      string
+	signals     nodeSignals
+	capturedAt  time.Time
+}
+
+// rememberScoringInput keeps a copy of the unscored prediction for previews
+func (c *Client) rememberScoringInput(prediction *PredictionResponse, signals nodeSignals) {
+	c.lastScoring.Store(&scoringInput{
+		predictions: append([]NodePrediction(nil), prediction.AllPredictions...),
+		mlNode:      prediction.RecommendedNode,
+		signals:     signals,
+		capturedAt:  time.Now(),
+	})
+}
+
+// ScoringOutcome is the result of scoring one prediction with a weight set
+type ScoringOutcome struct {
+	Weights         ScoringWeights     `json:"weights"`
+	RecommendedNode string             `json:"recommended_node"`
+	Scores          map[string]float64 `json:"scores"`
+	Excluded        map[string]string  `json:"excluded,omitempty"`
+}
+
+// ScorePreview compares the live and candidate weights on the latest ML prediction
+type ScorePreview struct {
+	PredictionAt time.Time      `json:"prediction_at"`
+	Current      ScoringOutcome `json:"current"`
+	Candidate    ScoringOutcome `json:"candidate"`
+	Changed      bool           `json:"changed"`
+}
+
+// ErrNoScoringInput is returned by PreviewScoring before any ML prediction
+var ErrNoScoringInput = errors.New("no ML prediction has been scored yet")
+
+// PreviewScoring re-scores the latest ML prediction with the live and the
+// candidate weights. Live routing and configuration are left untouched.
+func (c *Client) PreviewScoring(candidate ScoringWeights) (*ScorePreview, error) {
+	input := c.lastScoring.Load()
+	if input == nil {
+		return nil, ErrNoScoringInput
+	}
+
+	current := c.scoreWith(input, c.ScoringWeights())
+	proposed := c.scoreWith(input, candidate)
+	return &ScorePreview{
+		PredictionAt: input.capturedAt,
+		Current:      current,
+		Candidate:    proposed,
+		Changed:      current.RecommendedNode != proposed.RecommendedNode,
+	}, nil
+}
+
+// scoreWith runs hybrid scoring over a copy of input
+func (c *Client) scoreWith(input *scoringInput, weights ScoringWeights) ScoringOutcome {
+	prediction := &PredictionResponse{
+		RecommendedNode: input.mlNode,
+		AllPredictions:  append([]NodePrediction(nil), input.predictions...),
+	}
+	c.applyHybridScoring(prediction, input.signals, weights)
+
+	outcome := ScoringOutcome{
+		Weights:         weights,
+		RecommendedNode: prediction.RecommendedNode,
+		Scores:          make(map[string]float64, len(prediction.AllPredictions)),
+		Excluded:        make(map[string]string),
+	}
+	for _, node := range prediction.AllPredictions {
+		outcome.Scores[node.NodeID] = node.CostScore
+		if reason := prediction.Decision.node(node.NodeID).Excluded; reason != "" {
+			outcome.Excluded[node.NodeID] = reason
+		}
+	}
+	return outcome
+}
@@ -0,0 +1,61 @@
+package ml
+
+import (
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestPreviewScoringHybridWeights(t *testing.T) {
+	c := NewClient(nil, "", 0, nil, Options{}, zap.NewNop())
+	if w := c.ScoringWeights(); w.PredictionWeight != 0.7 || w.RecentWeight != 0.3 {
+		t.Fatalf("default weights = %v/%v, want 0.7/0.3", w.PredictionWeight, w.RecentWeight)
+	}
+
+	// a predicts fast but has been slow lately; b the other way round
+	c.rememberScoringInput(&PredictionResponse{
+		RecommendedNode: "a",
+		AllPredictions: []NodePrediction{
+			{NodeID: "a", PredictedLatencyMS: 10},
+			{NodeID: "b", PredictedLatencyMS: 30},
+		},
+	}, nodeSignals{recentAvgs: map[string]float64{"a": 100, "b": 20}})
+
+	candidate := c.ScoringWeights()
+	candidate.PredictionWeight, candidate.RecentWeight = 1, 0
+	preview, err := c.PreviewScoring(candidate)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := preview.Current.RecommendedNode; got != "b" {
+		t.Errorf("current recommended %q, want b", got)
+	}
+	if got := preview.Current.Scores["a"]; got != 0.7*10+0.3*100 {
+		t.Errorf("current score of a = %v, want %v", got, 0.7*10+0.3*100)
+	}
+	if got := preview.Candidate.RecommendedNode; got != "a" {
+		t.Errorf("candidate recommended %q, want a", got)
+	}
+	if got := preview.Candidate.Scores["b"]; got != 30 {
+		t.Errorf("candidate score of b = %v, want 30", got)
+	}
+	if !preview.Changed {
+		t.Error("preview not marked changed")
+	}
+}
+
+func TestScoringWeightsValidate(t *testing.T) {
+	valid := ScoringWeights{SuccessRateWeight: 0.3, PredictionWeight: 0.7, RecentWeight: 0.3}
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("valid weights rejected: %v", err)
+	}
+	for name, w := range map[string]ScoringWeights{
+		"both zero":       {SuccessRateWeight: 0.3},
+		"negative recent": {PredictionWeight: 1, RecentWeight: -0.1},
+	} {
+		if err := w.Validate(); err == nil {
+			t.Errorf("%s: accepted", name)
+		}
+	}
+}
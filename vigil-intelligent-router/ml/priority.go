@@ -0,0 +1,70 @@
+package ml
+
+import (
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Routing strategies
+const (
+	StrategyHybrid   = "hybrid"
+	StrategyPriority = "priority"
+)
+
+// priorityRecommendation picks the first node in NodePriority that is healthy
+// and not excluded (blocklisted, draining, in maintenance or SLA-demoted),
+// without consulting the ML service. Nodes are listed in AllPredictions in
+// priority order, with CostScore set to their rank so spill-over follows it.
+//
+// A node with no health data counts as unavailable, unless the collector
+// returned no metrics at all; then health is ignored rather than refusing
+// every request.
+func (c *Client) priorityRecommendation(metrics []MetricData, recentAvgs map[string]float64) (*PredictionResponse, error) {
+	health := latestHealth(metrics)
+	ignoreHealth := len(health) == 0
+	if ignoreHealth {
+		c.logger.Warn("No node health available, priority routing ignores health")
+	}
+
+	prediction := &PredictionResponse{
+		Decision: &DecisionBreakdown{
+			Source:         StrategyPriority,
+			RecentAverages: recentAvgs,
+		},
+		Timestamp: time.Now().Format(time.RFC3339),
+	}
+	for rank, nodeID := range c.options.NodePriority {
+		node := NodePrediction{NodeID: nodeID, CostScore: float64(rank)}
+		if avg, ok := recentAvgs[nodeID]; ok {
+			node.PredictedLatencyMS = avg
+		}
+		prediction.AllPredictions = append(prediction.AllPredictions, node)
+
+		breakdown := prediction.Decision.node(nodeID)
+		breakdown.HybridScore = node.CostScore
+		healthy, known := health[nodeID]
+		switch {
+		case c.exclusionReason(nodeID) != "":
+			breakdown.Excluded = c.exclusionReason(nodeID)
+		case ignoreHealth:
+		case !known:
+			breakdown.Excluded = "no_metrics"
+		case !healthy:
+			breakdown.Excluded = "unhealthy"
+		}
+		if breakdown.Excluded == "" && prediction.RecommendedNode == "" {
+			prediction.SelectNode(nodeID)
+		}
+	}
+
+	if prediction.RecommendedNode == "" {
+		return nil, &NoEligibleNodesError{Reasons: c.RejectionReasons(prediction)}
+	}
+	prediction.Explanation = fmt.Sprintf("Priority routing: %s is the first available node in NODE_PRIORITY", prediction.RecommendedNode)
+
+	c.logger.Info("Priority recommendation selected",
+		zap.String("recommended_node", prediction.RecommendedNode))
+	return prediction, nil
+}
@@ -0,0 +1,53 @@
+package ml
+
+import (
+	"sort"
+	"time"
+)
+
+// RankedPredictions returns the node predictions ordered best (lowest cost score) first
+func (p *PredictionResponse) RankedPredictions() []NodePrediction {
+	ranked := make([]NodePrediction, len(p.AllPredictions))
+	copy(ranked, p.AllPredictions)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return ranked[i].CostScore < ranked[j].CostScore
+	})
+	return ranked
+}
+
+// SelectNode makes nodeID the recommended node, updating RecommendationDetails
+// from its entry in AllPredictions. It returns false if the node has no prediction.
+func (p *PredictionResponse) SelectNode(nodeID string) bool {
+	for _, node := range p.AllPredictions {
+		if node.NodeID == nodeID {
+			p.RecommendedNode = nodeID
+			p.RecommendationDetails = node
+			return true
+		}
+	}
+	return false
+}
+
+// Age returns how long ago the recommendation was computed
+func (p *PredictionResponse) Age() time.Duration {
+	if p.ComputedAt.IsZero() {
+		return 0
+	}
+	return time.Since(p.ComputedAt)
+}
+
+// MarkDecided records the recommendation's age as the router settles on a node,
+// before the request is forwarded
+func (p *PredictionResponse) MarkDecided() {
+	p.decisionAge = p.Age()
+	p.decided = true
+}
+
+// DecisionAge returns the age recorded by MarkDecided, or the current age when
+// no decision has been marked
+func (p *PredictionResponse) DecisionAge() time.Duration {
+	if p.decided {
+		return p.decisionAge
+	}
+	return p.Age()
+}
@@ -0,0 +1,23 @@
+package ml
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDecisionAge(t *testing.T) {
+	p := &PredictionResponse{ComputedAt: time.Now().Add(-time.Second)}
+	if age := p.DecisionAge(); age < time.Second {
+		t.Fatalf("unmarked DecisionAge = %v, want the current age", age)
+	}
+
+	p.MarkDecided()
+	decided := p.DecisionAge()
+	time.Sleep(20 * time.Millisecond)
+	if p.DecisionAge() != decided {
+		t.Errorf("DecisionAge moved from %v to %v after MarkDecided", decided, p.DecisionAge())
+	}
+	if p.Age() <= decided {
+		t.Errorf("Age = %v, want it to keep growing past %v", p.Age(), decided)
+	}
+}
@@ -0,0 +1,118 @@
+package ml
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// RateLimitedLogger keeps high-frequency warnings from flooding the logs. The
+// first occurrence of a message is logged immediately; repeats within the
+// interval are counted and reported in one summary when it ends. Messages
+// about different targets (a node, url or target field) are limited
+// separately, so a failing node can't hide another.
+type RateLimitedLogger struct {
+	logger   *zap.Logger
+	interval time.Duration
+
+	mutex      sync.Mutex
+	suppressed map[rateLimitKey]*suppressedLog
+}
+
+type rateLimitKey struct {
+	level   zapcore.Level
+	message string
+	target  string
+}
+
+// targetFields name the fields that tell apart what a message is about
+var targetFields = map[string]bool{"node": true, "node_id": true, "url": true, "target": true}
+
+// messageTarget returns the value of the first target field, if any
+func messageTarget(fields []zap.Field) string {
+	for _, field := range fields {
+		if field.Type == zapcore.StringType && targetFields[field.Key] {
+			return field.String
+		}
+	}
+	return ""
+}
+
+// suppressedLog counts repeats of one message, keeping the latest fields and
+// call site
+type suppressedLog struct {
+	count  int64
+	fields []zap.Field
+	caller zapcore.EntryCaller
+}
+
+// NewRateLimitedLogger wraps logger; an interval of zero or less logs every call
+func NewRateLimitedLogger(logger *zap.Logger, interval time.Duration) *RateLimitedLogger {
+	return &RateLimitedLogger{
+		logger:     logger.WithOptions(zap.AddCallerSkip(2)),
+		interval:   interval,
+		suppressed: make(map[rateLimitKey]*suppressedLog),
+	}
+}
+
+// Warn logs at warn level, subject to rate limiting
+func (l *RateLimitedLogger) Warn(message string, fields ...zap.Field) {
+	l.log(zapcore.WarnLevel, message, fields)
+}
+
+// Error logs at error level, subject to rate limiting
+func (l *RateLimitedLogger) Error(message string, fields ...zap.Field) {
+	l.log(zapcore.ErrorLevel, message, fields)
+}
+
+func (l *RateLimitedLogger) log(level zapcore.Level, message string, fields []zap.Field) {
+	if l.interval <= 0 {
+		l.logger.Log(level, message, fields...)
+		return
+	}
+
+	key := rateLimitKey{level: level, message: message, target: messageTarget(fields)}
+	l.mutex.Lock()
+	if entry, ok := l.suppressed[key]; ok {
+		entry.count++
+		entry.fields = fields
+		entry.caller = zapcore.NewEntryCaller(runtime.Caller(2))
+		l.mutex.Unlock()
+		return
+	}
+	l.suppressed[key] = &suppressedLog{}
+	l.mutex.Unlock()
+
+	l.logger.Log(level, message, fields...)
+	time.AfterFunc(l.interval, func() { l.summarize(key) })
+}
+
+// summarize ends the interval for key, logging how many repeats were
+// suppressed. It runs on a timer goroutine, so the summary is attributed to
+// the call site of the last repeat rather than to its own. The next
+// occurrence is logged immediately again.
+func (l *RateLimitedLogger) summarize(key rateLimitKey) {
+	l.mutex.Lock()
+	entry := l.suppressed[key]
+	delete(l.suppressed, key)
+	l.mutex.Unlock()
+
+	if entry == nil || entry.count == 0 {
+		return
+	}
+	fields := append([]zap.Field{
+		zap.Int64("suppressed", entry.count),
+		zap.Duration("interval", l.interval),
+	}, entry.fields...)
+	message := fmt.Sprintf("%s (%d more times in last %s)", key.message, entry.count, l.interval)
+	if ce := l.logger.Check(key.level, message); ce != nil {
+		if ce.Caller.Defined {
+			ce.Caller = entry.caller
+		}
+		ce.Write(fields...)
+	}
+}
@@ -0,0 +1,64 @@
+package ml
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestRateLimitedLoggerPerTarget(t *testing.T) {
+	core, logs := observer.New(zapcore.WarnLevel)
+	l := NewRateLimitedLogger(zap.New(core), time.Hour)
+
+	l.Warn("Node failed", zap.String("node", "a"))
+	l.Warn("Node failed", zap.String("node", "a"))
+	l.Warn("Node failed", zap.String("node", "b"))
+	l.Warn("Node failed", zap.String("url", "http://c"))
+	l.Warn("Budget exhausted")
+	l.Warn("Budget exhausted")
+
+	var targets []string
+	for _, entry := range logs.All() {
+		targets = append(targets, entry.Message+"/"+messageTarget(entry.Context))
+	}
+	want := []string{"Node failed/a", "Node failed/b", "Node failed/http://c", "Budget exhausted/"}
+	if strings.Join(targets, ",") != strings.Join(want, ",") {
+		t.Errorf("logged %v, want %v", targets, want)
+	}
+}
+
+func TestRateLimitedLoggerSummary(t *testing.T) {
+	core, logs := observer.New(zapcore.WarnLevel)
+	l := NewRateLimitedLogger(zap.New(core, zap.AddCaller()), 20*time.Millisecond)
+
+	for i := 0; i < 3; i++ {
+		l.Warn("Node failed", zap.String("node", "a"), zap.Int("attempt", i))
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for logs.Len() < 2 {
+		if time.Now().After(deadline) {
+			t.Fatal("no summary logged")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	first, summary := logs.All()[0], logs.All()[1]
+	if !strings.HasPrefix(summary.Message, "Node failed (2 more times in last 20ms)") {
+		t.Errorf("summary message = %q", summary.Message)
+	}
+	fields := summary.ContextMap()
+	if fields["suppressed"] != int64(2) || fields["attempt"] != int64(2) || fields["node"] != "a" {
+		t.Errorf("summary fields = %v", fields)
+	}
+	for _, entry := range []observer.LoggedEntry{first, summary} {
+		if file := filepath.Base(entry.Caller.File); file != "ratelog_test.go" {
+			t.Errorf("%q attributed to %s, want ratelog_test.go", entry.Message, entry.Caller.String())
+		}
+	}
+}
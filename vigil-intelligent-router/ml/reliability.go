@@ -0,0 +1,51 @@
+package ml
+
+import (
+	"go.uber.org/zap"
+)
+
+// minReliabilityWeight keeps a failing node's score finite so it can still be
+// compared with others and recover
+const minReliabilityWeight = 0.1
+
+// updateReliabilityWeight applies one outcome to nodeID's AIMD weight: a
+// failure multiplies it by ReliabilityDecreaseFactor, a success adds
+// ReliabilityRecoveryStep, up to 1. Caller must hold outcomeMutex.
+func (c *Client) updateReliabilityWeight(nodeID string, success bool) {
+	if !c.options.ReliabilityWeighting {
+		return
+	}
+
+	weight, ok := c.reliabilityWeights[nodeID]
+	if !ok {
+		weight = 1
+	}
+	if success {
+		weight += c.options.ReliabilityRecoveryStep
+		if weight > 1 {
+			weight = 1
+		}
+	} else {
+		weight *= c.options.ReliabilityDecreaseFactor
+		if weight < minReliabilityWeight {
+			weight = minReliabilityWeight
+		}
+		c.logger.Debug("Reliability weight decreased",
+			zap.String("node", nodeID),
+			zap.Float64("weight", weight))
+	}
+	c.reliabilityWeights[nodeID] = weight
+}
+
+// GetReliabilityWeights returns the current AIMD reliability weight per node.
+// Nodes without any recorded failure are at 1 and may be missing.
+func (c *Client) GetReliabilityWeights() map[string]float64 {
+	c.outcomeMutex.RLock()
+	defer c.outcomeMutex.RUnlock()
+
+	weights := make(map[string]float64, len(c.reliabilityWeights))
+	for nodeID, weight := range c.reliabilityWeights {
+		weights[nodeID] = weight
+	}
+	return weights
+}
@@ -0,0 +1,30 @@
+package ml
+
+import (
+	"context"
+	"net/http"
+)
+
+type requestIDKey struct{}
+
+// WithRequestID attaches a correlation ID that is sent as X-Request-ID on the
+// metrics and prediction calls made with ctx
+func WithRequestID(ctx context.Context, id string) context.Context {
+	if id == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestIDFrom returns the correlation ID attached to ctx, if any
+func RequestIDFrom(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// setRequestID copies the context's correlation ID onto an outgoing request
+func setRequestID(req *http.Request) {
+	if id := RequestIDFrom(req.Context()); id != "" {
+		req.Header.Set("X-Request-ID", id)
+	}
+}
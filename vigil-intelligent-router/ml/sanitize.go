@@ -0,0 +1,127 @@
+package ml
+
+import (
+	"math"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// maxPlausibleLatencyMS is the largest latency treated as a real measurement
+	maxPlausibleLatencyMS = 600000.0
+	// maxPlausibleBlockGap is the largest block-height gap treated as real
+	maxPlausibleBlockGap = 1000000
+)
+
+// sanitizeMetrics drops metrics with impossible values and clamps the ones
+// that are merely out of range, so they can't skew averages or the ML payload
+func (c *Client) sanitizeMetrics(metrics []MetricData) []MetricData {
+	if len(metrics) == 0 {
+		return metrics
+	}
+
+	clean := make([]MetricData, 0, len(metrics))
+	dropped, clamped := 0, 0
+
+	for _, m := range metrics {
+		if m.LatencyMS != nil {
+			lat := *m.LatencyMS
+			if math.IsNaN(lat) || lat < 0 || lat > maxPlausibleLatencyMS {
+				dropped++
+				continue
+			}
+		}
+		if m.BlockHeightGap != nil && *m.BlockHeightGap > maxPlausibleBlockGap {
+			dropped++
+			continue
+		}
+
+		wasClamped := false
+		m.CPUUsage, wasClamped = clampPercent(m.CPUUsage, wasClamped)
+		m.MemoryUsage, wasClamped = clampPercent(m.MemoryUsage, wasClamped)
+		if m.DiskIO != nil && (*m.DiskIO < 0 || math.IsNaN(*m.DiskIO)) {
+			zero := 0.0
+			m.DiskIO = &zero
+			wasClamped = true
+		}
+		if m.BlockHeightGap != nil && *m.BlockHeightGap < 0 {
+			zero := 0
+			m.BlockHeightGap = &zero
+			wasClamped = true
+		}
+		if wasClamped {
+			clamped++
+		}
+
+		clean = append(clean, m)
+	}
+
+	if dropped > 0 || clamped > 0 {
+		c.logger.Warn("Sanitized out-of-range metrics",
+			zap.Int("received", len(metrics)),
+			zap.Int("dropped", dropped),
+			zap.Int("clamped", clamped))
+	}
+
+	return clean
+}
+
+// clampPercent bounds a percentage to 0-100, returning a new pointer if changed
+func clampPercent(value *float64, clamped bool) (*float64, bool) {
+	if value == nil {
+		return nil, clamped
+	}
+	v := *value
+	switch {
+	case math.IsNaN(v) || v < 0:
+		v = 0
+	case v > 100:
+		v = 100
+	default:
+		return value, clamped
+	}
+	return &v, true
+}
+
+// dedupMetrics drops repeated scrapes: metrics for the same node with the same
+// timestamp. The last occurrence wins and the original order is kept.
+func (c *Client) dedupMetrics(metrics []MetricData) []MetricData {
+	type key struct{ node, timestamp string }
+	seen := make(map[key]struct{}, len(metrics))
+	keep := make([]bool, len(metrics))
+	duplicates := 0
+
+	for i := len(metrics) - 1; i >= 0; i-- {
+		m := metrics[i]
+		nodeID := m.NodeName
+		if nodeID == "" {
+			nodeID = m.NodeID
+		}
+		if m.Timestamp == "" || nodeID == "" {
+			keep[i] = true
+			continue
+		}
+		k := key{nodeID, m.Timestamp}
+		if _, dup := seen[k]; dup {
+			duplicates++
+			continue
+		}
+		seen[k] = struct{}{}
+		keep[i] = true
+	}
+
+	if duplicates == 0 {
+		return metrics
+	}
+
+	unique := make([]MetricData, 0, len(metrics)-duplicates)
+	for i, m := range metrics {
+		if keep[i] {
+			unique = append(unique, m)
+		}
+	}
+	c.logger.Info("Dropped duplicate metrics",
+		zap.Int("received", len(metrics)),
+		zap.Int("duplicates", duplicates))
+	return unique
+}
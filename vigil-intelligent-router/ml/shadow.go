@@ -0,0 +1,83 @@
+package ml
+
+import (
+	"context"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+)
+
+// maxShadowInflight bounds concurrent shadow queries; comparisons beyond it
+// are skipped rather than queued
+const maxShadowInflight = 16
+
+// shadowCounters tracks how a shadow model's picks compare with production
+type shadowCounters struct {
+	inflight      atomic.Int64
+	agreements    atomic.Int64
+	disagreements atomic.Int64
+	errors        atomic.Int64
+	skipped       atomic.Int64
+}
+
+// ShadowStats summarizes shadow model agreement with the production model
+type ShadowStats struct {
+	Comparisons   int64   `json:"comparisons"`
+	Agreements    int64   `json:"agreements"`
+	Disagreements int64   `json:"disagreements"`
+	AgreementRate float64 `json:"agreement_rate"`
+	Errors        int64   `json:"errors"`
+	Skipped       int64   `json:"skipped"`
+}
+
+// startShadowComparison sends the same prediction request to the shadow ML
+// service in the background and compares its pick with the production one.
+// The shadow result never affects routing.
+func (c *Client) startShadowComparison(requestID string, jsonData []byte, productionNode string) {
+	if c.options.ShadowPredictURL == "" {
+		return
+	}
+	if c.shadow.inflight.Add(1) > maxShadowInflight {
+		c.shadow.inflight.Add(-1)
+		c.shadow.skipped.Add(1)
+		return
+	}
+
+	go func() {
+		defer c.shadow.inflight.Add(-1)
+
+		ctx, cancel := context.WithTimeout(WithRequestID(context.Background(), requestID), c.httpClient.Timeout)
+		defer cancel()
+
+		shadow, err := c.queryPredictor(ctx, c.options.ShadowPredictURL, jsonData)
+		if err != nil {
+			c.shadow.errors.Add(1)
+			c.logger.Debug("Shadow ML query failed", zap.Error(err))
+			return
+		}
+
+		if shadow.RecommendedNode == productionNode {
+			c.shadow.agreements.Add(1)
+			return
+		}
+		c.shadow.disagreements.Add(1)
+		c.logger.Info("Shadow ML model disagreed with production",
+			zap.String("production_node", productionNode),
+			zap.String("shadow_node", shadow.RecommendedNode))
+	}()
+}
+
+// GetShadowStats returns the shadow-vs-production agreement counters
+func (c *Client) GetShadowStats() ShadowStats {
+	stats := ShadowStats{
+		Agreements:    c.shadow.agreements.Load(),
+		Disagreements: c.shadow.disagreements.Load(),
+		Errors:        c.shadow.errors.Load(),
+		Skipped:       c.shadow.skipped.Load(),
+	}
+	stats.Comparisons = stats.Agreements + stats.Disagreements
+	if stats.Comparisons > 0 {
+		stats.AgreementRate = float64(stats.Agreements) / float64(stats.Comparisons)
+	}
+	return stats
+}
@@ -0,0 +1,109 @@
+package ml
+
+import (
+	"sort"
+	"strings"
+)
+
+// nodeSignals holds the per-node observations from recent metrics that feed
+// hybrid scoring alongside the ML predictions
+type nodeSignals struct {
+	recentAvgs map[string]float64
+	blockGaps  map[string]int  // latest block-height gap per node
+	health     map[string]bool // latest IsHealthy flag per node
+}
+
+// latestBlockGaps returns the most recent BlockHeightGap per node.
+// Metrics are ordered oldest to newest, so later entries win.
+func latestBlockGaps(metrics []MetricData) map[string]int {
+	gaps := make(map[string]int)
+	for _, m := range metrics {
+		nodeID := m.NodeName
+		if nodeID == "" {
+			nodeID = m.NodeID
+		}
+		if nodeID == "" || m.BlockHeightGap == nil {
+			continue
+		}
+		gaps[nodeID] = *m.BlockHeightGap
+	}
+	return gaps
+}
+
+// ineligibleReason returns why the prediction's recommended node can't be used,
+// or "" if it can. Hybrid scoring leaves the ML pick in place when no node is
+// eligible, so this is checked after scoring.
+func (c *Client) ineligibleReason(prediction *PredictionResponse) string {
+	return c.NodeIneligibleReason(prediction, prediction.RecommendedNode)
+}
+
+// NodeIneligibleReason returns why nodeID can't be selected for this prediction,
+// or "" if it can. It combines node-level exclusions (e.g. the blocklist) with
+// the per-request ones recorded during scoring.
+func (c *Client) NodeIneligibleReason(prediction *PredictionResponse, nodeID string) string {
+	if reason := c.exclusionReason(nodeID); reason != "" {
+		return reason
+	}
+	if prediction.Decision != nil {
+		if breakdown, ok := prediction.Decision.Nodes[nodeID]; ok && breakdown.Excluded != "" {
+			return breakdown.Excluded
+		}
+	}
+	return ""
+}
+
+// NoEligibleNodesError is returned when selection rejected every node.
+// Reasons maps each rejected node to why it was rejected.
+type NoEligibleNodesError struct {
+	Reasons map[string]string
+}
+
+func (e *NoEligibleNodesError) Error() string {
+	nodes := make([]string, 0, len(e.Reasons))
+	for nodeID, reason := range e.Reasons {
+		nodes = append(nodes, nodeID+"="+reason)
+	}
+	sort.Strings(nodes)
+	return "no eligible nodes: " + strings.Join(nodes, ", ")
+}
+
+// RejectionReasons returns why each scored node can't be used for this
+// prediction. Nodes that are eligible and mapped to a URL are omitted.
+func (c *Client) RejectionReasons(prediction *PredictionResponse) map[string]string {
+	reasons := make(map[string]string)
+	for _, node := range prediction.AllPredictions {
+		if reason := c.NodeIneligibleReason(prediction, node.NodeID); reason != "" {
+			reasons[node.NodeID] = reason
+		} else if _, mapped := c.nodeURLMap[node.NodeID]; !mapped {
+			reasons[node.NodeID] = "unmapped"
+		}
+	}
+	return reasons
+}
+
+// ExcludeNodes applies per-request exclusions decided outside scoring (e.g.
+// method requirements). If the recommended node is excluded, the best
+// remaining eligible node is selected; a NoEligibleNodesError is returned when
+// none is left.
+func (c *Client) ExcludeNodes(prediction *PredictionResponse, reasons map[string]string) error {
+	if len(reasons) == 0 {
+		return nil
+	}
+	if prediction.Decision == nil {
+		prediction.Decision = &DecisionBreakdown{}
+	}
+	for nodeID, reason := range reasons {
+		prediction.Decision.node(nodeID).Excluded = reason
+	}
+
+	if c.ineligibleReason(prediction) == "" {
+		return nil
+	}
+	for _, node := range prediction.RankedPredictions() {
+		if c.NodeIneligibleReason(prediction, node.NodeID) == "" {
+			prediction.SelectNode(node.NodeID)
+			return nil
+		}
+	}
+	return &NoEligibleNodesError{Reasons: c.RejectionReasons(prediction)}
+}
@@ -0,0 +1,117 @@
+package ml
+
+import (
+	"math"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// RecordLatency checks an observed request latency against the SLA. A node
+// that breaches it more than SLAViolationThreshold times within SLAWindow is
+// demoted (excluded from selection) for SLACooldown.
+func (c *Client) RecordLatency(nodeID string, latencyMS float64) {
+	if c.options.SLALatencyMS <= 0 || latencyMS <= c.options.SLALatencyMS {
+		return
+	}
+
+	now := time.Now()
+	c.slaMutex.Lock()
+	defer c.slaMutex.Unlock()
+
+	if now.Before(c.slaDemotedUntil[nodeID]) {
+		return
+	}
+
+	// Keep only violations inside the window
+	violations := c.slaViolations[nodeID]
+	cutoff := now.Add(-c.options.SLAWindow)
+	kept := violations[:0]
+	for _, at := range violations {
+		if at.After(cutoff) {
+			kept = append(kept, at)
+		}
+	}
+	kept = append(kept, now)
+	c.slaViolations[nodeID] = kept
+
+	if len(kept) > c.options.SLAViolationThreshold {
+		c.slaDemotedUntil[nodeID] = now.Add(c.options.SLACooldown)
+		delete(c.slaViolations, nodeID)
+		c.logger.Warn("Node demoted for repeated SLA violations",
+			zap.String("node", nodeID),
+			zap.Int("violations", len(kept)),
+			zap.Float64("sla_latency_ms", c.options.SLALatencyMS),
+			zap.Duration("cooldown", c.options.SLACooldown))
+	}
+}
+
+// slaDemoted reports whether nodeID is serving an SLA demotion. Once the
+// cooldown has passed the node is given another chance.
+func (c *Client) slaDemoted(nodeID string) bool {
+	if c.options.SLALatencyMS <= 0 {
+		return false
+	}
+
+	c.slaMutex.Lock()
+	defer c.slaMutex.Unlock()
+
+	until, demoted := c.slaDemotedUntil[nodeID]
+	if !demoted {
+		return false
+	}
+	if time.Now().Before(until) {
+		return true
+	}
+	delete(c.slaDemotedUntil, nodeID)
+	c.logger.Info("SLA demotion expired, node eligible again", zap.String("node", nodeID))
+	return false
+}
+
+// GetSLAStatus returns per-node SLA violation counts and demotion state
+func (c *Client) GetSLAStatus() map[string]interface{} {
+	c.slaMutex.Lock()
+	defer c.slaMutex.Unlock()
+
+	now := time.Now()
+	cutoff := now.Add(-c.options.SLAWindow)
+	nodes := make(map[string]interface{})
+	for nodeID, violations := range c.slaViolations {
+		recent := 0
+		for _, at := range violations {
+			if at.After(cutoff) {
+				recent++
+			}
+		}
+		nodes[nodeID] = map[string]interface{}{"recent_violations": recent, "demoted": false}
+	}
+	for nodeID, until := range c.slaDemotedUntil {
+		if now.Before(until) {
+			nodes[nodeID] = map[string]interface{}{
+				"demoted":          true,
+				"demoted_until":    until.UTC().Format(time.RFC3339),
+				"reset_in_seconds": math.Ceil(until.Sub(now).Seconds()),
+			}
+		}
+	}
+
+	return map[string]interface{}{
+		"sla_latency_ms": c.options.SLALatencyMS,
+		"nodes":          nodes,
+	}
+}
+
+// SLADemotions returns the time left on each active SLA demotion
+func (c *Client) SLADemotions() map[string]time.Duration {
+	c.slaMutex.Lock()
+	defer c.slaMutex.Unlock()
+
+	now := time.Now()
+	demotions := make(map[string]time.Duration)
+	for nodeID, until := range c.slaDemotedUntil {
+		if now.Before(until) {
+			demotions[nodeID] = until.Sub(now)
+		}
+	}
+	return demotions
+}
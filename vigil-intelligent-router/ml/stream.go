@@ -0,0 +1,175 @@
+package ml
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// metricsStreamMaxEvent bounds a single SSE event payload
+const metricsStreamMaxEvent = 1 << 20
+
+// metricsSnapshot holds the most recent metrics pushed by the collector stream.
+// It is only used while the stream is connected.
+type metricsSnapshot struct {
+	mutex   sync.RWMutex
+	live    bool
+	limit   int
+	metrics []MetricData
+}
+
+// current returns a copy of the snapshot, or false when the stream is down
+func (s *metricsSnapshot) current() ([]MetricData, bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	if !s.live {
+		return nil, false
+	}
+	metrics := make([]MetricData, len(s.metrics))
+	copy(metrics, s.metrics)
+	return metrics, true
+}
+
+// reset replaces the snapshot and marks it live
+func (s *metricsSnapshot) reset(metrics []MetricData, limit int) {
+	s.mutex.Lock()
+	s.live = true
+	s.limit = limit
+	s.metrics = nil
+	s.mutex.Unlock()
+	s.add(metrics)
+}
+
+// add appends pushed metrics, keeping the newest limit entries
+func (s *metricsSnapshot) add(metrics []MetricData) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.metrics = append(s.metrics, metrics...)
+	if s.limit > 0 && len(s.metrics) > s.limit {
+		s.metrics = append([]MetricData(nil), s.metrics[len(s.metrics)-s.limit:]...)
+	}
+}
+
+// drop marks the snapshot unusable so requests poll again
+func (s *metricsSnapshot) drop() {
+	s.mutex.Lock()
+	s.live = false
+	s.metrics = nil
+	s.mutex.Unlock()
+}
+
+// StreamMetrics subscribes to the Data Collector's server-sent events stream
+// and keeps the newest historyLimit metrics in memory, so recommendations
+// don't fetch metrics per request. While the stream is down requests poll the
+// metrics endpoint as usual; the subscription is retried every reconnectDelay
+// until ctx is canceled.
+func (c *Client) StreamMetrics(ctx context.Context, streamURL string, historyLimit int, reconnectDelay time.Duration) {
+	for {
+		err := c.consumeMetricsStream(ctx, streamURL, historyLimit)
+		c.stream.drop()
+		if ctx.Err() != nil {
+			return
+		}
+		c.logger.Warn("Metrics stream dropped, polling until it reconnects",
+			zap.String("url", streamURL),
+			zap.Duration("retry_in", reconnectDelay),
+			zap.Error(err))
+
+		timer := time.NewTimer(reconnectDelay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// consumeMetricsStream reads one stream connection until it ends
+func (c *Client) consumeMetricsStream(ctx context.Context, streamURL string, historyLimit int) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, streamURL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "text/event-stream")
+
+	// No client timeout: the connection is expected to stay open
+	resp, err := c.streamClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+
+	// Start from a full history so averages don't depend on how long we've
+	// been subscribed
+	seedCtx, cancel := context.WithTimeout(ctx, c.httpClient.Timeout)
+	seed, err := c.fetchMetrics(seedCtx)
+	cancel()
+	if err != nil {
+		c.logger.Warn("Failed to seed metrics snapshot", zap.Error(err))
+	}
+	c.stream.reset(seed, historyLimit)
+	c.logger.Info("Subscribed to metrics stream",
+		zap.String("url", streamURL),
+		zap.Int("seeded", len(seed)))
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), metricsStreamMaxEvent)
+
+	var data bytes.Buffer
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			// Blank line ends the event
+			if data.Len() > 0 {
+				c.applyStreamEvent(data.Bytes())
+				data.Reset()
+			}
+			continue
+		}
+		if payload, ok := bytes.CutPrefix(line, []byte("data:")); ok {
+			if data.Len() > 0 {
+				data.WriteByte('\n')
+			}
+			data.Write(bytes.TrimPrefix(payload, []byte(" ")))
+		}
+		// Comments (keep-alives), event names and ids are ignored
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return errors.New("stream closed by collector")
+}
+
+// applyStreamEvent adds the metrics in one event, which may be a single
+// metric or an array of them
+func (c *Client) applyStreamEvent(data []byte) {
+	var metrics []MetricData
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) > 0 && trimmed[0] == '[' {
+		if err := json.Unmarshal(trimmed, &metrics); err != nil {
+			c.logger.Warn("Ignoring malformed metrics stream event", zap.Error(err))
+			return
+		}
+	} else {
+		var metric MetricData
+		if err := json.Unmarshal(trimmed, &metric); err != nil {
+			c.logger.Warn("Ignoring malformed metrics stream event", zap.Error(err))
+			return
+		}
+		metrics = []MetricData{metric}
+	}
+	c.stream.add(metrics)
+}
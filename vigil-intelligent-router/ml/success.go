@@ -0,0 +1,82 @@
+package ml
+
+import (
+	"go.uber.org/zap"
+)
+
+// successWindow is the number of recent outcomes kept per node
+const successWindow = 100
+
+// RecordOutcome records whether a request routed to nodeID succeeded.
+// These observations feed an empirical failure penalty into hybrid scoring.
+func (c *Client) RecordOutcome(nodeID string, success bool) {
+	c.outcomeMutex.Lock()
+	defer c.outcomeMutex.Unlock()
+
+	outcomes := append(c.outcomes[nodeID], success)
+	if len(outcomes) > successWindow {
+		outcomes = outcomes[len(outcomes)-successWindow:]
+	}
+	c.outcomes[nodeID] = outcomes
+	c.updateReliabilityWeight(nodeID, success)
+
+	c.logger.Debug("Recorded request outcome",
+		zap.String("node", nodeID),
+		zap.Bool("success", success),
+		zap.Int("window_size", len(outcomes)))
+}
+
+// GetSuccessRates returns the rolling success rate (0-1) per node
+func (c *Client) GetSuccessRates() map[string]float64 {
+	c.outcomeMutex.RLock()
+	defer c.outcomeMutex.RUnlock()
+
+	rates := make(map[string]float64, len(c.outcomes))
+	for nodeID, outcomes := range c.outcomes {
+		if len(outcomes) == 0 {
+			continue
+		}
+		successes := 0
+		for _, ok := range outcomes {
+			if ok {
+				successes++
+			}
+		}
+		rates[nodeID] = float64(successes) / float64(len(outcomes))
+	}
+	return rates
+}
+
+// RecentFailures returns how many of each node's last successWindow routed
+// requests failed, for nodes that have served any
+func (c *Client) RecentFailures() map[string]int {
+	c.outcomeMutex.RLock()
+	defer c.outcomeMutex.RUnlock()
+
+	failures := make(map[string]int, len(c.outcomes))
+	for nodeID, outcomes := range c.outcomes {
+		if len(outcomes) == 0 {
+			continue
+		}
+		failed := 0
+		for _, ok := range outcomes {
+			if !ok {
+				failed++
+			}
+		}
+		failures[nodeID] = failed
+	}
+	return failures
+}
+
+// blendFailureProb mixes the ML failure probability with the observed failure
+// rate, giving the observed rate the given weight
+func blendFailureProb(weight, mlFailureProb, successRate float64, hasObserved bool) float64 {
+	if !hasObserved || weight <= 0 {
+		return mlFailureProb
+	}
+	if weight > 1 {
+		weight = 1
+	}
+	return (1-weight)*mlFailureProb + weight*(1-successRate)
+}
@@ -0,0 +1,62 @@
+package ml
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestObservedFailuresDeprioritizeNode(t *testing.T) {
+	stub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/metrics" {
+			json.NewEncoder(w).Encode([]MetricData{})
+			return
+		}
+		json.NewEncoder(w).Encode(PredictionResponse{
+			RecommendedNode: "a",
+			AllPredictions: []NodePrediction{
+				{NodeID: "a", PredictedLatencyMS: 10},
+				{NodeID: "b", PredictedLatencyMS: 20},
+			},
+		})
+	}))
+	defer stub.Close()
+
+	tests := []struct {
+		name   string
+		weight float64
+		want   string
+	}{
+		{"default weight keeps the ML failure probability", 0, "a"},
+		{"observed failures outweigh the latency lead", 0.5, "b"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewClient([]string{stub.URL + "/predict"}, stub.URL+"/metrics", time.Second,
+				map[string]string{"a": "http://a.invalid", "b": "http://b.invalid"},
+				Options{SuccessRateWeight: tt.weight}, zap.NewNop())
+			for i := 0; i < 5; i++ {
+				c.RecordOutcome("a", false)
+				c.RecordOutcome("b", true)
+			}
+
+			rates := c.GetSuccessRates()
+			if rates["a"] != 0 || rates["b"] != 1 {
+				t.Fatalf("success rates = %v, want a=0 b=1", rates)
+			}
+			prediction, err := c.GetRecommendation(context.Background())
+			if err != nil {
+				t.Fatalf("GetRecommendation: %v", err)
+			}
+			if prediction.RecommendedNode != tt.want {
+				t.Errorf("recommended %s, want %s", prediction.RecommendedNode, tt.want)
+			}
+		})
+	}
+}
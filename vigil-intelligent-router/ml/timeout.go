@@ -0,0 +1,97 @@
+package ml
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Sizing for the adaptive ML timeout
+const (
+	mlLatencyWindow     = 200 // recent prediction round-trips kept
+	mlLatencyMinSamples = 20  // samples needed before the timeout adapts
+)
+
+// mlLatencySample is one prediction round-trip. A censored sample timed out:
+// the call took longer than d, by an unknown amount.
+type mlLatencySample struct {
+	d        time.Duration
+	censored bool
+}
+
+// mlLatencyTracker keeps a ring of recent prediction round-trips
+type mlLatencyTracker struct {
+	mu      sync.Mutex
+	samples []mlLatencySample
+	next    int
+}
+
+// record adds one sample, overwriting the oldest once full
+func (t *mlLatencyTracker) record(sample mlLatencySample) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.samples) < mlLatencyWindow {
+		t.samples = append(t.samples, sample)
+		return
+	}
+	t.samples[t.next] = sample
+	t.next = (t.next + 1) % mlLatencyWindow
+}
+
+// p99 returns the 99th percentile round-trip and whether it is known. It is
+// unknown with too few samples, or when it falls on a timed-out call, since
+// censored samples rank above every completed one.
+func (t *mlLatencyTracker) p99() (time.Duration, bool) {
+	t.mu.Lock()
+	sorted := append([]mlLatencySample(nil), t.samples...)
+	t.mu.Unlock()
+	if len(sorted) < mlLatencyMinSamples {
+		return 0, false
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].censored != sorted[j].censored {
+			return sorted[j].censored
+		}
+		return sorted[i].d < sorted[j].d
+	})
+	sample := sorted[(len(sorted)*99)/100]
+	return sample.d, !sample.censored
+}
+
+// recordPredictionLatency records an ML prediction round-trip. Timeouts are
+// kept as censored samples rather than as their (too short) duration; other
+// failures say nothing about latency and are left out.
+func (c *Client) recordPredictionLatency(d time.Duration, err error) {
+	var netErr net.Error
+	switch {
+	case err == nil:
+		c.latencies.record(mlLatencySample{d: d})
+	case errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()):
+		c.latencies.record(mlLatencySample{d: d, censored: true})
+	}
+}
+
+// QueryTimeout returns the timeout for one ML prediction call. With adaptive
+// timeouts enabled it is the p99 round-trip times AdaptiveTimeoutFactor,
+// clamped to [AdaptiveTimeoutMin, fixed]; otherwise, or while the p99 is
+// unknown, it is fixed.
+func (c *Client) QueryTimeout(fixed time.Duration) time.Duration {
+	if !c.options.AdaptiveTimeout {
+		return fixed
+	}
+	p99, ok := c.latencies.p99()
+	if !ok {
+		return fixed
+	}
+	timeout := time.Duration(float64(p99) * c.options.AdaptiveTimeoutFactor)
+	if timeout < c.options.AdaptiveTimeoutMin {
+		timeout = c.options.AdaptiveTimeoutMin
+	}
+	if timeout > fixed {
+		timeout = fixed
+	}
+	return timeout
+}
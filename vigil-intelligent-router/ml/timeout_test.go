@@ -0,0 +1,72 @@
+package ml
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestLatencyTrackerCensoredSamples(t *testing.T) {
+	var tracker mlLatencyTracker
+	for i := 0; i < 100; i++ {
+		tracker.record(mlLatencySample{d: 10 * time.Millisecond})
+	}
+	tracker.record(mlLatencySample{d: time.Millisecond, censored: true})
+	if p99, ok := tracker.p99(); !ok || p99 != 10*time.Millisecond {
+		t.Fatalf("p99 with 1 timeout in 101 = %v, %v; want 10ms", p99, ok)
+	}
+
+	// With more than 1% timed out, the p99 is past the timeouts and unknown
+	tracker.record(mlLatencySample{d: time.Millisecond, censored: true})
+	if p99, ok := tracker.p99(); ok {
+		t.Fatalf("p99 with 2 timeouts in 102 = %v, want unknown", p99)
+	}
+}
+
+func TestRecordPredictionLatency(t *testing.T) {
+	c := NewClient(nil, "", time.Second, nil, Options{}, zap.NewNop())
+	c.recordPredictionLatency(5*time.Millisecond, nil)
+	c.recordPredictionLatency(time.Millisecond, fmt.Errorf("request failed: %w", context.DeadlineExceeded))
+	c.recordPredictionLatency(time.Millisecond, errors.New("ML service returned status 500"))
+
+	want := []mlLatencySample{{d: 5 * time.Millisecond}, {d: time.Millisecond, censored: true}}
+	if fmt.Sprint(c.latencies.samples) != fmt.Sprint(want) {
+		t.Errorf("samples = %v, want %v", c.latencies.samples, want)
+	}
+}
+
+func TestAdaptiveTimeoutIgnoresMetricsFetch(t *testing.T) {
+	// The collector is slow; the ML service answers at once
+	stub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/metrics" {
+			time.Sleep(30 * time.Millisecond)
+			w.Write([]byte(`[]`))
+			return
+		}
+		json.NewEncoder(w).Encode(PredictionResponse{
+			RecommendedNode: "a",
+			AllPredictions:  []NodePrediction{{NodeID: "a", PredictedLatencyMS: 10}},
+		})
+	}))
+	defer stub.Close()
+
+	c := NewClient([]string{stub.URL + "/predict"}, stub.URL+"/metrics", 5*time.Second, map[string]string{"a": "http://a.invalid"},
+		Options{AdaptiveTimeout: true, AdaptiveTimeoutFactor: 3, AdaptiveTimeoutMin: 50 * time.Millisecond}, zap.NewNop())
+	for i := 0; i < mlLatencyMinSamples; i++ {
+		if _, err := c.GetRecommendation(context.Background()); err != nil {
+			t.Fatalf("GetRecommendation: %v", err)
+		}
+	}
+
+	if got := c.QueryTimeout(5 * time.Second); got != 50*time.Millisecond {
+		t.Errorf("QueryTimeout = %v, want the 50ms minimum from prediction round-trips alone", got)
+	}
+}
@@ -0,0 +1,239 @@
+package proxy
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/project-vigil/vigil-intelligent-router/config"
+	"github.com/project-vigil/vigil-intelligent-router/ml"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// requireAdmin checks the request carries the configured admin token, either as
+// "Authorization: Bearer <token>" or "X-Admin-Token". It replies 401 on failure.
+func requireAdmin(cfg *config.Config, w http.ResponseWriter, r *http.Request) bool {
+	token := r.Header.Get("X-Admin-Token")
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		token = strings.TrimPrefix(auth, "Bearer ")
+	}
+
+	if cfg.AdminToken == "" || subtle.ConstantTimeCompare([]byte(token), []byte(cfg.AdminToken)) != 1 {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return false
+	}
+	return true
+}
+
+// blocklistUpdate is the POST body for /admin/blocklist
+type blocklistUpdate struct {
+	Add    []string `json:"add"`
+	Remove []string `json:"remove"`
+}
+
+// BlocklistHandler lists (GET) or updates (POST) the manual node blocklist
+func BlocklistHandler(mlClient *ml.Client, cfg *config.Config, logger *zap.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !requireAdmin(cfg, w, r) {
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPost:
+			var update blocklistUpdate
+			if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+				http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+				return
+			}
+			// Check every ID first so a bad request changes nothing
+			for _, nodeID := range update.Add {
+				if _, ok := cfg.NodeURLMap[nodeID]; !ok {
+					http.Error(w, "Unknown node_id: "+nodeID, http.StatusBadRequest)
+					return
+				}
+			}
+			for _, nodeID := range update.Add {
+				mlClient.Block(nodeID)
+			}
+			for _, nodeID := range update.Remove {
+				mlClient.Unblock(nodeID)
+			}
+			logger.Info("Blocklist updated via admin API",
+				zap.Strings("added", update.Add),
+				zap.Strings("removed", update.Remove),
+				zap.String("remote_addr", ClientIP(cfg, r)))
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"blocklist": mlClient.Blocklist(),
+		})
+	}
+}
+
+// CalibrationResetHandler clears learned calibration offsets (POST only)
+func CalibrationResetHandler(mlClient *ml.Client, cfg *config.Config, logger *zap.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !requireAdmin(cfg, w, r) {
+			return
+		}
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		cleared := mlClient.ResetCalibration()
+		logger.Info("Calibration reset via admin API",
+			zap.Int("records_cleared", cleared),
+			zap.String("remote_addr", ClientIP(cfg, r)))
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"records_cleared": cleared,
+		})
+	}
+}
+
+// drainUpdate is the POST body for /admin/drain
+type drainUpdate struct {
+	NodeID string `json:"node_id"`
+	Drain  bool   `json:"drain"`
+}
+
+// DrainHandler reports (GET) or changes (POST) which nodes are draining. A
+// draining node gets no new requests; its in-flight count shows when it is idle.
+func (h *Handler) DrainHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !requireAdmin(h.config, w, r) {
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPost:
+			var update drainUpdate
+			if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+				http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+				return
+			}
+			if _, ok := h.config.NodeURLMap[update.NodeID]; !ok {
+				http.Error(w, "Unknown node_id", http.StatusBadRequest)
+				return
+			}
+			if update.Drain {
+				h.mlClient.Drain(update.NodeID)
+			} else {
+				h.mlClient.Undrain(update.NodeID)
+			}
+			h.logger.Info("Node drain updated via admin API",
+				zap.String("node", update.NodeID),
+				zap.Bool("drain", update.Drain),
+				zap.String("remote_addr", ClientIP(h.config, r)))
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		draining := make(map[string]interface{})
+		for _, nodeID := range h.mlClient.Draining() {
+			var inflight int64
+			if counter, ok := h.inflight[nodeID]; ok {
+				inflight = counter.Load()
+			}
+			draining[nodeID] = map[string]interface{}{"inflight": inflight}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"draining": draining,
+		})
+	}
+}
+
+// ScorePreviewHandler re-scores the latest ML prediction with candidate
+// weights (POST) without changing live routing. Omitted weights keep their
+// configured values.
+func ScorePreviewHandler(mlClient *ml.Client, cfg *config.Config, logger *zap.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !requireAdmin(cfg, w, r) {
+			return
+		}
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		candidate := mlClient.ScoringWeights()
+		if err := json.NewDecoder(r.Body).Decode(&candidate); err != nil {
+			http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+			return
+		}
+		if err := candidate.Validate(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		preview, err := mlClient.PreviewScoring(candidate)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		logger.Debug("Score preview requested",
+			zap.Any("candidate", candidate),
+			zap.Bool("changed", preview.Changed),
+			zap.String("remote_addr", ClientIP(cfg, r)))
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(preview)
+	}
+}
+
+// logLevelUpdate is the POST body for /admin/loglevel
+type logLevelUpdate struct {
+	Level string `json:"level"`
+}
+
+// LogLevelHandler reports (GET) or changes (POST) the log level without a
+// restart. The change is not persisted; LOG_LEVEL applies again on restart.
+func LogLevelHandler(level zap.AtomicLevel, cfg *config.Config, logger *zap.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !requireAdmin(cfg, w, r) {
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPost:
+			var update logLevelUpdate
+			if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+				http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+				return
+			}
+			var newLevel zapcore.Level
+			if err := newLevel.UnmarshalText([]byte(update.Level)); err != nil || update.Level == "" {
+				http.Error(w, "Invalid log level", http.StatusBadRequest)
+				return
+			}
+			previous := level.Level()
+			level.SetLevel(newLevel)
+			logger.Info("Log level changed via admin API",
+				zap.Stringer("from", previous),
+				zap.Stringer("to", newLevel),
+				zap.String("remote_addr", ClientIP(cfg, r)))
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"level": level.Level().String(),
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/project-vigil/vigil-intelligent-router/ml"
+	"go.uber.org/zap"
+)
+
+func TestBlocklistHandler(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.NodeURLMap = map[string]string{"a": "http://a.invalid", "b": "http://b.invalid"}
+	cfg.AdminToken = "secret"
+	mlClient := ml.NewClient(nil, "", cfg.MLQueryTimeout, cfg.NodeURLMap, ml.Options{}, zap.NewNop())
+	handler := BlocklistHandler(mlClient, cfg, zap.NewNop())
+
+	post := func(body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "/admin/blocklist", strings.NewReader(body))
+		r.Header.Set("Authorization", "Bearer secret")
+		rec := httptest.NewRecorder()
+		handler(rec, r)
+		return rec
+	}
+
+	if rec := post(`{"add":["a","b"]}`); rec.Code != http.StatusOK {
+		t.Fatalf("adding known nodes: %d %s", rec.Code, rec.Body.String())
+	}
+	if rec := post(`{"remove":["b"]}`); rec.Code != http.StatusOK {
+		t.Fatalf("removing a node: %d %s", rec.Code, rec.Body.String())
+	}
+
+	// A typo fails the whole update rather than silently blocking nothing
+	rec := post(`{"add":["b","typo"]}`)
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "typo") {
+		t.Errorf("adding an unknown node: got %d %s, want 400 naming it", rec.Code, rec.Body.String())
+	}
+	if got := mlClient.Blocklist(); !reflect.DeepEqual(got, []string{"a"}) {
+		t.Errorf("blocklist = %v, want [a]", got)
+	}
+
+	r := httptest.NewRequest(http.MethodPost, "/admin/blocklist", strings.NewReader(`{"add":["b"]}`))
+	rec = httptest.NewRecorder()
+	handler(rec, r)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("update without token: got %d, want 401", rec.Code)
+	}
+}
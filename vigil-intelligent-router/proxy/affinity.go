@@ -0,0 +1,157 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+
+	"github.com/project-vigil/vigil-intelligent-router/ml"
+	"go.uber.org/zap"
+)
+
+const (
+	// affinityMaxMethods bounds how many distinct methods are learned; calls to
+	// methods beyond the limit are not tracked
+	affinityMaxMethods = 256
+
+	// affinitySmoothing is the weight of each new sample in the latency average
+	affinitySmoothing = 0.1
+)
+
+// methodLatency is a node's smoothed latency for one method
+type methodLatency struct {
+	AvgLatencyMS float64 `json:"avg_latency_ms"`
+	Samples      int64   `json:"samples"`
+}
+
+// methodAffinity learns which node serves each JSON-RPC method fastest
+type methodAffinity struct {
+	mutex sync.Mutex
+	stats map[string]map[string]*methodLatency // method -> node -> latency
+}
+
+// record folds a latency sample into the node's average for method
+func (a *methodAffinity) record(method, nodeID string, latencyMS float64) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	nodes, ok := a.stats[method]
+	if !ok {
+		if len(a.stats) >= affinityMaxMethods {
+			return
+		}
+		nodes = make(map[string]*methodLatency)
+		a.stats[method] = nodes
+	}
+	stat, ok := nodes[nodeID]
+	if !ok {
+		stat = &methodLatency{AvgLatencyMS: latencyMS}
+		nodes[nodeID] = stat
+	}
+	stat.AvgLatencyMS += affinitySmoothing * (latencyMS - stat.AvgLatencyMS)
+	stat.Samples++
+}
+
+// best returns the fastest node for method among those with enough samples
+// that eligible accepts
+func (a *methodAffinity) best(method string, minSamples int64, eligible func(string) bool) (string, bool) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	bestNode, bestLatency := "", 0.0
+	for nodeID, stat := range a.stats[method] {
+		if stat.Samples < minSamples || !eligible(nodeID) {
+			continue
+		}
+		if bestNode == "" || stat.AvgLatencyMS < bestLatency {
+			bestNode, bestLatency = nodeID, stat.AvgLatencyMS
+		}
+	}
+	return bestNode, bestNode != ""
+}
+
+// snapshot copies the learned stats for reporting
+func (a *methodAffinity) snapshot() map[string]map[string]methodLatency {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	out := make(map[string]map[string]methodLatency, len(a.stats))
+	for method, nodes := range a.stats {
+		out[method] = make(map[string]methodLatency, len(nodes))
+		for nodeID, stat := range nodes {
+			out[method][nodeID] = *stat
+		}
+	}
+	return out
+}
+
+type rpcMethodContextKey struct{}
+
+// withRPCMethod attaches the method of a single-call request to the context
+func withRPCMethod(ctx context.Context, method string) context.Context {
+	return context.WithValue(ctx, rpcMethodContextKey{}, method)
+}
+
+// rpcMethodFrom returns the method attached to the request, or ""
+func rpcMethodFrom(r *http.Request) string {
+	method, _ := r.Context().Value(rpcMethodContextKey{}).(string)
+	return method
+}
+
+// applyMethodAffinity moves the request to the node learned to be fastest for
+// its method, provided that node scores within MethodAffinityBonus of the
+// current winner. Batches are left alone.
+func (h *Handler) applyMethodAffinity(r *http.Request, prediction *ml.PredictionResponse) {
+	method := rpcMethodFrom(r)
+	if h.config.MethodAffinityBonus <= 0 || method == "" {
+		return
+	}
+
+	learned, ok := h.affinity.best(method, int64(h.config.MethodAffinityMinSamples), func(nodeID string) bool {
+		return h.mlClient.NodeIneligibleReason(prediction, nodeID) == ""
+	})
+	if !ok || learned == prediction.RecommendedNode {
+		return
+	}
+
+	for _, node := range prediction.AllPredictions {
+		if node.NodeID != learned {
+			continue
+		}
+		if node.CostScore-h.config.MethodAffinityBonus <= prediction.RecommendationDetails.CostScore {
+			h.logger.Debug("Routing to learned best node for method",
+				zap.String("method", method),
+				zap.String("node", learned),
+				zap.String("scored_best", prediction.RecommendedNode))
+			prediction.SelectNode(learned)
+		}
+		return
+	}
+}
+
+// recordMethodLatency feeds an observed latency into the method affinity stats
+func (h *Handler) recordMethodLatency(r *http.Request, nodeID string, latencyMS float64) {
+	if h.config.MethodAffinityBonus <= 0 {
+		return
+	}
+	if method := rpcMethodFrom(r); method != "" {
+		h.affinity.record(method, nodeID, latencyMS)
+	}
+}
+
+// MethodAffinityHandler serves the learned per-method node latencies
+func (h *Handler) MethodAffinityHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !requireAdmin(h.config, w, r) {
+			return
+		}
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(h.affinity.snapshot())
+	}
+}
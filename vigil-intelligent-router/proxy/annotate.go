@@ -0,0 +1,75 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"time"
+)
+
+// responseAnnotation is injected into annotated responses as "_vigil"
+type responseAnnotation struct {
+	Node      string  `json:"node"`
+	LatencyMS float64 `json:"latency_ms"`
+}
+
+// annotateResponse adds a "_vigil" field naming the serving node to a single
+// JSON-RPC response for ANNOTATE_RESPONSE_NODE. Batches, encoded bodies and
+// responses over ANNOTATE_RESPONSE_MAX_BYTES are left alone. resp.Body is
+// replaced either way, so it must be read after this returns.
+func (h *Handler) annotateResponse(w http.ResponseWriter, resp *http.Response, bodyBytes []byte, targetURL string, latency time.Duration) {
+	maxBytes := int64(h.config.AnnotateResponseMaxBytes)
+	if !h.config.AnnotateResponseNode || resp.Header.Get("Content-Encoding") != "" ||
+		resp.ContentLength > maxBytes || !isJSONObject(bodyBytes) {
+		return
+	}
+
+	// Read one byte past the limit to tell a fitting body from a large one
+	buffered, err := io.ReadAll(io.LimitReader(resp.Body, maxBytes+1))
+	original := resp.Body
+	if err != nil || int64(len(buffered)) > maxBytes || !isJSONObject(buffered) || !json.Valid(buffered) {
+		resp.Body = readCloser{io.MultiReader(bytes.NewReader(buffered), original), original}
+		return
+	}
+
+	annotation, _ := json.Marshal(responseAnnotation{
+		Node:      h.nodeMetricName(targetURL),
+		LatencyMS: float64(latency.Milliseconds()),
+	})
+	annotated := injectField(buffered, "_vigil", annotation)
+	resp.Body = readCloser{bytes.NewReader(annotated), original}
+	resp.ContentLength = int64(len(annotated))
+	w.Header().Del("Content-Length")
+}
+
+// readCloser reads from one source and closes another
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// isJSONObject reports whether body's first non-whitespace byte opens an object
+func isJSONObject(body []byte) bool {
+	trimmed := bytes.TrimSpace(body)
+	return len(trimmed) > 0 && trimmed[0] == '{'
+}
+
+// injectField adds "name": value as the last member of a valid JSON object,
+// leaving the existing members byte-for-byte intact
+func injectField(object []byte, name string, value []byte) []byte {
+	trimmed := bytes.TrimSpace(object)
+	inner := bytes.TrimSpace(trimmed[1 : len(trimmed)-1])
+
+	var out bytes.Buffer
+	out.Write(trimmed[:len(trimmed)-1])
+	if len(inner) > 0 {
+		out.WriteByte(',')
+	}
+	key, _ := json.Marshal(name)
+	out.Write(key)
+	out.WriteByte(':')
+	out.Write(value)
+	out.WriteByte('}')
+	return out.Bytes()
+}
@@ -0,0 +1,107 @@
+package proxy
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/project-vigil/vigil-intelligent-router/ml"
+)
+
+func TestAnnotateResponseNode(t *testing.T) {
+	small := `{"jsonrpc":"2.0","id":1,"result":42}`
+	large := `{"jsonrpc":"2.0","id":1,"result":"` + strings.Repeat("x", 200) + `"}`
+	single := `{"jsonrpc":"2.0","id":1,"method":"getSlot"}`
+	batch := `[{"jsonrpc":"2.0","id":1,"method":"getSlot"}]`
+
+	tests := []struct {
+		name      string
+		enabled   bool
+		request   string
+		response  string
+		chunked   bool
+		annotated bool
+	}{
+		{"small response", true, single, small, false, true},
+		{"small chunked response", true, single, small, true, true},
+		{"oversized response", true, single, large, false, false},
+		{"oversized chunked response", true, single, large, true, false},
+		{"batch request", true, batch, `[` + small + `]`, false, false},
+		{"disabled", false, single, small, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				if tt.chunked {
+					// Flushing before the end drops Content-Length
+					io.WriteString(w, tt.response[:10])
+					w.(http.Flusher).Flush()
+					io.WriteString(w, tt.response[10:])
+					return
+				}
+				io.WriteString(w, tt.response)
+			}))
+			defer upstream.Close()
+
+			cfg := testConfig(t)
+			cfg.NodeURLMap = map[string]string{"node_a": upstream.URL}
+			cfg.AnnotateResponseNode = tt.enabled
+			cfg.AnnotateResponseMaxBytes = 100
+			h := newTestHandler(t, cfg, ml.Options{})
+
+			r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.request))
+			w := httptest.NewRecorder()
+			if err := h.forwardRequest(w, r, upstream.URL, []byte(tt.request), time.Now()); err != nil {
+				t.Fatalf("forwardRequest: %v", err)
+			}
+
+			if !tt.annotated {
+				if w.Body.String() != tt.response {
+					t.Fatalf("body = %q, want it unchanged", w.Body.String())
+				}
+				return
+			}
+
+			var body map[string]json.RawMessage
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatalf("annotated body is not JSON: %v (%s)", err, w.Body)
+			}
+			if string(body["result"]) != "42" {
+				t.Errorf("result = %s, want 42", body["result"])
+			}
+			var annotation responseAnnotation
+			if err := json.Unmarshal(body["_vigil"], &annotation); err != nil || annotation.Node != "node_a" {
+				t.Fatalf("_vigil = %s, want node node_a", body["_vigil"])
+			}
+			if cl := w.Header().Get("Content-Length"); cl != "" && cl != strconv.Itoa(w.Body.Len()) {
+				t.Errorf("Content-Length = %s for a %d byte body", cl, w.Body.Len())
+			}
+		})
+	}
+}
+
+func TestInjectField(t *testing.T) {
+	tests := []struct {
+		object string
+		want   string
+	}{
+		{`{"a":1}`, `{"a":1,"_vigil":{}}`},
+		{` {} `, `{"_vigil":{}}`},
+		{`{"a":{"b":[1,2]}}`, `{"a":{"b":[1,2]},"_vigil":{}}`},
+	}
+	for _, tt := range tests {
+		got := string(injectField([]byte(tt.object), "_vigil", []byte(`{}`)))
+		if got != tt.want {
+			t.Errorf("injectField(%s) = %s, want %s", tt.object, got, tt.want)
+		}
+		if !json.Valid([]byte(got)) {
+			t.Errorf("injectField(%s) produced invalid JSON", tt.object)
+		}
+	}
+}
@@ -0,0 +1,190 @@
+package proxy
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// auditEntry is one routing decision in the audit log
+type auditEntry struct {
+	Timestamp    string   `json:"timestamp"`
+	RequestID    string   `json:"request_id"`
+	ClientIP     string   `json:"client_ip"`
+	Methods      []string `json:"methods"`
+	Node         string   `json:"node,omitempty"`
+	Score        *float64 `json:"score,omitempty"`
+	FallbackUsed bool     `json:"fallback_used"`
+	Status       int      `json:"status"`
+	DurationMS   int64    `json:"duration_ms"`
+}
+
+// auditLog appends one JSON line per request to AUDIT_LOG_FILE. Entries are
+// written straight to the file and fsynced every AUDIT_FSYNC_INTERVAL_SECONDS.
+type auditLog struct {
+	mutex sync.Mutex
+	file  *os.File
+	dirty bool
+}
+
+// OpenAuditLog opens AUDIT_LOG_FILE for appending and schedules periodic fsyncs.
+// It does nothing when no audit log is configured.
+func (h *Handler) OpenAuditLog(tasks *TaskManager) error {
+	if h.config.AuditLogFile == "" {
+		return nil
+	}
+	file, err := os.OpenFile(h.config.AuditLogFile, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+	h.audit = &auditLog{file: file}
+	tasks.Every("audit_fsync", h.config.AuditFsyncInterval, func(ctx context.Context) {
+		if err := h.audit.sync(); err != nil {
+			h.logger.Error("Failed to sync audit log", zap.Error(err))
+		}
+	})
+	return nil
+}
+
+// CloseAuditLog flushes and closes the audit log
+func (h *Handler) CloseAuditLog() error {
+	if h.audit == nil {
+		return nil
+	}
+	if err := h.audit.sync(); err != nil {
+		return err
+	}
+	return h.audit.file.Close()
+}
+
+func (a *auditLog) append(entry *auditEntry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.dirty = true
+	_, err = a.file.Write(line)
+	return err
+}
+
+func (a *auditLog) sync() error {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	if !a.dirty {
+		return nil
+	}
+	a.dirty = false
+	return a.file.Sync()
+}
+
+// statusRecorder remembers the status code written to the client
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (s *statusRecorder) WriteHeader(status int) {
+	if s.status == 0 {
+		s.status = status
+	}
+	s.ResponseWriter.WriteHeader(status)
+}
+
+func (s *statusRecorder) Write(p []byte) (int, error) {
+	if s.status == 0 {
+		s.status = http.StatusOK
+	}
+	return s.ResponseWriter.Write(p)
+}
+
+func (s *statusRecorder) Flush() {
+	if flusher, ok := s.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+type auditContextKey struct{}
+
+// startAudit begins the audit entry for a request. The returned writer and
+// request must be used for the rest of the request, and finish called once the
+// response is written. Without an audit log it returns its inputs unchanged.
+func (h *Handler) startAudit(w http.ResponseWriter, r *http.Request) (http.ResponseWriter, *http.Request, func()) {
+	if h.audit == nil {
+		return w, r, func() {}
+	}
+
+	startTime := time.Now()
+	entry := &auditEntry{
+		Timestamp: startTime.UTC().Format(time.RFC3339Nano),
+		RequestID: r.Header.Get("X-Request-ID"),
+		ClientIP:  ClientIP(h.config, r),
+		Methods:   []string{},
+	}
+	if entry.RequestID == "" {
+		entry.RequestID = newAuditID()
+	}
+	recorder := &statusRecorder{ResponseWriter: w}
+
+	finish := func() {
+		entry.Status = recorder.status
+		entry.DurationMS = time.Since(startTime).Milliseconds()
+		if err := h.audit.append(entry); err != nil {
+			h.logger.Error("Failed to write audit log entry",
+				zap.String("request_id", entry.RequestID),
+				zap.Error(err))
+		}
+	}
+	return recorder, r.WithContext(context.WithValue(r.Context(), auditContextKey{}, entry)), finish
+}
+
+// auditFrom returns the audit entry attached to the request, if any
+func auditFrom(r *http.Request) *auditEntry {
+	entry, _ := r.Context().Value(auditContextKey{}).(*auditEntry)
+	return entry
+}
+
+// auditMethods records the JSON-RPC methods of the request
+func auditMethods(r *http.Request, reqs []rpcRequest) {
+	if entry := auditFrom(r); entry != nil {
+		entry.Methods = rpcMethods(reqs)
+	}
+}
+
+// auditTarget records where the request was sent. score is nil for the
+// fallback and last-resort URLs, which were not chosen by scoring.
+func (h *Handler) auditTarget(r *http.Request, targetURL string, score *float64) {
+	h.recordProbeTarget(r, targetURL)
+	entry := auditFrom(r)
+	if entry == nil {
+		return
+	}
+	if nodeID, ok := h.costs.urlToNode[targetURL]; ok {
+		entry.Node, entry.Score, entry.FallbackUsed = nodeID, score, false
+		return
+	}
+	entry.Node, entry.Score, entry.FallbackUsed = "fallback", nil, true
+	if targetURL == h.config.LastResortNodeURL {
+		entry.Node = "last_resort"
+	}
+}
+
+// newAuditID returns a random id for requests without X-Request-ID
+func newAuditID() string {
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return fmt.Sprintf("%d", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b[:])
+}
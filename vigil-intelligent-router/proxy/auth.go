@@ -0,0 +1,36 @@
+package proxy
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/project-vigil/vigil-intelligent-router/config"
+)
+
+// basicAuth holds credentials for an upstream node
+type basicAuth struct {
+	username string
+	password string
+}
+
+// buildNodeAuth maps node URLs to their configured basic-auth credentials
+func buildNodeAuth(cfg *config.Config) map[string]basicAuth {
+	auth := make(map[string]basicAuth)
+	for nodeID, creds := range cfg.NodeBasicAuth {
+		url, ok := cfg.NodeURLMap[nodeID]
+		if !ok {
+			continue
+		}
+		username, password, _ := strings.Cut(creds, ":")
+		auth[url] = basicAuth{username: username, password: password}
+	}
+	return auth
+}
+
+// setUpstreamAuth adds the Authorization header for nodes with credentials.
+// Credentials are never logged.
+func (h *Handler) setUpstreamAuth(req *http.Request, targetURL string) {
+	if creds, ok := h.nodeAuth[targetURL]; ok {
+		req.SetBasicAuth(creds.username, creds.password)
+	}
+}
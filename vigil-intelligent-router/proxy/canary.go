@@ -0,0 +1,117 @@
+package proxy
+
+import (
+	"math"
+	"sync"
+	"sync/atomic"
+
+	"github.com/project-vigil/vigil-intelligent-router/ml"
+	"go.uber.org/zap"
+)
+
+// latencyTally accumulates request outcomes for one side of the canary
+// comparison, or for the shadow node
+type latencyTally struct {
+	requests     int64
+	successes    int64
+	latencySumMS float64
+}
+
+func (t latencyTally) report() map[string]interface{} {
+	report := map[string]interface{}{
+		"requests": t.requests,
+	}
+	if t.requests > 0 {
+		report["success_rate"] = float64(t.successes) / float64(t.requests)
+	}
+	if t.successes > 0 {
+		report["avg_latency_ms"] = t.latencySumMS / float64(t.successes)
+	}
+	return report
+}
+
+// canaryStats compares the canary node against all other nodes
+type canaryStats struct {
+	mutex    sync.Mutex
+	canary   latencyTally
+	baseline latencyTally
+}
+
+// canaryTurn reports whether the next request belongs to the canary
+func (h *Handler) canaryTurn() bool {
+	return sampleTurn(&h.canaryCounter, h.config.CanaryPercent)
+}
+
+// sampleTurn counts a request and reports whether it is sampled. It spreads
+// percent of requests evenly, so exactly that share is sampled over time.
+func sampleTurn(counter *atomic.Int64, percent float64) bool {
+	n := float64(counter.Add(1))
+	share := percent / 100
+	return math.Floor(n*share) > math.Floor((n-1)*share)
+}
+
+// applyCanary routes this request to the canary node regardless of its score
+// when it is the canary's turn. Blocklisted or otherwise ineligible canaries
+// are left alone. A canary the ML service didn't score (e.g. a node it hasn't
+// been trained on yet) is routed to by its configured URL.
+func (h *Handler) applyCanary(prediction *ml.PredictionResponse) {
+	canary := h.config.CanaryNode
+	if canary == "" || h.config.CanaryPercent <= 0 || !h.canaryTurn() {
+		return
+	}
+	if prediction.RecommendedNode == canary {
+		return
+	}
+	if reason := h.mlClient.NodeIneligibleReason(prediction, canary); reason != "" {
+		h.logger.Debug("Skipping canary routing",
+			zap.String("node", canary),
+			zap.String("reason", reason))
+		return
+	}
+	if prediction.SelectNode(canary) {
+		h.logger.Debug("Routing to canary node", zap.String("node", canary))
+		return
+	}
+	if _, err := h.mlClient.GetRecommendedNodeURL(canary); err != nil {
+		h.logger.Debug("Skipping canary routing",
+			zap.String("node", canary),
+			zap.Error(err))
+		return
+	}
+	prediction.RecommendedNode = canary
+	prediction.RecommendationDetails = ml.NodePrediction{NodeID: canary}
+	h.logger.Debug("Routing to canary node without an ML prediction", zap.String("node", canary))
+}
+
+// recordCanaryComparison tallies an upstream outcome against the canary or the baseline
+func (h *Handler) recordCanaryComparison(nodeID string, latencyMS float64, success bool) {
+	if h.config.CanaryNode == "" {
+		return
+	}
+
+	h.canary.mutex.Lock()
+	defer h.canary.mutex.Unlock()
+
+	tally := &h.canary.baseline
+	if nodeID == h.config.CanaryNode {
+		tally = &h.canary.canary
+	}
+	tally.requests++
+	if success {
+		tally.successes++
+		tally.latencySumMS += latencyMS
+	}
+}
+
+// canaryReport summarizes canary vs baseline performance for /stats
+func (h *Handler) canaryReport() map[string]interface{} {
+	h.canary.mutex.Lock()
+	defer h.canary.mutex.Unlock()
+
+	return map[string]interface{}{
+		"node":     h.config.CanaryNode,
+		"percent":  h.config.CanaryPercent,
+		"canary":   h.canary.canary.report(),
+		"baseline": h.canary.baseline.report(),
+	}
+}
@@ -0,0 +1,51 @@
+package proxy
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/project-vigil/vigil-intelligent-router/ml"
+)
+
+func TestCanaryWithoutPrediction(t *testing.T) {
+	tests := []struct {
+		name    string
+		blocked bool
+		want    string
+	}{
+		{"unscored canary gets its share", false, "canary"},
+		{"blocklisted canary gets none", true, "a"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig(t)
+			cfg.NodeURLMap = map[string]string{
+				"a":      namedUpstream(t, "a"),
+				"canary": namedUpstream(t, "canary"),
+			}
+			cfg.CanaryNode = "canary"
+			cfg.CanaryPercent = 100
+			// The ML service only knows about a
+			backendStub(t, cfg, []ml.MetricData{recentMetric("a", 10)}, ml.PredictionResponse{
+				RecommendedNode: "a",
+				AllPredictions:  []ml.NodePrediction{{NodeID: "a", PredictedLatencyMS: 10}},
+			})
+			h := newTestHandler(t, cfg, ml.Options{})
+			if tt.blocked {
+				h.mlClient.Block("canary")
+			}
+
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, newRPCRequest("getSlot"))
+
+			want := `{"jsonrpc":"2.0","id":1,"result":"` + tt.want + `"}`
+			if rec.Body.String() != want {
+				t.Fatalf("got %d %s, want node %s", rec.Code, rec.Body.String(), tt.want)
+			}
+			report := h.canaryReport()
+			if got := report["canary"].(map[string]interface{})["requests"]; (got == int64(1)) != !tt.blocked {
+				t.Errorf("canary requests = %v", got)
+			}
+		})
+	}
+}
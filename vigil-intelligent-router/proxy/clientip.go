@@ -0,0 +1,54 @@
+package proxy
+
+import (
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/project-vigil/vigil-intelligent-router/config"
+)
+
+// ClientIP returns the real client address. X-Forwarded-For and X-Real-IP are
+// only honored when the immediate peer is a trusted proxy; the forwarded chain
+// is walked right to left, skipping trusted hops, so a client cannot spoof its
+// address by prepending entries.
+func ClientIP(cfg *config.Config, r *http.Request) string {
+	peer := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(peer); err == nil {
+		peer = host
+	}
+	if !isTrustedProxy(cfg, peer) {
+		return peer
+	}
+
+	if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
+		hops := strings.Split(xff, ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop := strings.TrimSpace(hops[i])
+			if hop == "" {
+				continue
+			}
+			if i == 0 || !isTrustedProxy(cfg, hop) {
+				return hop
+			}
+		}
+	}
+	if realIP := strings.TrimSpace(r.Header.Get("X-Real-IP")); realIP != "" {
+		return realIP
+	}
+	return peer
+}
+
+// isTrustedProxy reports whether addr falls inside TRUSTED_PROXIES
+func isTrustedProxy(cfg *config.Config, addr string) bool {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return false
+	}
+	for _, network := range cfg.TrustedProxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
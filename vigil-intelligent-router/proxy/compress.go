@@ -0,0 +1,47 @@
+package proxy
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// acceptsGzip reports whether the client listed gzip in Accept-Encoding with
+// a non-zero quality. A malformed q counts as a refusal.
+func acceptsGzip(r *http.Request) bool {
+	for _, part := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		encoding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if !strings.EqualFold(strings.TrimSpace(encoding), "gzip") {
+			continue
+		}
+		for _, param := range strings.Split(params, ";") {
+			name, value, _ := strings.Cut(param, "=")
+			if strings.EqualFold(strings.TrimSpace(name), "q") {
+				q, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+				return err == nil && q > 0
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// prepareCompression decides whether to gzip resp for the client. Responses of
+// unknown length are peeked to see whether they reach the threshold, so the
+// returned reader must be used in place of resp.Body.
+func (h *Handler) prepareCompression(r *http.Request, resp *http.Response) (io.Reader, bool) {
+	threshold := h.config.CompressionMinBytes
+	if !h.config.ResponseCompressionEnabled || !acceptsGzip(r) ||
+		resp.Header.Get("Content-Encoding") != "" || resp.StatusCode == http.StatusNoContent {
+		return resp.Body, false
+	}
+	if resp.ContentLength >= 0 {
+		return resp.Body, resp.ContentLength >= int64(threshold)
+	}
+
+	peeker := bufio.NewReaderSize(resp.Body, max(threshold, 16))
+	peeked, _ := peeker.Peek(threshold)
+	return peeker, len(peeked) >= threshold
+}
@@ -0,0 +1,66 @@
+package proxy
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// WritePreflight answers a CORS preflight request. The caller sets
+// Access-Control-Allow-Methods and -Headers to everything the endpoint accepts;
+// the requested method and headers are echoed back from within those lists, and
+// dropped when not allowed so the browser refuses the request. It also adds
+// Access-Control-Max-Age so browsers cache the result instead of repeating the
+// preflight before every request.
+func WritePreflight(w http.ResponseWriter, r *http.Request, maxAge time.Duration) {
+	header := w.Header()
+	header.Add("Vary", "Access-Control-Request-Method")
+	header.Add("Vary", "Access-Control-Request-Headers")
+
+	if method := r.Header.Get("Access-Control-Request-Method"); method != "" {
+		// Method names are case-sensitive
+		if listContains(header.Get("Access-Control-Allow-Methods"), method, false) {
+			header.Set("Access-Control-Allow-Methods", method)
+		} else {
+			header.Del("Access-Control-Allow-Methods")
+		}
+	}
+
+	if requested := r.Header.Get("Access-Control-Request-Headers"); requested != "" {
+		allowed := header.Get("Access-Control-Allow-Headers")
+		var reflected []string
+		for _, name := range strings.Split(requested, ",") {
+			name = strings.TrimSpace(name)
+			if name == "" {
+				continue
+			}
+			if !listContains(allowed, name, true) {
+				reflected = nil
+				break
+			}
+			reflected = append(reflected, name)
+		}
+		if len(reflected) > 0 {
+			header.Set("Access-Control-Allow-Headers", strings.Join(reflected, ", "))
+		} else {
+			header.Del("Access-Control-Allow-Headers")
+		}
+	}
+
+	if maxAge > 0 {
+		header.Set("Access-Control-Max-Age", strconv.Itoa(int(maxAge.Seconds())))
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// listContains reports whether the comma-separated list contains value
+func listContains(list, value string, foldCase bool) bool {
+	for _, item := range strings.Split(list, ",") {
+		item = strings.TrimSpace(item)
+		if item == value || (foldCase && strings.EqualFold(item, value)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,73 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/project-vigil/vigil-intelligent-router/ml"
+)
+
+func preflight(method, headers string) *http.Request {
+	r := httptest.NewRequest(http.MethodOptions, "/", nil)
+	r.Header.Set("Origin", "https://app.example")
+	r.Header.Set("Access-Control-Request-Method", method)
+	if headers != "" {
+		r.Header.Set("Access-Control-Request-Headers", headers)
+	}
+	return r
+}
+
+func TestPreflight(t *testing.T) {
+	tests := []struct {
+		name        string
+		allowGet    bool
+		method      string
+		headers     string
+		wantMethods string
+		wantHeaders string
+	}{
+		{"post with headers", false, "POST", "content-type, x-vigil-explain", "POST", "content-type, x-vigil-explain"},
+		{"post without headers", false, "POST", "", "POST", "Content-Type, Authorization, X-Vigil-Explain, X-Vigil-Tenant, X-API-Key"},
+		{"get without ALLOW_GET_RPC", false, "GET", "", "", "Content-Type, Authorization, X-Vigil-Explain, X-Vigil-Tenant, X-API-Key"},
+		{"get with ALLOW_GET_RPC", true, "GET", "", "GET", "Content-Type, Authorization, X-Vigil-Explain, X-Vigil-Tenant, X-API-Key"},
+		{"disallowed method", false, "DELETE", "", "", "Content-Type, Authorization, X-Vigil-Explain, X-Vigil-Tenant, X-API-Key"},
+		{"disallowed header", false, "POST", "content-type, x-evil", "POST", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig(t)
+			cfg.AllowGetRPC = tt.allowGet
+			cfg.CORSMaxAge = 600 * time.Second
+			h := newTestHandler(t, cfg, ml.Options{})
+
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, preflight(tt.method, tt.headers))
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, want 200", rec.Code)
+			}
+			if got := rec.Header().Get("Access-Control-Max-Age"); got != "600" {
+				t.Errorf("Access-Control-Max-Age = %q, want 600", got)
+			}
+			if got := rec.Header().Get("Access-Control-Allow-Methods"); got != tt.wantMethods {
+				t.Errorf("Access-Control-Allow-Methods = %q, want %q", got, tt.wantMethods)
+			}
+			if got := rec.Header().Get("Access-Control-Allow-Headers"); got != tt.wantHeaders {
+				t.Errorf("Access-Control-Allow-Headers = %q, want %q", got, tt.wantHeaders)
+			}
+		})
+	}
+}
+
+func TestPreflightMaxAgeDisabled(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.CORSMaxAge = 0
+	h := newTestHandler(t, cfg, ml.Options{})
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, preflight("POST", ""))
+	if got := rec.Header().Values("Access-Control-Max-Age"); len(got) != 0 {
+		t.Errorf("Access-Control-Max-Age = %v with CORS_MAX_AGE_SECONDS=0", got)
+	}
+}
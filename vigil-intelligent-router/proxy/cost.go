@@ -0,0 +1,75 @@
+package proxy
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+)
+
+// costTally counts requests answered by each node for spend estimates
+type costTally struct {
+	mutex     sync.Mutex
+	urlToNode map[string]string
+	requests  map[string]int64
+}
+
+func newCostTally(nodeURLMap map[string]string) *costTally {
+	urlToNode := make(map[string]string, len(nodeURLMap))
+	for nodeID, url := range nodeURLMap {
+		urlToNode[url] = nodeID
+	}
+	return &costTally{urlToNode: urlToNode, requests: make(map[string]int64)}
+}
+
+// recordRequest counts a request answered by targetURL. URLs that are not
+// configured nodes (fallback, last resort) are ignored.
+func (t *costTally) recordRequest(targetURL string) {
+	nodeID, ok := t.urlToNode[targetURL]
+	if !ok {
+		return
+	}
+	t.mutex.Lock()
+	t.requests[nodeID]++
+	t.mutex.Unlock()
+}
+
+// costReport returns request counts and estimated spend per node, in cents
+func (h *Handler) costReport() map[string]interface{} {
+	h.costs.mutex.Lock()
+	defer h.costs.mutex.Unlock()
+
+	nodes := make(map[string]interface{}, len(h.costs.requests))
+	total := 0.0
+	for nodeID, requests := range h.costs.requests {
+		unitCost := h.config.NodeCost[nodeID]
+		nodeTotal := float64(requests) * unitCost
+		total += nodeTotal
+		nodes[nodeID] = map[string]interface{}{
+			"requests":         requests,
+			"unit_cost_cents":  unitCost,
+			"total_cost_cents": nodeTotal,
+		}
+	}
+
+	return map[string]interface{}{
+		"nodes":            nodes,
+		"total_cost_cents": total,
+	}
+}
+
+// CostHandler serves the estimated per-node spend as JSON
+func (h *Handler) CostHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+		w.Header().Set("Content-Type", "application/json")
+
+		if r.Method == http.MethodOptions {
+			WritePreflight(w, r, h.config.CORSMaxAge)
+			return
+		}
+
+		json.NewEncoder(w).Encode(h.costReport())
+	}
+}
@@ -0,0 +1,230 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/project-vigil/vigil-intelligent-router/config"
+	"github.com/project-vigil/vigil-intelligent-router/ml"
+	"go.uber.org/zap"
+)
+
+// maxDecisionBatch caps how many events are sent to the backend at once
+const maxDecisionBatch = 500
+
+// decisionEvent is one routing decision and how it turned out
+type decisionEvent struct {
+	Timestamp         string              `json:"timestamp"`
+	RequestID         string              `json:"request_id,omitempty"`
+	Method            string              `json:"method,omitempty"`
+	Source            string              `json:"source,omitempty"`
+	MLRecommendedNode string              `json:"ml_recommended_node,omitempty"`
+	ChosenNode        string              `json:"chosen_node"`
+	Predictions       []ml.NodePrediction `json:"predictions"`
+	RecentAverages    map[string]float64  `json:"recent_avg_latency_ms,omitempty"`
+	PredictedLatency  float64             `json:"predicted_latency_ms"`
+	Outcome           decisionOutcome     `json:"outcome"`
+
+	at time.Time
+}
+
+// decisionOutcome is what happened when the request was forwarded
+type decisionOutcome struct {
+	Success   bool    `json:"success"`
+	Status    int     `json:"status,omitempty"`
+	LatencyMS float64 `json:"latency_ms"`
+}
+
+// decisionExporter queues decision events for a backend without ever blocking
+// a request: when the buffer is full the event is dropped and counted
+type decisionExporter struct {
+	events        chan []byte
+	encode        func(event *decisionEvent) ([]byte, error)
+	send          func(ctx context.Context, events [][]byte) error
+	flushInterval time.Duration
+	logger        *zap.Logger
+
+	published atomic.Int64
+	dropped   atomic.Int64
+	failed    atomic.Int64
+}
+
+func newDecisionExporter(bufferSize int, flushInterval time.Duration, encode func(event *decisionEvent) ([]byte, error), send func(ctx context.Context, events [][]byte) error, logger *zap.Logger) *decisionExporter {
+	return &decisionExporter{
+		events:        make(chan []byte, bufferSize),
+		encode:        encode,
+		send:          send,
+		flushInterval: flushInterval,
+		logger:        logger,
+	}
+}
+
+// enqueue adds an encoded event, dropping it if the buffer is full
+func (e *decisionExporter) enqueue(event []byte) {
+	select {
+	case e.events <- event:
+	default:
+		e.dropped.Add(1)
+	}
+}
+
+// run sends queued events in batches until ctx is canceled, then makes one
+// last attempt to send what is left
+func (e *decisionExporter) run(ctx context.Context) {
+	ticker := time.NewTicker(e.flushInterval)
+	defer ticker.Stop()
+
+	var batch [][]byte
+	flush := func(ctx context.Context) {
+		if len(batch) == 0 {
+			return
+		}
+		if err := e.send(ctx, batch); err != nil {
+			e.failed.Add(int64(len(batch)))
+			e.logger.Warn("Failed to export routing decisions",
+				zap.Int("events", len(batch)),
+				zap.Error(err))
+		} else {
+			e.published.Add(int64(len(batch)))
+		}
+		batch = nil
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			for len(e.events) > 0 && len(batch) < maxDecisionBatch {
+				batch = append(batch, <-e.events)
+			}
+			final, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			flush(final)
+			cancel()
+			return
+		case event := <-e.events:
+			batch = append(batch, event)
+			if len(batch) >= maxDecisionBatch {
+				flush(ctx)
+			}
+		case <-ticker.C:
+			flush(ctx)
+		}
+	}
+}
+
+func (e *decisionExporter) report() map[string]int64 {
+	return map[string]int64{
+		"published": e.published.Load(),
+		"dropped":   e.dropped.Load(),
+		"failed":    e.failed.Load(),
+		"queued":    int64(len(e.events)),
+	}
+}
+
+// newDecisionExports returns an exporter per configured backend ("kafka",
+// "file"), each with its own queue so a slow backend can't hold up the other.
+// The map is empty when decision export is disabled.
+func newDecisionExports(cfg *config.Config, logger *zap.Logger) map[string]*decisionExporter {
+	encode := encodeDecisionJSON
+	if cfg.DecisionLogFormat == "influx" {
+		encode = encodeDecisionLine
+	}
+
+	exporters := make(map[string]*decisionExporter)
+	if len(cfg.KafkaBrokers) > 0 {
+		producer := newKafkaProducer(cfg.KafkaBrokers, cfg.KafkaTopic, 10*time.Second)
+		exporters["kafka"] = newDecisionExporter(cfg.KafkaBufferSize, cfg.KafkaFlushInterval, encode, producer.produce,
+			logger.With(zap.String("sink", "kafka")))
+	}
+	if cfg.DecisionLogFile != "" {
+		sink := &decisionFile{path: cfg.DecisionLogFile}
+		exporters["file"] = newDecisionExporter(cfg.DecisionBufferSize, cfg.DecisionFlushInterval, encode, sink.write,
+			logger.With(zap.String("sink", "file")))
+	}
+	return exporters
+}
+
+func encodeDecisionJSON(event *decisionEvent) ([]byte, error) {
+	return json.Marshal(event)
+}
+
+// decisionFile appends one event per line to DECISION_LOG_FILE. The file is
+// opened on first use and reopened after a write error. Like the Kafka
+// producer it is only used from the exporter's goroutine.
+type decisionFile struct {
+	path string
+	file *os.File
+}
+
+func (f *decisionFile) write(ctx context.Context, events [][]byte) error {
+	if f.file == nil {
+		file, err := os.OpenFile(f.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+		if err != nil {
+			return fmt.Errorf("failed to open decision log: %w", err)
+		}
+		f.file = file
+	}
+
+	var buf bytes.Buffer
+	for _, event := range events {
+		buf.Write(event)
+		buf.WriteByte('\n')
+	}
+	if _, err := f.file.Write(buf.Bytes()); err != nil {
+		f.file.Close()
+		f.file = nil
+		return err
+	}
+	return nil
+}
+
+// StartDecisionExport runs the configured decision exporters
+func (h *Handler) StartDecisionExport(tasks *TaskManager) {
+	for name, exporter := range h.decisions {
+		tasks.Go("decision_export_"+name, exporter.run)
+	}
+}
+
+// exportDecision queues the routing decision for r and its outcome with
+// every exporter
+func (h *Handler) exportDecision(r *http.Request, prediction *ml.PredictionResponse, outcome decisionOutcome) {
+	if len(h.decisions) == 0 {
+		return
+	}
+
+	now := time.Now()
+	event := decisionEvent{
+		Timestamp:        now.UTC().Format(time.RFC3339Nano),
+		RequestID:        r.Header.Get("X-Request-ID"),
+		Method:           rpcMethodFrom(r),
+		ChosenNode:       prediction.RecommendedNode,
+		Predictions:      prediction.AllPredictions,
+		PredictedLatency: prediction.RecommendationDetails.PredictedLatencyMS,
+		Outcome:          outcome,
+		at:               now,
+	}
+	if entry := auditFrom(r); entry != nil {
+		event.RequestID = entry.RequestID
+	}
+	if prediction.Decision != nil {
+		event.Source = prediction.Decision.Source
+		event.MLRecommendedNode = prediction.Decision.MLRecommendedNode
+		event.RecentAverages = prediction.Decision.RecentAverages
+	}
+
+	for name, exporter := range h.decisions {
+		encoded, err := exporter.encode(&event)
+		if err != nil {
+			h.logger.Debug("Failed to encode routing decision",
+				zap.String("sink", name),
+				zap.Error(err))
+			continue
+		}
+		exporter.enqueue(encoded)
+	}
+}
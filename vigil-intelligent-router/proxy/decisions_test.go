@@ -0,0 +1,190 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/project-vigil/vigil-intelligent-router/ml"
+	"go.uber.org/zap"
+)
+
+func TestDecisionExporterDropsWhenFull(t *testing.T) {
+	send := func(ctx context.Context, events [][]byte) error { return nil }
+	exporter := newDecisionExporter(2, time.Hour, encodeDecisionJSON, send, zap.NewNop())
+
+	for i := 0; i < 5; i++ {
+		exporter.enqueue([]byte("{}"))
+	}
+
+	report := exporter.report()
+	if report["queued"] != 2 || report["dropped"] != 3 {
+		t.Fatalf("report = %v, want 2 queued and 3 dropped", report)
+	}
+}
+
+func TestDecisionExporterCountsPublishedAndFailed(t *testing.T) {
+	var sent [][]byte
+	fail := true
+	send := func(ctx context.Context, events [][]byte) error {
+		if fail {
+			fail = false
+			return errors.New("broker unavailable")
+		}
+		sent = append(sent, events...)
+		return nil
+	}
+	exporter := newDecisionExporter(10, 10*time.Millisecond, encodeDecisionJSON, send, zap.NewNop())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		exporter.run(ctx)
+		close(done)
+	}()
+
+	exporter.enqueue([]byte(`{"n":1}`))
+	waitFor(t, func() bool { return exporter.failed.Load() == 1 })
+	exporter.enqueue([]byte(`{"n":2}`))
+	exporter.enqueue([]byte(`{"n":3}`))
+	cancel()
+	<-done
+
+	report := exporter.report()
+	if report["failed"] != 1 || report["published"] != 2 {
+		t.Fatalf("report = %v, want 1 failed and 2 published", report)
+	}
+	if len(sent) != 2 {
+		t.Fatalf("sent %d events, want 2", len(sent))
+	}
+}
+
+func TestExportDecisionSchema(t *testing.T) {
+	var events [][]byte
+	h := &Handler{logger: zap.NewNop()}
+	h.decisions = map[string]*decisionExporter{
+		"file": newDecisionExporter(10, time.Hour, encodeDecisionJSON, nil, zap.NewNop()),
+	}
+
+	r := httptest.NewRequest("POST", "/", nil)
+	r.Header.Set("X-Request-ID", "req-1")
+	r = r.WithContext(withRPCMethod(r.Context(), "getBalance"))
+	prediction := &ml.PredictionResponse{
+		RecommendedNode: "agave2",
+		AllPredictions: []ml.NodePrediction{
+			{NodeID: "agave1", CostScore: 20},
+			{NodeID: "agave2", CostScore: 12.5, PredictedLatencyMS: 37.5},
+		},
+		RecommendationDetails: ml.NodePrediction{NodeID: "agave2", PredictedLatencyMS: 37.5},
+		Decision: &ml.DecisionBreakdown{
+			Source:            "hybrid",
+			MLRecommendedNode: "agave1",
+			RecentAverages:    map[string]float64{"agave1": 41.2},
+		},
+	}
+	h.exportDecision(r, prediction, decisionOutcome{Success: true, Status: 200, LatencyMS: 42.1})
+
+	select {
+	case event := <-h.decisions["file"].events:
+		events = append(events, event)
+	default:
+		t.Fatal("no event queued")
+	}
+
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(events[0], &decoded); err != nil {
+		t.Fatalf("event is not JSON: %v", err)
+	}
+	var keys []string
+	for key := range decoded {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	want := []string{"chosen_node", "method", "ml_recommended_node", "outcome", "predicted_latency_ms",
+		"predictions", "recent_avg_latency_ms", "request_id", "source", "timestamp"}
+	if !reflect.DeepEqual(keys, want) {
+		t.Fatalf("event keys = %v, want %v", keys, want)
+	}
+
+	checks := map[string]interface{}{
+		"request_id":           "req-1",
+		"method":               "getBalance",
+		"source":               "hybrid",
+		"chosen_node":          "agave2",
+		"ml_recommended_node":  "agave1",
+		"predicted_latency_ms": 37.5,
+	}
+	for key, value := range checks {
+		if decoded[key] != value {
+			t.Errorf("%s = %v, want %v", key, decoded[key], value)
+		}
+	}
+	outcome := decoded["outcome"].(map[string]interface{})
+	if outcome["success"] != true || outcome["status"] != float64(200) || outcome["latency_ms"] != 42.1 {
+		t.Errorf("outcome = %v", outcome)
+	}
+	if _, err := time.Parse(time.RFC3339Nano, decoded["timestamp"].(string)); err != nil {
+		t.Errorf("timestamp: %v", err)
+	}
+}
+
+func TestDecisionExportToKafkaAndFile(t *testing.T) {
+	batches := make(chan []byte, 1)
+	cfg := testConfig(t)
+	cfg.KafkaBrokers = []string{stubBroker(t, "decisions", batches)}
+	cfg.KafkaTopic = "decisions"
+	cfg.KafkaFlushInterval = 10 * time.Millisecond
+	cfg.DecisionLogFile = filepath.Join(t.TempDir(), "decisions.log")
+	cfg.DecisionLogFormat = "influx"
+	cfg.DecisionFlushInterval = 10 * time.Millisecond
+	h := newTestHandler(t, cfg, ml.Options{})
+	if len(h.decisions) != 2 {
+		t.Fatalf("exporters = %v, want kafka and file", h.decisions)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	for _, exporter := range h.decisions {
+		go exporter.run(ctx)
+	}
+
+	prediction := &ml.PredictionResponse{RecommendedNode: "agave1"}
+	h.exportDecision(newRPCRequest("getSlot"), prediction, decisionOutcome{Success: true, LatencyMS: 5})
+
+	select {
+	case batch := <-batches:
+		if !strings.Contains(string(batch), "vigil_decision,node=agave1") {
+			t.Errorf("kafka batch does not hold the decision line: %q", batch)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("nothing produced to kafka")
+	}
+	waitFor(t, func() bool { return h.decisions["file"].published.Load() == 1 })
+	data, err := os.ReadFile(cfg.DecisionLogFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(data), "vigil_decision,node=agave1 ") || !strings.HasSuffix(string(data), "\n") {
+		t.Errorf("decision log = %q", data)
+	}
+}
+
+// waitFor polls cond for up to a second
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met in time")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
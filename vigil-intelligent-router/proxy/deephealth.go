@@ -0,0 +1,110 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// deepHealthBody is the probe routed by DEEP_HEALTH_CHECK
+var deepHealthBody = []byte(`{"jsonrpc":"2.0","id":"vigil-deep-health","method":"getHealth"}`)
+
+// deepHealth caches the last probe so /health polling can't turn into a
+// stream of real RPC traffic
+type deepHealth struct {
+	mutex     sync.Mutex
+	result    map[string]interface{}
+	ok        bool
+	checkedAt time.Time
+}
+
+type probeContextKey struct{}
+
+// probeTarget is filled in with the node a probe was routed to
+type probeTarget struct {
+	node string
+}
+
+// recordProbeTarget notes where a deep health probe was sent
+func (h *Handler) recordProbeTarget(r *http.Request, targetURL string) {
+	probe, _ := r.Context().Value(probeContextKey{}).(*probeTarget)
+	if probe == nil {
+		return
+	}
+	probe.node = h.nodeMetricName(targetURL)
+	if targetURL == h.config.LastResortNodeURL {
+		probe.node = "last_resort"
+	}
+}
+
+// DeepHealth routes a getHealth call through the normal routing path and
+// reports whether a node answered it successfully, and which one. Results are
+// reused for DEEP_HEALTH_CHECK_INTERVAL_SECONDS; concurrent callers wait for
+// the probe in progress rather than starting their own.
+func (h *Handler) DeepHealth() (map[string]interface{}, bool) {
+	h.deepHealth.mutex.Lock()
+	defer h.deepHealth.mutex.Unlock()
+
+	if h.deepHealth.result != nil && time.Since(h.deepHealth.checkedAt) < h.config.DeepHealthCheckInterval {
+		return h.deepHealth.result, h.deepHealth.ok
+	}
+
+	result, ok := h.probeRouting()
+	h.deepHealth.result, h.deepHealth.ok, h.deepHealth.checkedAt = result, ok, time.Now()
+	if !ok {
+		h.logger.Warn("Deep health check failed", zap.Any("result", result))
+	}
+	return result, ok
+}
+
+// probeRouting sends one getHealth through ServeHTTP
+func (h *Handler) probeRouting() (map[string]interface{}, bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), h.config.RequestTimeout)
+	defer cancel()
+
+	probe := &probeTarget{}
+	req, err := http.NewRequestWithContext(context.WithValue(ctx, probeContextKey{}, probe),
+		http.MethodPost, h.config.RPCPath, bytes.NewReader(deepHealthBody))
+	if err != nil {
+		return map[string]interface{}{"error": err.Error()}, false
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Request-ID", "vigil-deep-health")
+
+	start := time.Now()
+	recorder := newResponseBuffer()
+	h.ServeHTTP(recorder, req)
+	if recorder.status == 0 {
+		recorder.status = http.StatusOK
+	}
+
+	result := map[string]interface{}{
+		"node":       probe.node,
+		"status":     recorder.status,
+		"latency_ms": time.Since(start).Milliseconds(),
+		"checked_at": time.Now().UTC().Format(time.RFC3339),
+	}
+
+	var response struct {
+		Result json.RawMessage `json:"result"`
+		Error  *rpcError       `json:"error"`
+	}
+	switch {
+	case recorder.status != http.StatusOK:
+		result["error"] = "probe answered with HTTP status " + http.StatusText(recorder.status)
+	case json.Unmarshal(recorder.body.Bytes(), &response) != nil:
+		result["error"] = "probe response is not JSON-RPC"
+	case response.Error != nil:
+		result["error"] = response.Error.Message
+	case string(response.Result) != `"ok"`:
+		result["error"] = "node reported unhealthy: " + string(response.Result)
+	default:
+		return result, true
+	}
+	return result, false
+}
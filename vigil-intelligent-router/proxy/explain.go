@@ -0,0 +1,112 @@
+package proxy
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/project-vigil/vigil-intelligent-router/ml"
+)
+
+// explainResponse is returned instead of forwarding when explain mode is
+// requested. RecommendedNode is where the request would go after every
+// handler step; Decision.MLRecommendedNode is the scored pick before them.
+type explainResponse struct {
+	RecommendedNode string                `json:"recommended_node,omitempty"`
+	PinnedNode      string                `json:"pinned_node,omitempty"`
+	SignatureNode   string                `json:"signature_node,omitempty"`
+	Explanation     string                `json:"explanation,omitempty"`
+	Decision        *ml.DecisionBreakdown `json:"decision,omitempty"`
+	Adjustments     []explainStep         `json:"adjustments,omitempty"`
+	AllPredictions  []ml.NodePrediction   `json:"all_predictions,omitempty"`
+	Error           string                `json:"error,omitempty"`
+	FallbackURLUsed bool                  `json:"fallback_url_used"`
+}
+
+// explainStep is a handler step that changed or ended the routing decision
+// after scoring
+type explainStep struct {
+	Step   string `json:"step"`
+	From   string `json:"from,omitempty"`
+	To     string `json:"to,omitempty"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// routingTrail collects the explain steps for one request. A nil trail, used
+// outside explain mode, records nothing.
+type routingTrail struct {
+	node  string
+	steps []explainStep
+}
+
+func newRoutingTrail(explain bool) *routingTrail {
+	if !explain {
+		return nil
+	}
+	return &routingTrail{}
+}
+
+// start records the scored pick the later steps are measured against
+func (t *routingTrail) start(prediction *ml.PredictionResponse) {
+	if t == nil {
+		return
+	}
+	t.node = prediction.RecommendedNode
+}
+
+// note records step if it moved the request to another node
+func (t *routingTrail) note(step string, prediction *ml.PredictionResponse) {
+	if t == nil || prediction.RecommendedNode == t.node {
+		return
+	}
+	t.steps = append(t.steps, explainStep{Step: step, From: t.node, To: prediction.RecommendedNode})
+	t.node = prediction.RecommendedNode
+}
+
+// add records a step that sends the request outside the scored nodes or
+// refuses it
+func (t *routingTrail) add(step, to, detail string) {
+	if t == nil {
+		return
+	}
+	t.steps = append(t.steps, explainStep{Step: step, From: t.node, To: to, Detail: detail})
+}
+
+// explainRequested reports whether the client asked for the routing breakdown
+// via the X-Vigil-Explain header or the explain query parameter
+func explainRequested(r *http.Request) bool {
+	value := r.Header.Get("X-Vigil-Explain")
+	if value == "" {
+		value = r.URL.Query().Get("explain")
+	}
+	explain, _ := strconv.ParseBool(value)
+	return explain
+}
+
+// writeExplain replies with the full decision breakdown for the request,
+// including the steps in trail that adjusted it after scoring
+func (h *Handler) writeExplain(w http.ResponseWriter, rpcReqs []rpcRequest, prediction *ml.PredictionResponse, predictionErr error, trail *routingTrail) {
+	resp := explainResponse{Adjustments: trail.steps}
+	if nodeID, ok := h.methodOverride(rpcReqs); ok {
+		resp.PinnedNode = nodeID
+	}
+	if nodeID, ok := h.signatureNode(rpcReqs); ok {
+		resp.SignatureNode = nodeID
+	}
+
+	if predictionErr != nil {
+		resp.Error = predictionErr.Error()
+		resp.FallbackURLUsed = h.config.FallbackEnabled
+	} else {
+		resp.RecommendedNode = prediction.RecommendedNode
+		resp.Explanation = prediction.Explanation
+		resp.Decision = prediction.Decision
+		resp.AllPredictions = prediction.AllPredictions
+		if _, err := h.mlClient.GetRecommendedNodeURL(prediction.RecommendedNode); err != nil {
+			resp.FallbackURLUsed = h.config.FallbackEnabled
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,87 @@
+package proxy
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/project-vigil/vigil-intelligent-router/ml"
+)
+
+// untouchedUpstream fails the test if the router forwards anything to it
+func untouchedUpstream(t *testing.T) string {
+	t.Helper()
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("explain request forwarded to %s", r.Host)
+	}))
+	t.Cleanup(upstream.Close)
+	return upstream.URL
+}
+
+func TestExplainReportsHandlerAdjustments(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.NodeURLMap = map[string]string{"a": untouchedUpstream(t), "b": untouchedUpstream(t)}
+	cfg.CanaryNode = "b"
+	cfg.CanaryPercent = 100
+	cfg.NodeMaxInflight = map[string]int{"b": 1}
+	backendStub(t, cfg, []ml.MetricData{recentMetric("a", 10), recentMetric("b", 50)}, ml.PredictionResponse{
+		RecommendedNode: "a",
+		AllPredictions: []ml.NodePrediction{
+			{NodeID: "a", PredictedLatencyMS: 10},
+			{NodeID: "b", PredictedLatencyMS: 50},
+		},
+	})
+	h := newTestHandler(t, cfg, ml.Options{})
+
+	// The canary is chosen but already full, so the request spills back to a
+	if !h.acquireNode("b") {
+		t.Fatal("could not take b's slot")
+	}
+	defer h.releaseNode("b")
+
+	r := newRPCRequest("getSlot")
+	r.Header.Set("X-Vigil-Explain", "true")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, r)
+
+	var resp struct {
+		RecommendedNode string        `json:"recommended_node"`
+		Adjustments     []explainStep `json:"adjustments"`
+		Decision        struct {
+			MLRecommendedNode string                            `json:"ml_recommended_node"`
+			RecentAverages    map[string]float64                `json:"recent_averages"`
+			Nodes             map[string]map[string]interface{} `json:"nodes"`
+		} `json:"decision"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("explain body %q: %v", rec.Body.String(), err)
+	}
+
+	if resp.RecommendedNode != "a" || resp.Decision.MLRecommendedNode != "a" {
+		t.Errorf("recommended %q (scored %q), want a", resp.RecommendedNode, resp.Decision.MLRecommendedNode)
+	}
+	want := []explainStep{{Step: "canary", From: "a", To: "b"}, {Step: "spill_over", From: "b", To: "a"}}
+	if len(resp.Adjustments) != len(want) {
+		t.Fatalf("adjustments = %+v, want %+v", resp.Adjustments, want)
+	}
+	for i := range want {
+		if resp.Adjustments[i] != want[i] {
+			t.Errorf("adjustment %d = %+v, want %+v", i, resp.Adjustments[i], want[i])
+		}
+	}
+
+	if len(resp.Decision.RecentAverages) != 2 {
+		t.Errorf("recent_averages = %v, want both nodes", resp.Decision.RecentAverages)
+	}
+	for _, term := range []string{"prediction_term", "recent_term", "failure_penalty", "anomaly_multiplier", "calibration_offset", "hybrid_score"} {
+		if _, ok := resp.Decision.Nodes["a"][term]; !ok {
+			t.Errorf("score breakdown for a has no %s: %v", term, resp.Decision.Nodes["a"])
+		}
+	}
+
+	// Only the slot held by the test remains taken
+	if counts := h.inflightCounts(); counts["a"] != 0 || counts["b"] != 1 {
+		t.Errorf("in-flight after explain = %v", counts)
+	}
+}
@@ -0,0 +1,61 @@
+package proxy
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/project-vigil/vigil-intelligent-router/config"
+)
+
+// otherExplanations counts explanations no category matches
+const otherExplanations = "other"
+
+// explanationTally counts ML explanations by configured category
+type explanationTally struct {
+	mutex  sync.Mutex
+	names  []string // category names, in match order
+	counts map[string]int64
+}
+
+// newExplanationTally tries categories in name order so classification is
+// deterministic when several patterns match
+func newExplanationTally(cfg *config.Config) *explanationTally {
+	names := make([]string, 0, len(cfg.ExplanationCategories))
+	for name := range cfg.ExplanationCategories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return &explanationTally{names: names, counts: make(map[string]int64)}
+}
+
+// classifyExplanation returns the first category whose pattern matches explanation
+func (h *Handler) classifyExplanation(explanation string) string {
+	for _, name := range h.explanations.names {
+		if h.config.ExplanationCategories[name].MatchString(explanation) {
+			return name
+		}
+	}
+	return otherExplanations
+}
+
+// recordExplanation tallies one recommendation's explanation
+func (h *Handler) recordExplanation(explanation string) {
+	if len(h.explanations.names) == 0 {
+		return
+	}
+	category := h.classifyExplanation(explanation)
+	h.explanations.mutex.Lock()
+	h.explanations.counts[category]++
+	h.explanations.mutex.Unlock()
+}
+
+// explanationReport returns a copy of the tally for /stats
+func (h *Handler) explanationReport() map[string]int64 {
+	h.explanations.mutex.Lock()
+	defer h.explanations.mutex.Unlock()
+	report := make(map[string]int64, len(h.explanations.counts))
+	for category, count := range h.explanations.counts {
+		report[category] = count
+	}
+	return report
+}
@@ -0,0 +1,23 @@
+package proxy
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/project-vigil/vigil-intelligent-router/config"
+)
+
+// IsGetRPC reports whether r is a JSON-RPC call carried in the ?request= query
+// parameter, as sent by some legacy tools. Only honored when ALLOW_GET_RPC is set.
+func IsGetRPC(cfg *config.Config, r *http.Request) bool {
+	return cfg.AllowGetRPC && r.Method == http.MethodGet && r.URL.Query().Has("request")
+}
+
+// readRPCPayload returns the JSON-RPC payload from the query string for GET
+// requests and from the body otherwise
+func readRPCPayload(r *http.Request) ([]byte, error) {
+	if r.Method == http.MethodGet {
+		return []byte(r.URL.Query().Get("request")), nil
+	}
+	return io.ReadAll(r.Body)
+}
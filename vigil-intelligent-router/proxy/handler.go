@@ -2,10 +2,17 @@ package proxy
 
 import (
 	"bytes"
+	"compress/gzip"
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/project-vigil/vigil-intelligent-router/config"
@@ -15,26 +22,102 @@ import (
 
 // Handler handles the intelligent routing of RPC requests
 type Handler struct {
-	mlClient   *ml.Client
-	httpClient *http.Client
-	config     *config.Config
-	logger     *zap.Logger
+	mlClient    *ml.Client
+	httpClient  *http.Client
+	nodeClients map[string]*http.Client // upstream URL -> client with node-specific TLS settings
+	nodeAuth    map[string]basicAuth    // upstream URL -> basic-auth credentials
+	config      *config.Config
+	logger      *zap.Logger
+	warnings    *ml.RateLimitedLogger // for warnings repeated per request
+
+	// Responses whose JSON-RPC id did not match the request
+	idMismatches atomic.Int64
+
+	// Forwarded requests that had to open a new upstream connection
+	coldRequests atomic.Int64
+
+	// Last DEEP_HEALTH_CHECK probe result
+	deepHealth deepHealth
+
+	// Startup warm-up tracking
+	startedAt  time.Time
+	warmupDone atomic.Bool
+
+	// Last node chosen, for routing hysteresis
+	lastNodeMutex sync.Mutex
+	lastNode      string
+
+	// In-flight requests per node
+	inflight map[string]*atomic.Int64
+
+	// Requests being handled, and those turned away, under MAX_GLOBAL_INFLIGHT
+	globalInflight atomic.Int64
+	globalRejected atomic.Int64
+
+	// Tenant policies, keyed by lower-cased tenant name
+	tenants map[string]*tenant
+
+	// Requests answered per node, for cost estimates
+	costs *costTally
+
+	// Last reported software version per node
+	versions nodeVersions
+
+	// Hedged request winners
+	hedging hedgeStats
+
+	// Canary traffic split and evaluation
+	canaryCounter atomic.Int64
+	canary        canaryStats
+
+	// Shadow copies sent to SHADOW_FORWARD_NODE and how it handled them
+	shadowForward shadowForwardStats
+
+	// Optional StatsD metrics push (nil when disabled)
+	statsd *statsdSink
+
+	// Learned per-method node latencies
+	affinity methodAffinity
+
+	// Append-only routing audit trail (nil when disabled)
+	audit *auditLog
+
+	// ML explanations tallied by category
+	explanations *explanationTally
+
+	// Last good read responses for STALE_ON_ERROR (nil when disabled)
+	stale *staleCache
+
+	// Node each recently submitted transaction went to (nil when disabled)
+	signatures *signatureAffinity
+
+	// Routing decision export (nil when disabled)
+	decisions map[string]*decisionExporter // by sink
 }
 
 // NewHandler creates a new proxy handler
 func NewHandler(mlClient *ml.Client, cfg *config.Config, logger *zap.Logger) *Handler {
+	httpClient, nodeClients := buildUpstreamClients(cfg, logger)
 	return &Handler{
-		mlClient: mlClient,
-		httpClient: &http.Client{
-			Timeout: cfg.RequestTimeout,
-			Transport: &http.Transport{
-				MaxIdleConns:        100,
-				MaxIdleConnsPerHost: 20,
-				IdleConnTimeout:     90 * time.Second,
-			},
-		},
-		config: cfg,
-		logger: logger,
+		mlClient:     mlClient,
+		httpClient:   httpClient,
+		nodeClients:  nodeClients,
+		nodeAuth:     buildNodeAuth(cfg),
+		config:       cfg,
+		startedAt:    time.Now(),
+		inflight:     newInflightCounters(cfg.NodeURLMap),
+		tenants:      newTenants(cfg, mlClient.ScoringWeights()),
+		costs:        newCostTally(cfg.NodeURLMap),
+		hedging:      hedgeStats{wins: make(map[string]int64)},
+		versions:     nodeVersions{versions: make(map[string]string)},
+		statsd:       newStatsdSink(cfg, logger),
+		affinity:     methodAffinity{stats: make(map[string]map[string]*methodLatency)},
+		explanations: newExplanationTally(cfg),
+		stale:        newStaleCache(cfg),
+		signatures:   newSignatureAffinity(cfg),
+		decisions:    newDecisionExports(cfg, logger),
+		logger:       logger,
+		warnings:     ml.NewRateLimitedLogger(logger, cfg.LogRateLimitInterval),
 	}
 }
 
@@ -44,17 +127,33 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	
 	// Enable CORS for browser-based clients
 	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
-	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+	if h.config.AllowGetRPC {
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+	} else {
+		w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+	}
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Vigil-Explain, X-Vigil-Tenant, X-API-Key")
+	w.Header().Set("Access-Control-Expose-Headers", "X-Vigil-Decision-Age-Ms, X-Vigil-Stale")
 	
 	// Handle preflight OPTIONS request
 	if r.Method == http.MethodOptions {
-		w.WriteHeader(http.StatusOK)
+		WritePreflight(w, r, h.config.CORSMaxAge)
 		return
 	}
+
+	// Shed load before doing any work once the router is saturated
+	if !h.acquireGlobal() {
+		h.serveOverloaded(w)
+		return
+	}
+	defer h.globalInflight.Add(-1)
+	
+	// Every request past preflight gets an audit entry
+	w, r, finishAudit := h.startAudit(w, r)
+	defer finishAudit()
 	
-	// Only accept POST requests
-	if r.Method != http.MethodPost {
+	// Only accept POST requests, plus GET ?request= when enabled
+	if r.Method != http.MethodPost && !IsGetRPC(h.config, r) {
 		h.logger.Warn("Invalid request method",
 			zap.String("method", r.Method),
 			zap.String("path", r.URL.Path))
@@ -62,8 +161,43 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Apply the caller's tenant policy
+	t, authenticated := h.resolveTenant(r)
+	if !authenticated {
+		h.logger.Warn("Tenant API key missing or invalid",
+			zap.String("tenant", r.Header.Get("X-Vigil-Tenant")),
+			zap.String("remote_addr", ClientIP(h.config, r)))
+		writeRPCError(w, http.StatusUnauthorized, rpcInvalidRequest, "invalid tenant API key", nil)
+		return
+	}
+	if t != nil {
+		if !t.allow() {
+			h.logger.Warn("Tenant rate limit exceeded",
+				zap.String("tenant", t.name),
+				zap.String("remote_addr", ClientIP(h.config, r)))
+			writeRPCError(w, http.StatusTooManyRequests, rpcLimitExceeded, "rate limit exceeded", nil)
+			return
+		}
+		r = r.WithContext(withTenant(r.Context(), t))
+	}
+
+	// One deadline covers every phase so a slow ML query leaves less time upstream
+	if h.config.TotalRequestBudget > 0 {
+		budgetCtx, cancelBudget := context.WithDeadline(r.Context(), startTime.Add(h.config.TotalRequestBudget))
+		defer cancelBudget()
+		r = r.WithContext(budgetCtx)
+	}
+
+	// Turn away binary garbage before reading the whole body
+	if !bodyLooksLikeJSON(r) {
+		h.warnings.Warn("Rejecting non-JSON request body",
+			zap.String("remote_addr", ClientIP(h.config, r)))
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
 	// Read the original request body
-	bodyBytes, err := io.ReadAll(r.Body)
+	bodyBytes, err := readRPCPayload(r)
 	if err != nil {
 		h.logger.Error("Failed to read request body", zap.Error(err))
 		http.Error(w, "Failed to read request body", http.StatusBadRequest)
@@ -71,6 +205,13 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 	defer r.Body.Close()
 
+	// An empty body is the most common client mistake, so call it out explicitly
+	if len(bytes.TrimSpace(bodyBytes)) == 0 {
+		h.logger.Warn("Empty request body", zap.String("remote_addr", ClientIP(h.config, r)))
+		writeRPCError(w, http.StatusBadRequest, rpcInvalidRequest, "empty request body; expected a JSON-RPC payload", nil)
+		return
+	}
+
 	// Validate JSON-RPC format
 	if !json.Valid(bodyBytes) {
 		h.logger.Warn("Invalid JSON in request body")
@@ -80,28 +221,174 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 	h.logger.Info("Received RPC request",
 		zap.Int("body_size", len(bodyBytes)),
-		zap.String("remote_addr", r.RemoteAddr))
+		zap.String("remote_addr", ClientIP(h.config, r)))
 
-	// Query ML service for best node recommendation
-	ctx, cancel := context.WithTimeout(context.Background(), h.config.MLQueryTimeout)
+	rpcReqs, batch, err := parseRPCRequests(bodyBytes)
+	auditMethods(r, rpcReqs)
+	if err != nil {
+		h.logger.Debug("Request is not a JSON-RPC object or batch", zap.Error(err))
+	} else if allNotifications(rpcReqs) {
+		// Forwarded like any other call; the upstream may answer with an empty body
+		h.logger.Debug("Forwarding JSON-RPC notification",
+			zap.Strings("methods", rpcMethods(rpcReqs)))
+	}
+
+	// Enforce the method allow/deny lists before any routing
+	filtered, rejected, ok := h.enforceMethodPolicy(w, rpcReqs, batch, err)
+	if !ok {
+		return
+	}
+	if filtered != nil {
+		merger := newBatchMergeWriter(w)
+		defer merger.finish(rejected)
+		w = merger
+		bodyBytes = filtered
+		rpcReqs, _, _ = parseRPCRequests(filtered)
+	}
+
+	if len(rpcReqs) == 1 && !batch {
+		r = r.WithContext(withRPCMethod(r.Context(), rpcReqs[0].Method))
+	}
+
+	// Keep good read responses to fall back on if every upstream fails
+	w, finishStale := h.captureForStale(w, rpcReqs, err, bodyBytes)
+	defer finishStale()
+
+	explain := explainRequested(r)
+
+	// Copy a sample of reads to the node under evaluation
+	if !explain {
+		h.startShadowForward(r, rpcReqs, err, bodyBytes)
+	}
+
+	// Method pins bypass ML selection entirely
+	if nodeID, ok := h.methodOverride(rpcReqs); ok && !explain {
+		if h.routeToPinnedNode(w, r, nodeID, bodyBytes, startTime) {
+			h.rememberSignatures(rpcReqs, nodeID)
+			return
+		}
+	}
+
+	// Status queries follow their transaction to the node it was sent to
+	if nodeID, ok := h.signatureNode(rpcReqs); ok && !explain {
+		if h.routeToPinnedNode(w, r, nodeID, bodyBytes, startTime) {
+			return
+		}
+	}
+
+	// Query ML service for best node recommendation, within the request budget
+	// when one is set
+	mlParent := context.Background()
+	if h.config.TotalRequestBudget > 0 {
+		mlParent = r.Context()
+	}
+	ctx, cancel := context.WithTimeout(h.mlContext(mlParent, r), h.config.MLQueryTimeout)
 	defer cancel()
 
 	prediction, err := h.mlClient.GetRecommendation(ctx)
+	trail := newRoutingTrail(explain)
+
+	if err == nil {
+		h.recordExplanation(prediction.Explanation)
+	}
+
+	// Keep methods away from nodes too old to support them
+	if err == nil {
+		trail.start(prediction)
+		err = h.mlClient.ExcludeNodes(prediction, h.versionExclusions(rpcReqs))
+		if err == nil {
+			trail.note("version_exclusion", prediction)
+		}
+	}
+
+	// Explain mode reports the decision instead of forwarding; without a
+	// prediction there is nothing further to adjust
+	if explain && err != nil {
+		h.writeExplain(w, rpcReqs, prediction, err, trail)
+		return
+	}
+
 	if err != nil {
-		h.logger.Error("ML service query failed", zap.Error(err))
+		h.warnings.Error("ML service query failed", zap.Error(err))
+		h.statsd.incr("ml_errors", 1)
+		
+		// Every node was rejected; report why instead of a generic error
+		var noNodes *ml.NoEligibleNodesError
+		errors.As(err, &noNodes)
+		
+		// ALL_ANOMALOUS_POLICY=degraded refuses rather than guessing
+		if errors.Is(err, ml.ErrAllNodesAnomalous) {
+			h.serveAllAnomalous(w)
+			return
+		}
 		
 		// Use fallback if enabled
 		if h.config.FallbackEnabled {
 			h.logger.Info("Using fallback RPC",
-				zap.String("url", h.config.FallbackRPCURL))
-			h.forwardRequest(w, r, h.config.FallbackRPCURL, bodyBytes, startTime)
+				zap.String("url", h.fallbackURL(r)))
+			if err := h.forwardRequest(w, r, h.fallbackURL(r), bodyBytes, startTime); err == nil {
+				return
+			}
+			h.failoverBackoff(r, startTime)
+			if noNodes != nil {
+				h.serveNoViableNode(w, r, bodyBytes, startTime, noNodes.Reasons)
+				return
+			}
+			h.serveLastResort(w, r, bodyBytes, startTime, "Failed to reach RPC node", http.StatusBadGateway)
 			return
 		}
 		
-		http.Error(w, "ML service unavailable and no fallback configured", http.StatusServiceUnavailable)
+		if noNodes != nil {
+			h.serveNoViableNode(w, r, bodyBytes, startTime, noNodes.Reasons)
+			return
+		}
+		h.serveLastResort(w, r, bodyBytes, startTime, "ML service unavailable and no fallback configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	// Too few healthy nodes left to serve reliably
+	if healthy, below := h.belowMinHealthy(); below {
+		if explain {
+			trail.add("min_healthy_nodes", "", fmt.Sprintf("refused: %d healthy nodes, %d required", healthy, h.config.MinHealthyNodes))
+			h.writeExplain(w, rpcReqs, prediction, nil, trail)
+			return
+		}
+		h.serveInsufficientHealthy(w, healthy)
 		return
 	}
 
+	// Prefer stability over optimization until we have baseline samples
+	if h.inWarmup() {
+		if explain {
+			if nodeID, url, ok := h.warmupTarget(r); ok {
+				trail.add("warmup", nodeID, url)
+				h.writeExplain(w, rpcReqs, prediction, nil, trail)
+				return
+			}
+		} else if h.routeWarmup(w, r, bodyBytes, startTime, prediction) {
+			return
+		}
+	}
+
+	// Priority routing is deterministic, so score-based adjustments don't apply
+	if h.config.RoutingStrategy != ml.StrategyPriority {
+		// Stay within the preferred node pool unless it is degraded
+		h.applyNodePools(prediction)
+		trail.note("node_pools", prediction)
+
+		// Avoid flapping between closely scored nodes
+		h.applyStickiness(prediction)
+		trail.note("stickiness", prediction)
+
+		// Favor the node that has proven fastest for this method
+		h.applyMethodAffinity(r, prediction)
+		trail.note("method_affinity", prediction)
+	}
+
+	// Divert the configured share of traffic to the canary node
+	h.applyCanary(prediction)
+	trail.note("canary", prediction)
+
 	// Get the target RPC URL from the recommended node
 	targetURL, err := h.mlClient.GetRecommendedNodeURL(prediction.RecommendedNode)
 	if err != nil {
@@ -109,13 +396,27 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			zap.String("node_id", prediction.RecommendedNode),
 			zap.Error(err))
 		
-		// Use fallback
-		if h.config.FallbackEnabled {
-			targetURL = h.config.FallbackRPCURL
+		// Prefer the next-best node the ML scored that we can actually reach
+		if nodeID, url, ok := h.nextResolvableNode(prediction); ok {
+			h.logger.Info("Using next-best resolvable node",
+				zap.String("unmapped_node", prediction.RecommendedNode),
+				zap.String("node", nodeID))
+			prediction.SelectNode(nodeID)
+			targetURL = url
+			trail.note("unmapped_node", prediction)
+		} else if h.config.FallbackEnabled {
+			// Use fallback
+			targetURL = h.fallbackURL(r)
 			h.logger.Info("Using fallback due to URL resolution failure",
 				zap.String("url", targetURL))
+			trail.add("unmapped_node", "", targetURL)
 		} else {
-			http.Error(w, "Failed to resolve target node", http.StatusInternalServerError)
+			if explain {
+				trail.add("unmapped_node", "", "refused: no viable node")
+				h.writeExplain(w, rpcReqs, prediction, nil, trail)
+				return
+			}
+			h.serveNoViableNode(w, r, bodyBytes, startTime, h.mlClient.RejectionReasons(prediction))
 			return
 		}
 	}
@@ -127,118 +428,474 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		zap.Float64("predicted_latency", prediction.RecommendationDetails.PredictedLatencyMS),
 		zap.Float64("cost_score", prediction.RecommendationDetails.CostScore))
 
-	// Forward the request with prediction details for calibration
-	h.forwardRequestWithCalibration(w, r, targetURL, bodyBytes, startTime, prediction)
-}
+	// Respect per-node concurrency limits, spilling over to the next-best node
+	if !h.acquireNode(prediction.RecommendedNode) {
+		h.logger.Info("Node at in-flight capacity",
+			zap.String("node", prediction.RecommendedNode),
+			zap.Int("limit", h.config.NodeMaxInflight[prediction.RecommendedNode]))
+		nodeID, url, ok := h.nextAvailableNode(prediction)
+		if !ok && explain {
+			trail.add("spill_over", "", "all nodes at in-flight capacity")
+			h.writeExplain(w, rpcReqs, prediction, nil, trail)
+			return
+		}
+		if !ok {
+			h.warnings.Warn("All nodes at in-flight capacity")
+			if h.config.FallbackEnabled {
+				if err := h.forwardRequest(w, r, h.fallbackURL(r), bodyBytes, startTime); err == nil {
+					return
+				}
+				h.failoverBackoff(r, startTime)
+			}
+			h.serveLastResort(w, r, bodyBytes, startTime, "All nodes at capacity", http.StatusServiceUnavailable)
+			return
+		}
+		prediction.SelectNode(nodeID)
+		targetURL = url
+		trail.note("spill_over", prediction)
+	}
+	chosenNode := prediction.RecommendedNode
+	defer h.releaseNode(chosenNode)
+	prediction.MarkDecided()
 
-// forwardRequest forwards the RPC request to the target node and streams the response
-func (h *Handler) forwardRequest(w http.ResponseWriter, originalReq *http.Request, targetURL string, bodyBytes []byte, startTime time.Time) {
-	// Create new request to target RPC
-	req, err := http.NewRequest(http.MethodPost, targetURL, bytes.NewReader(bodyBytes))
-	if err != nil {
-		h.logger.Error("Failed to create forwarding request", zap.Error(err))
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	// Explain mode reports the decision once every step has had its say
+	if explain {
+		h.writeExplain(w, rpcReqs, prediction, nil, trail)
 		return
 	}
 
-	// Copy essential headers
-	req.Header.Set("Content-Type", "application/json")
-	if userAgent := originalReq.Header.Get("User-Agent"); userAgent != "" {
-		req.Header.Set("User-Agent", userAgent)
+	h.rememberChosenNode(prediction.RecommendedNode)
+
+	// Race latency-critical reads across the top candidates
+	if h.shouldHedge(rpcReqs) {
+		if err := h.forwardHedged(w, r, targetURL, bodyBytes, startTime, prediction); err != nil {
+			h.failoverBackoff(r, startTime)
+			h.serveLastResort(w, r, bodyBytes, startTime, "Failed to reach RPC node", http.StatusBadGateway)
+		}
+		return
 	}
 
-	// Execute the request
-	resp, err := h.httpClient.Do(req)
-	if err != nil {
-		h.logger.Error("Request to target RPC failed",
-			zap.String("target", targetURL),
-			zap.Error(err))
-		http.Error(w, "Failed to reach RPC node", http.StatusBadGateway)
+	// Forward the request with prediction details for calibration, retrying
+	// transient JSON-RPC errors on other nodes when configured
+	forward := h.forwardRequestWithCalibration
+	if h.rpcRetryEnabled(rpcReqs, batch) {
+		forward = h.forwardWithRPCRetry
+	}
+	if err := forward(w, r, targetURL, bodyBytes, startTime, prediction); err != nil {
+		h.failoverBackoff(r, startTime)
+		h.serveLastResort(w, r, bodyBytes, startTime, "Failed to reach RPC node", http.StatusBadGateway)
 		return
 	}
-	defer resp.Body.Close()
+	h.rememberSignatures(rpcReqs, prediction.RecommendedNode)
+}
 
-	// Copy response headers, but skip CORS headers (we set our own)
-	for key, values := range resp.Header {
-		// Skip CORS headers from backend to avoid duplicates
-		if key == "Access-Control-Allow-Origin" ||
-			key == "Access-Control-Allow-Methods" ||
-			key == "Access-Control-Allow-Headers" ||
-			key == "Access-Control-Allow-Credentials" ||
-			key == "Access-Control-Max-Age" ||
-			key == "Access-Control-Expose-Headers" {
+// nextResolvableNode walks the predictions in score order and returns the best
+// eligible node that has a URL mapping
+func (h *Handler) nextResolvableNode(prediction *ml.PredictionResponse) (string, string, bool) {
+	for _, node := range prediction.RankedPredictions() {
+		if node.NodeID == prediction.RecommendedNode {
 			continue
 		}
-		for _, value := range values {
-			w.Header().Add(key, value)
+		if h.mlClient.NodeIneligibleReason(prediction, node.NodeID) != "" {
+			continue
+		}
+		if url, err := h.mlClient.GetRecommendedNodeURL(node.NodeID); err == nil {
+			return node.NodeID, url, true
 		}
 	}
+	return "", "", false
+}
 
-	// Set status code
-	w.WriteHeader(resp.StatusCode)
+// serveLastResort is the final step of the failure cascade. It forwards to the
+// last-resort node when one is configured, otherwise replies with the given error.
+func (h *Handler) serveLastResort(w http.ResponseWriter, r *http.Request, bodyBytes []byte, startTime time.Time, message string, status int) {
+	if h.config.LastResortNodeURL == "" {
+		if !h.serveStale(w, bodyBytes) {
+			http.Error(w, message, status)
+		}
+		return
+	}
 
-	// Stream response body back to client
-	written, err := io.Copy(w, resp.Body)
-	if err != nil {
-		h.logger.Error("Failed to stream response",
-			zap.Error(err),
-			zap.Int64("bytes_written", written))
+	// Reaching this point means ML, metrics fallback and the fallback RPC are all
+	// unusable, so make it stand out in the logs
+	h.warnings.Error("All routing options exhausted, using last-resort node",
+		zap.String("url", h.config.LastResortNodeURL),
+		zap.String("reason", message))
+
+	if err := h.forwardRequest(w, r, h.config.LastResortNodeURL, bodyBytes, startTime); err != nil {
+		if !h.serveStale(w, bodyBytes) {
+			http.Error(w, "Failed to reach RPC node", http.StatusBadGateway)
+		}
+	}
+}
+
+// serveNoViableNode handles a request when selection rejected every node. Without
+// a last-resort node it replies 503 with the reason each node was rejected.
+func (h *Handler) serveNoViableNode(w http.ResponseWriter, r *http.Request, bodyBytes []byte, startTime time.Time, reasons map[string]string) {
+	if h.config.LastResortNodeURL != "" {
+		h.serveLastResort(w, r, bodyBytes, startTime, "No viable node", http.StatusServiceUnavailable)
+		return
+	}
+
+	h.warnings.Error("No viable node for request", zap.Any("rejections", reasons))
+	if h.serveStale(w, bodyBytes) {
+		return
+	}
+	writeJSON(w, http.StatusServiceUnavailable, map[string]interface{}{
+		"error": "no viable node",
+		"nodes": reasons,
+	})
+}
+
+// failoverBackoff pauses for FAILOVER_BACKOFF_MS before the next failover
+// attempt so a broad incident isn't made worse by instant retries. The pause is
+// skipped when there is no further attempt to make or it would leave no time
+// for the attempt within REQUEST_TIMEOUT_SECONDS.
+func (h *Handler) failoverBackoff(r *http.Request, startTime time.Time) {
+	if h.config.LastResortNodeURL == "" {
+		return
+	}
+	h.waitFailoverBackoff(r, startTime)
+}
+
+// waitFailoverBackoff pauses for FAILOVER_BACKOFF_MS unless the pause would leave
+// no time for the next attempt within REQUEST_TIMEOUT_SECONDS
+func (h *Handler) waitFailoverBackoff(r *http.Request, startTime time.Time) {
+	backoff := h.config.FailoverBackoff
+	if backoff <= 0 {
 		return
 	}
+	if h.config.RequestTimeout > 0 && time.Until(startTime.Add(h.config.RequestTimeout)) <= backoff {
+		return
+	}
+
+	timer := time.NewTimer(backoff)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-r.Context().Done():
+	}
+}
+
+// failoverTimeLeft reports whether REQUEST_TIMEOUT_SECONDS leaves room for
+// another failover attempt
+func (h *Handler) failoverTimeLeft(startTime time.Time) bool {
+	return h.config.RequestTimeout <= 0 || time.Since(startTime) < h.config.RequestTimeout
+}
+
+// forwardRequest forwards the RPC request to the target node and streams the response.
+// An error is returned only when the target could not be reached, in which case
+// nothing has been written to w and the caller may try another node.
+func (h *Handler) forwardRequest(w http.ResponseWriter, originalReq *http.Request, targetURL string, bodyBytes []byte, startTime time.Time) error {
+	req, err := h.newUpstreamRequest(originalReq, targetURL, bodyBytes)
+	if err != nil {
+		h.logger.Error("Failed to create forwarding request", zap.Error(err))
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return nil
+	}
+
+	// Execute the request
+	rpcStartTime := time.Now()
+	resp, err := h.clientFor(targetURL).Do(req)
+	if err != nil {
+		if clientGone(originalReq) {
+			h.logger.Info("Client disconnected, upstream request canceled",
+				zap.String("target", targetURL))
+			return nil
+		}
+		h.warnings.Error("Request to target RPC failed",
+			zap.String("target", targetURL),
+			zap.Error(err))
+		h.statsd.incr("upstream_errors."+h.nodeMetricName(targetURL), 1)
+		if budgetExhausted(originalReq) {
+			h.serveBudgetExhausted(w, targetURL)
+			return nil
+		}
+		return err
+	}
+	defer resp.Body.Close()
+	h.costs.recordRequest(targetURL)
+	latency := time.Since(rpcStartTime)
+	h.recordUpstreamMetrics(targetURL, resp.StatusCode, float64(latency.Milliseconds()))
+	h.auditTarget(originalReq, targetURL, nil)
+
+	written, err := h.streamResponse(w, originalReq, resp, bodyBytes, targetURL, latency)
+	if err != nil {
+		h.logStreamError(err, targetURL, written)
+		return nil
+	}
 
 	duration := time.Since(startTime)
+	h.statsd.timing("request_duration_ms", float64(duration.Milliseconds()))
 	h.logger.Info("Request completed",
 		zap.String("target", targetURL),
 		zap.Int("status", resp.StatusCode),
 		zap.Int64("response_size", written),
 		zap.Duration("total_duration", duration))
+	return nil
 }
 
-// forwardRequestWithCalibration forwards the request and records actual latency for calibration
-func (h *Handler) forwardRequestWithCalibration(w http.ResponseWriter, originalReq *http.Request, targetURL string, bodyBytes []byte, startTime time.Time, prediction *ml.PredictionResponse) {
+// forwardRequestWithCalibration forwards the request and records actual latency for calibration.
+// Like forwardRequest, it returns an error only when the target could not be reached.
+func (h *Handler) forwardRequestWithCalibration(w http.ResponseWriter, originalReq *http.Request, targetURL string, bodyBytes []byte, startTime time.Time, prediction *ml.PredictionResponse) error {
 	// Measure the actual latency to the RPC node
 	rpcStartTime := time.Now()
 	
-	// Create new request to target RPC
-	req, err := http.NewRequest(http.MethodPost, targetURL, bytes.NewReader(bodyBytes))
+	req, err := h.newUpstreamRequest(originalReq, targetURL, bodyBytes)
 	if err != nil {
 		h.logger.Error("Failed to create forwarding request", zap.Error(err))
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
-		return
-	}
-
-	// Copy essential headers
-	req.Header.Set("Content-Type", "application/json")
-	if userAgent := originalReq.Header.Get("User-Agent"); userAgent != "" {
-		req.Header.Set("User-Agent", userAgent)
+		return nil
 	}
+	req, cold := traceColdConnection(req)
 
 	// Execute the request
-	resp, err := h.httpClient.Do(req)
+	resp, err := h.clientFor(targetURL).Do(req)
 	if err != nil {
-		h.logger.Error("Request to target RPC failed",
+		// A client hang-up says nothing about the node's reliability
+		if clientGone(originalReq) {
+			h.logger.Info("Client disconnected, upstream request canceled",
+				zap.String("target", targetURL))
+			return nil
+		}
+		h.warnings.Error("Request to target RPC failed",
 			zap.String("target", targetURL),
 			zap.Error(err))
-		http.Error(w, "Failed to reach RPC node", http.StatusBadGateway)
-		return
+		h.mlClient.RecordOutcome(prediction.RecommendedNode, false)
+		h.recordCanaryComparison(prediction.RecommendedNode, 0, false)
+		h.statsd.incr("upstream_errors."+h.nodeMetricName(targetURL), 1)
+		h.exportDecision(originalReq, prediction, decisionOutcome{
+			LatencyMS: float64(time.Since(rpcStartTime).Milliseconds()),
+		})
+		if budgetExhausted(originalReq) {
+			h.serveBudgetExhausted(w, targetURL)
+			return nil
+		}
+		return err
 	}
 	defer resp.Body.Close()
+	h.costs.recordRequest(targetURL)
 	
 	// Calculate actual RPC latency (time to first byte)
 	actualLatencyMS := float64(time.Since(rpcStartTime).Milliseconds())
 	
-	// Record actual latency for calibration
-	h.mlClient.RecordActual(
+	// Connection setup on a cold connection would skew latency tracking
+	coldLatency := h.excludeColdLatency(prediction.RecommendedNode, cold)
+	
+	// Record the outcome so observed reliability feeds back into scoring
+	success := resp.StatusCode >= 200 && resp.StatusCode < 300
+	h.mlClient.RecordOutcome(prediction.RecommendedNode, success)
+	if !coldLatency {
+		h.mlClient.RecordLatency(prediction.RecommendedNode, actualLatencyMS)
+	}
+	if success && !coldLatency {
+		h.recordMethodLatency(originalReq, prediction.RecommendedNode, actualLatencyMS)
+	}
+	h.recordCanaryComparison(prediction.RecommendedNode, actualLatencyMS, success)
+	h.recordUpstreamMetrics(targetURL, resp.StatusCode, actualLatencyMS)
+	h.auditTarget(originalReq, targetURL, &prediction.RecommendationDetails.CostScore)
+	h.exportDecision(originalReq, prediction, decisionOutcome{
+		Success:   success,
+		Status:    resp.StatusCode,
+		LatencyMS: actualLatencyMS,
+	})
+	
+	// Record actual latency for calibration (sampled)
+	recorded := !coldLatency && h.mlClient.RecordActual(
 		prediction.RecommendedNode,
+		prediction.RawPredictedLatency(prediction.RecommendedNode),
 		prediction.RecommendationDetails.PredictedLatencyMS,
 		actualLatencyMS,
 	)
 	
-	h.logger.Info("Calibration recorded",
-		zap.String("node", prediction.RecommendedNode),
-		zap.Float64("predicted_ms", prediction.RecommendationDetails.PredictedLatencyMS),
-		zap.Float64("actual_ms", actualLatencyMS),
-		zap.Float64("error", prediction.RecommendationDetails.PredictedLatencyMS-actualLatencyMS))
+	if recorded {
+		h.logger.Info("Calibration recorded",
+			zap.String("node", prediction.RecommendedNode),
+			zap.Float64("predicted_ms", prediction.RecommendationDetails.PredictedLatencyMS),
+			zap.Float64("actual_ms", actualLatencyMS),
+			zap.Float64("error", prediction.RecommendationDetails.PredictedLatencyMS-actualLatencyMS))
+	}
+
+	setDecisionAge(w, prediction)
+	written, err := h.streamResponse(w, originalReq, resp, bodyBytes, targetURL, time.Duration(actualLatencyMS)*time.Millisecond)
+	if err != nil {
+		h.logStreamError(err, targetURL, written)
+		return nil
+	}
+
+	duration := time.Since(startTime)
+	h.statsd.timing("request_duration_ms", float64(duration.Milliseconds()))
+	h.logger.Info("Request completed",
+		zap.String("target", targetURL),
+		zap.Int("status", resp.StatusCode),
+		zap.Int64("response_size", written),
+		zap.Duration("total_duration", duration),
+		zap.Float64("rpc_latency_ms", actualLatencyMS))
+	return nil
+}
+
+// setDecisionAge reports how old the routing decision was when the request was forwarded
+func setDecisionAge(w http.ResponseWriter, prediction *ml.PredictionResponse) {
+	if prediction.ComputedAt.IsZero() {
+		return
+	}
+	w.Header().Set("X-Vigil-Decision-Age-Ms", strconv.FormatInt(prediction.DecisionAge().Milliseconds(), 10))
+}
+
+// mlContext tags ctx with the request's correlation ID for the ML service and
+// Data Collector calls: the audit ID when auditing, else the client's
+// X-Request-ID, else a fresh one
+func (h *Handler) mlContext(ctx context.Context, r *http.Request) context.Context {
+	if t := tenantFrom(r); t != nil && t.scoring != nil {
+		ctx = ml.WithScoringWeights(ctx, *t.scoring)
+	}
+	if !h.config.PropagateRequestID {
+		return ctx
+	}
+	id := r.Header.Get("X-Request-ID")
+	if entry := auditFrom(r); entry != nil {
+		id = entry.RequestID
+	}
+	if id == "" {
+		id = newAuditID()
+	}
+	return ml.WithRequestID(ctx, id)
+}
+
+// newUpstreamRequest builds the request forwarded to an RPC node
+func (h *Handler) newUpstreamRequest(originalReq *http.Request, targetURL string, bodyBytes []byte) (*http.Request, error) {
+	// GETs keep their method and query string under PRESERVE_METHOD;
+	// everything else is sent as a POST carrying the JSON-RPC payload
+	if h.config.PreserveMethod && originalReq.Method == http.MethodGet {
+		return h.newUpstreamGet(originalReq, targetURL)
+	}
+
+	// Create new request to target RPC, bound to the client's context so a
+	// client disconnect cancels the upstream call
+	req, err := http.NewRequestWithContext(originalReq.Context(), http.MethodPost, targetURL, bytes.NewReader(bodyBytes))
+	if err != nil {
+		return nil, err
+	}
+
+	// Copy essential headers
+	req.Header.Set("Content-Type", "application/json")
+	if userAgent := originalReq.Header.Get("User-Agent"); userAgent != "" {
+		req.Header.Set("User-Agent", userAgent)
+	}
+	h.setUpstreamAuth(req, targetURL)
+
+	return req, nil
+}
+
+// newUpstreamGet builds a GET to the node carrying the client's query string
+func (h *Handler) newUpstreamGet(originalReq *http.Request, targetURL string) (*http.Request, error) {
+	target, err := url.Parse(targetURL)
+	if err != nil {
+		return nil, err
+	}
+	if target.RawQuery == "" {
+		target.RawQuery = originalReq.URL.RawQuery
+	} else if originalReq.URL.RawQuery != "" {
+		target.RawQuery += "&" + originalReq.URL.RawQuery
+	}
+
+	req, err := http.NewRequestWithContext(originalReq.Context(), http.MethodGet, target.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if userAgent := originalReq.Header.Get("User-Agent"); userAgent != "" {
+		req.Header.Set("User-Agent", userAgent)
+	}
+	h.setUpstreamAuth(req, targetURL)
+
+	return req, nil
+}
+
+// clientGone reports whether the client canceled or disconnected
+func clientGone(r *http.Request) bool {
+	return errors.Is(r.Context().Err(), context.Canceled)
+}
+
+// budgetExhausted reports whether TOTAL_REQUEST_BUDGET_SECONDS ran out
+func budgetExhausted(r *http.Request) bool {
+	return errors.Is(r.Context().Err(), context.DeadlineExceeded)
+}
+
+// serveBudgetExhausted replies 504 once the request budget has run out; no
+// further attempt could succeed
+func (h *Handler) serveBudgetExhausted(w http.ResponseWriter, targetURL string) {
+	h.warnings.Warn("Request budget exhausted",
+		zap.String("target", targetURL),
+		zap.Duration("budget", h.config.TotalRequestBudget))
+	http.Error(w, "Request budget exhausted", http.StatusGatewayTimeout)
+}
+
+// flushWriter flushes the response after every write
+type flushWriter struct {
+	w     io.Writer
+	flush func()
+}
+
+func (f flushWriter) Write(p []byte) (int, error) {
+	n, err := f.w.Write(p)
+	if n > 0 {
+		f.flush()
+	}
+	return n, err
+}
+
+// errResponseTooLarge is returned when an upstream response exceeds MaxResponseBytes
+var errResponseTooLarge = errors.New("upstream response exceeds size limit")
+
+// errClientGone is returned when streaming stopped because the client went away
+var errClientGone = errors.New("client disconnected mid-response")
+
+// maxDrainBytes is how much of an abandoned upstream body is read so its
+// connection can go back to the pool; larger remainders close the connection
+const maxDrainBytes = 256 << 10
+
+// clientWriter records whether a copy failed writing to the client, as opposed
+// to reading from the upstream
+type clientWriter struct {
+	w      io.Writer
+	failed bool
+}
+
+func (c *clientWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	if err != nil {
+		c.failed = true
+	}
+	return n, err
+}
+
+// logStreamError logs a failed response copy. Client disconnects are routine;
+// upstream failures are not.
+func (h *Handler) logStreamError(err error, targetURL string, written int64) {
+	if errors.Is(err, errClientGone) {
+		h.logger.Info("Client disconnected mid-response",
+			zap.String("target", targetURL),
+			zap.Int64("bytes_written", written))
+		return
+	}
+	h.warnings.Error("Failed to stream response",
+		zap.String("target", targetURL),
+		zap.Error(err),
+		zap.Int64("bytes_written", written))
+}
+
+// streamResponse copies the upstream response headers, status and body to the
+// client. latency is how long the upstream took to respond.
+func (h *Handler) streamResponse(w http.ResponseWriter, clientReq *http.Request, resp *http.Response, bodyBytes []byte, targetURL string, latency time.Duration) (int64, error) {
+	maxBytes := h.config.MaxResponseBytes
+	if maxBytes > 0 && resp.ContentLength > maxBytes {
+		h.logger.Warn("Rejecting oversized upstream response",
+			zap.String("target", targetURL),
+			zap.Int64("content_length", resp.ContentLength),
+			zap.Int64("max_bytes", maxBytes))
+		http.Error(w, "Upstream response too large", http.StatusBadGateway)
+		return 0, errResponseTooLarge
+	}
 
 	// Copy response headers, but skip CORS headers (we set our own)
 	for key, values := range resp.Header {
@@ -251,34 +908,136 @@ func (h *Handler) forwardRequestWithCalibration(w http.ResponseWriter, originalR
 			key == "Access-Control-Expose-Headers" {
 			continue
 		}
+		// Strip headers that reveal the provider behind the router
+		if !h.responseHeaderPermitted(key) {
+			continue
+		}
 		for _, value := range values {
 			w.Header().Add(key, value)
 		}
 	}
 
+	// Statuses that can't carry a body are passed through without one
+	if !bodyAllowedForStatus(resp.StatusCode) {
+		w.WriteHeader(resp.StatusCode)
+		return 0, nil
+	}
+
+	// Announce upstream trailers; their values are only known after the body
+	for key := range resp.Trailer {
+		if h.responseHeaderPermitted(key) {
+			w.Header().Add("Trailer", key)
+		}
+	}
+
+	// Name the serving node in the body for clients that can't read headers
+	h.annotateResponse(w, resp, bodyBytes, targetURL, latency)
+
+	// Gzip large responses for clients that accept it
+	source, compress := h.prepareCompression(clientReq, resp)
+	if compress {
+		w.Header().Del("Content-Length")
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Header().Add("Vary", "Accept-Encoding")
+	}
+
 	// Set status code
 	w.WriteHeader(resp.StatusCode)
 
-	// Stream response body back to client
-	written, err := io.Copy(w, resp.Body)
+	// Capture a copy of the body while streaming if we need to check its id
+	body := source
+	var capture *cappedBuffer
+	expectedID, checkID := h.expectedResponseID(bodyBytes)
+	if checkID {
+		capture = newCappedBuffer(maxIDCheckBytes)
+		body = io.TeeReader(source, capture)
+	}
+
+	// Bound the copy so a pathological upstream can't stream forever
+	var limited *io.LimitedReader
+	if maxBytes > 0 {
+		limited = &io.LimitedReader{R: body, N: maxBytes}
+		body = limited
+	}
+
+	// Stream response body back to client, flushing each chunk so slow
+	// streams reach the client incrementally
+	client := &clientWriter{w: w}
+	var dst io.Writer = client
+	var gz *gzip.Writer
+	if compress {
+		gz = gzip.NewWriter(client)
+		dst = gz
+	}
+	if flusher, ok := w.(http.Flusher); ok {
+		flush := flusher.Flush
+		if gz != nil {
+			flush = func() {
+				gz.Flush()
+				flusher.Flush()
+			}
+		}
+		dst = flushWriter{w: dst, flush: flush}
+	}
+	written, err := io.Copy(dst, body)
+	if gz != nil {
+		if closeErr := gz.Close(); err == nil {
+			err = closeErr
+		}
+	}
 	if err != nil {
-		h.logger.Error("Failed to stream response",
-			zap.Error(err),
-			zap.Int64("bytes_written", written))
-		return
+		if client.failed || clientGone(clientReq) {
+			// Read what's left of a small body so the upstream connection can be
+			// reused; the caller's Close drops the connection otherwise
+			io.CopyN(io.Discard, source, maxDrainBytes)
+			return written, fmt.Errorf("%w: %v", errClientGone, err)
+		}
+		return written, err
 	}
 
-	duration := time.Since(startTime)
-	h.logger.Info("Request completed",
-		zap.String("target", targetURL),
-		zap.Int("status", resp.StatusCode),
-		zap.Int64("response_size", written),
-		zap.Duration("total_duration", duration),
-		zap.Float64("rpc_latency_ms", actualLatencyMS))
+	if limited != nil && limited.N == 0 {
+		// Probe for data beyond the limit to tell "exactly at cap" from "truncated"
+		var probe [1]byte
+		if n, _ := source.Read(probe[:]); n > 0 {
+			h.logger.Warn("Truncated upstream response at size limit",
+				zap.String("target", targetURL),
+				zap.Int64("bytes_written", written),
+				zap.Int64("max_bytes", maxBytes))
+			return written, errResponseTooLarge
+		}
+	}
+
+	// Trailer values are filled in once the body has been read to EOF
+	for key, values := range resp.Trailer {
+		if !h.responseHeaderPermitted(key) {
+			continue
+		}
+		for _, value := range values {
+			w.Header().Add(key, value)
+		}
+	}
+
+	if capture != nil {
+		h.checkResponseID(expectedID, capture, targetURL)
+	}
+	return written, nil
 }
 
-// HealthCheckHandler returns a simple health check handler
-func HealthCheckHandler(logger *zap.Logger) http.HandlerFunc {
+// bodyAllowedForStatus reports whether a response with this status may have a body
+func bodyAllowedForStatus(status int) bool {
+	switch {
+	case status >= 100 && status <= 199:
+		return false
+	case status == http.StatusNoContent, status == http.StatusNotModified:
+		return false
+	}
+	return true
+}
+
+// HealthCheckHandler returns a simple health check handler. With
+// HEALTH_INCLUDE_NODES the response also carries nodeSummary(), and with
+// DEEP_HEALTH_CHECK the result of deepCheck(), failing the check when it does.
+func HealthCheckHandler(cfg *config.Config, logger *zap.Logger, nodeSummary func() map[string]interface{}, deepCheck func() (map[string]interface{}, bool)) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Enable CORS for health checks too
 		w.Header().Set("Access-Control-Allow-Origin", "*")
@@ -286,23 +1045,34 @@ func HealthCheckHandler(logger *zap.Logger) http.HandlerFunc {
 		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
 		
 		if r.Method == http.MethodOptions {
-			w.WriteHeader(http.StatusOK)
+			WritePreflight(w, r, cfg.CORSMaxAge)
 			return
 		}
 		
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		
 		response := map[string]interface{}{
 			"status":  "healthy",
 			"service": "vigil-intelligent-router",
 			"time":    time.Now().UTC().Format(time.RFC3339),
 		}
+		if cfg.HealthIncludeNodes && nodeSummary != nil {
+			response["nodes"] = nodeSummary()
+		}
+		status := http.StatusOK
+		if cfg.DeepHealthCheck && deepCheck != nil {
+			result, ok := deepCheck()
+			response["deep_check"] = result
+			if !ok {
+				response["status"] = "unhealthy"
+				status = http.StatusServiceUnavailable
+			}
+		}
 		
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
 		json.NewEncoder(w).Encode(response)
 		
 		logger.Debug("Health check requested",
-			zap.String("remote_addr", r.RemoteAddr))
+			zap.String("remote_addr", ClientIP(cfg, r)))
 	}
 }
 
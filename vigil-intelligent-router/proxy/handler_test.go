@@ -0,0 +1,241 @@
+package proxy
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/project-vigil/vigil-intelligent-router/ml"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestNoViableNodeDiagnostics(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.NodeURLMap = map[string]string{
+		"blocked":  "http://blocked.invalid",
+		"draining": "http://draining.invalid",
+		"sick":     "http://sick.invalid",
+		"lagging":  "http://lagging.invalid",
+	}
+
+	sick := recentMetric("sick", 10)
+	sick.IsHealthy = 0
+	lagging := recentMetric("lagging", 10)
+	gap := 500
+	lagging.BlockHeightGap = &gap
+	metrics := []ml.MetricData{recentMetric("blocked", 10), recentMetric("draining", 10), sick, lagging}
+
+	prediction := ml.PredictionResponse{RecommendedNode: "blocked"}
+	for _, nodeID := range []string{"blocked", "draining", "sick", "lagging"} {
+		prediction.AllPredictions = append(prediction.AllPredictions, ml.NodePrediction{NodeID: nodeID, PredictedLatencyMS: 10})
+	}
+	backendStub(t, cfg, metrics, prediction)
+
+	h := newTestHandler(t, cfg, ml.Options{MaxBlockHeightGap: 100, UnhealthyPolicy: "exclude"})
+	h.mlClient.Block("blocked")
+	h.mlClient.Drain("draining")
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, newRPCRequest("getSlot"))
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status = %d, want 503 (body %s)", w.Code, w.Body)
+	}
+	var body struct {
+		Error string            `json:"error"`
+		Nodes map[string]string `json:"nodes"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("body is not JSON: %v (%s)", err, w.Body)
+	}
+	want := map[string]string{
+		"blocked":  "blocklisted",
+		"draining": "draining",
+		"sick":     "unhealthy",
+		"lagging":  "block_height_lag",
+	}
+	if body.Error != "no viable node" || !reflect.DeepEqual(body.Nodes, want) {
+		t.Fatalf("body = %+v, want error \"no viable node\" and nodes %v", body, want)
+	}
+}
+
+func TestPropagateRequestID(t *testing.T) {
+	for _, propagate := range []bool{false, true} {
+		cfg := testConfig(t)
+		cfg.NodeURLMap = map[string]string{"a": namedUpstream(t, "a")}
+		cfg.PropagateRequestID = propagate
+
+		var mutex sync.Mutex
+		var seen []string
+		backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mutex.Lock()
+			seen = append(seen, r.Header.Get("X-Request-ID"))
+			mutex.Unlock()
+			w.Header().Set("Content-Type", "application/json")
+			if r.URL.Path == cfg.MetricsEndpoint {
+				json.NewEncoder(w).Encode([]ml.MetricData{recentMetric("a", 10)})
+				return
+			}
+			json.NewEncoder(w).Encode(ml.PredictionResponse{
+				RecommendedNode: "a",
+				AllPredictions:  []ml.NodePrediction{{NodeID: "a", PredictedLatencyMS: 10}},
+			})
+		}))
+		cfg.MLServiceURL = backend.URL
+		cfg.DataCollectorURL = backend.URL
+		h := newTestHandler(t, cfg, ml.Options{})
+
+		r := newRPCRequest("getSlot")
+		r.Header.Set("X-Request-ID", "req-1")
+		h.ServeHTTP(httptest.NewRecorder(), r)
+		backend.Close()
+
+		want := ""
+		if propagate {
+			want = "req-1"
+		}
+		if len(seen) != 2 {
+			t.Fatalf("propagate=%v: backend saw %d calls, want metrics and prediction", propagate, len(seen))
+		}
+		for _, id := range seen {
+			if id != want {
+				t.Errorf("propagate=%v: X-Request-ID = %q, want %q", propagate, id, want)
+			}
+		}
+	}
+}
+
+func TestUnmappedPickSkipsIneligibleNodes(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.NodeURLMap = map[string]string{
+		"blocked": namedUpstream(t, "blocked"),
+		"good":    namedUpstream(t, "good"),
+	}
+	// The best-scored node has no URL; the next-ranked one is blocklisted
+	backendStub(t, cfg, nil, ml.PredictionResponse{
+		RecommendedNode: "ghost",
+		AllPredictions: []ml.NodePrediction{
+			{NodeID: "ghost", PredictedLatencyMS: 5},
+			{NodeID: "blocked", PredictedLatencyMS: 10},
+			{NodeID: "good", PredictedLatencyMS: 20},
+		},
+	})
+	h := newTestHandler(t, cfg, ml.Options{})
+	h.mlClient.Block("blocked")
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, newRPCRequest("getSlot"))
+
+	if want := `{"jsonrpc":"2.0","id":1,"result":"good"}`; rec.Body.String() != want {
+		t.Fatalf("got %d %s, want node good", rec.Code, rec.Body.String())
+	}
+}
+
+func TestDecisionAgeExcludesUpstreamLatency(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+		w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":"a"}`))
+	}))
+	t.Cleanup(upstream.Close)
+
+	cfg := testConfig(t)
+	cfg.NodeURLMap = map[string]string{"a": upstream.URL}
+	backendStub(t, cfg, nil, ml.PredictionResponse{
+		RecommendedNode: "a",
+		AllPredictions:  []ml.NodePrediction{{NodeID: "a", PredictedLatencyMS: 10}},
+	})
+	h := newTestHandler(t, cfg, ml.Options{})
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, newRPCRequest("getSlot"))
+	header := rec.Header().Get("X-Vigil-Decision-Age-Ms")
+	age, err := strconv.Atoi(header)
+	if err != nil {
+		t.Fatalf("X-Vigil-Decision-Age-Ms = %q, want a number", header)
+	}
+	if age >= 200 {
+		t.Errorf("decision age = %dms, includes the 200ms upstream call", age)
+	}
+}
+
+// unreachableURL is the address of a server that has already shut down
+func unreachableURL(t *testing.T) string {
+	t.Helper()
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+	return server.URL
+}
+
+func TestLastResortAfterTotalFailure(t *testing.T) {
+	tests := []struct {
+		name       string
+		fallback   bool
+		lastResort bool
+		wantStatus int
+	}{
+		{"no fallback", false, true, http.StatusOK},
+		{"fallback unreachable", true, true, http.StatusOK},
+		{"no last resort", true, false, http.StatusBadGateway},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig(t)
+			cfg.NodeURLMap = map[string]string{"a": namedUpstream(t, "a")}
+			// Both the ML service and the Data Collector are down
+			cfg.MLServiceURL = unreachableURL(t)
+			cfg.DataCollectorURL = cfg.MLServiceURL
+			cfg.FallbackEnabled = tt.fallback
+			cfg.FallbackRPCURL = unreachableURL(t)
+			if tt.lastResort {
+				cfg.LastResortNodeURL = namedUpstream(t, "last_resort")
+			}
+			core, logs := observer.New(zapcore.ErrorLevel)
+			mlClient := ml.NewClient(cfg.GetMLPredictURLs(), cfg.GetMetricsURL(), cfg.MLQueryTimeout, cfg.NodeURLMap, ml.Options{}, zap.NewNop())
+			h := NewHandler(mlClient, cfg, zap.New(core))
+
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, newRPCRequest("getSlot"))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d %s, want %d", rec.Code, rec.Body.String(), tt.wantStatus)
+			}
+			if !tt.lastResort {
+				if logs.FilterMessage("All routing options exhausted, using last-resort node").Len() != 0 {
+					t.Error("logged last-resort use with none configured")
+				}
+				return
+			}
+			if want := `{"jsonrpc":"2.0","id":1,"result":"last_resort"}`; rec.Body.String() != want {
+				t.Fatalf("body = %s, want the last-resort node", rec.Body.String())
+			}
+			if logs.FilterMessage("All routing options exhausted, using last-resort node").Len() != 1 {
+				t.Errorf("last-resort use not logged as an error: %v", logs.All())
+			}
+		})
+	}
+}
+
+func TestLastResortAfterNodeFailure(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.NodeURLMap = map[string]string{"a": unreachableURL(t)}
+	cfg.LastResortNodeURL = namedUpstream(t, "last_resort")
+	backendStub(t, cfg, []ml.MetricData{recentMetric("a", 10)}, ml.PredictionResponse{
+		RecommendedNode: "a",
+		AllPredictions:  []ml.NodePrediction{{NodeID: "a", PredictedLatencyMS: 10}},
+	})
+	h := newTestHandler(t, cfg, ml.Options{})
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, newRPCRequest("getSlot"))
+
+	if want := `{"jsonrpc":"2.0","id":1,"result":"last_resort"}`; rec.Body.String() != want {
+		t.Fatalf("got %d %s, want the last-resort node", rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,39 @@
+package proxy
+
+import (
+	"strings"
+)
+
+// essentialResponseHeaders describe the body and always reach the client
+var essentialResponseHeaders = map[string]bool{
+	"Content-Type":     true,
+	"Content-Length":   true,
+	"Content-Encoding": true,
+}
+
+// responseHeaderPermitted checks an upstream response header against
+// RESPONSE_HEADER_ALLOWLIST and RESPONSE_HEADER_DENYLIST
+func (h *Handler) responseHeaderPermitted(key string) bool {
+	if essentialResponseHeaders[key] {
+		return true
+	}
+	if len(h.config.ResponseHeaderAllowlist) > 0 && !headerListMatches(h.config.ResponseHeaderAllowlist, key) {
+		return false
+	}
+	return !headerListMatches(h.config.ResponseHeaderDenylist, key)
+}
+
+// headerListMatches reports whether key is in patterns, ignoring case. A
+// pattern ending in '*' matches any header with that prefix.
+func headerListMatches(patterns []string, key string) bool {
+	for _, pattern := range patterns {
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+			if len(key) >= len(prefix) && strings.EqualFold(key[:len(prefix)], prefix) {
+				return true
+			}
+		} else if strings.EqualFold(key, pattern) {
+			return true
+		}
+	}
+	return false
+}
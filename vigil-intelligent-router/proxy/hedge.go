@@ -0,0 +1,256 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/project-vigil/vigil-intelligent-router/ml"
+	"go.uber.org/zap"
+)
+
+// hedgeStats counts hedged requests and which node won each race
+type hedgeStats struct {
+	mutex    sync.Mutex
+	requests int64
+	wins     map[string]int64
+}
+
+// hedgeableMethods are the read methods raced when HEDGING_METHODS is unset.
+// They have no side effects, so sending one to several nodes is harmless.
+var hedgeableMethods = map[string]bool{
+	"getAccountInfo":                    true,
+	"getBalance":                        true,
+	"getBlock":                          true,
+	"getBlockHeight":                    true,
+	"getBlockTime":                      true,
+	"getEpochInfo":                      true,
+	"getFeeForMessage":                  true,
+	"getHealth":                         true,
+	"getLatestBlockhash":                true,
+	"getMinimumBalanceForRentExemption": true,
+	"getMultipleAccounts":               true,
+	"getProgramAccounts":                true,
+	"getRecentPrioritizationFees":       true,
+	"getSignaturesForAddress":           true,
+	"getSignatureStatuses":              true,
+	"getSlot":                           true,
+	"getTokenAccountBalance":            true,
+	"getTokenAccountsByOwner":           true,
+	"getTransaction":                    true,
+	"getVersion":                        true,
+	"isBlockhashValid":                  true,
+}
+
+// shouldHedge reports whether every call in the request is a read method that
+// may be raced across nodes. Without HEDGING_METHODS, hedgeableMethods qualify.
+func (h *Handler) shouldHedge(reqs []rpcRequest) bool {
+	if !h.config.HedgingEnabled || h.config.HedgingFanout < 2 || len(reqs) == 0 {
+		return false
+	}
+	for _, req := range reqs {
+		// Notifications are fire-and-forget; racing them would send them twice
+		if req.isNotification() {
+			return false
+		}
+		if len(h.config.HedgingMethods) > 0 {
+			if !slices.Contains(h.config.HedgingMethods, req.Method) {
+				return false
+			}
+		} else if !hedgeableMethods[req.Method] {
+			return false
+		}
+	}
+	return true
+}
+
+// hedgeCandidate is one node a hedged request is sent to
+type hedgeCandidate struct {
+	nodeID string
+	url    string
+}
+
+// hedgeResult is the outcome of one leg of a hedged request
+type hedgeResult struct {
+	candidate hedgeCandidate
+	resp      *http.Response
+	err       error
+	latency   time.Duration
+}
+
+// hedgeCandidates returns the primary node plus up to HedgingFanout-1 of the
+// next-best eligible nodes with spare capacity. Extra nodes hold an in-flight
+// slot that the caller must release.
+func (h *Handler) hedgeCandidates(prediction *ml.PredictionResponse, primaryURL string) []hedgeCandidate {
+	candidates := []hedgeCandidate{{nodeID: prediction.RecommendedNode, url: primaryURL}}
+	for _, node := range prediction.RankedPredictions() {
+		if len(candidates) >= h.config.HedgingFanout {
+			break
+		}
+		if node.NodeID == prediction.RecommendedNode || h.mlClient.NodeIneligibleReason(prediction, node.NodeID) != "" {
+			continue
+		}
+		url, err := h.mlClient.GetRecommendedNodeURL(node.NodeID)
+		if err != nil || !h.acquireNode(node.NodeID) {
+			continue
+		}
+		candidates = append(candidates, hedgeCandidate{nodeID: node.NodeID, url: url})
+	}
+	return candidates
+}
+
+// forwardHedged sends the request to the top candidates at once, streams the
+// first successful response and cancels the rest. If no leg succeeds, the first
+// response received is streamed instead. Like forwardRequest, it returns an
+// error only when no candidate could be reached.
+func (h *Handler) forwardHedged(w http.ResponseWriter, originalReq *http.Request, targetURL string, bodyBytes []byte, startTime time.Time, prediction *ml.PredictionResponse) error {
+	candidates := h.hedgeCandidates(prediction, targetURL)
+	for _, c := range candidates[1:] {
+		defer h.releaseNode(c.nodeID)
+	}
+
+	// Each leg gets its own context so losers can be canceled without
+	// aborting the winner's body
+	cancels := make(map[string]context.CancelFunc, len(candidates))
+	defer func() {
+		for _, cancel := range cancels {
+			cancel()
+		}
+	}()
+
+	results := make(chan hedgeResult, len(candidates))
+	for _, c := range candidates {
+		ctx, cancel := context.WithCancel(originalReq.Context())
+		cancels[c.nodeID] = cancel
+		go func(ctx context.Context, c hedgeCandidate) {
+			req, err := h.newUpstreamRequest(originalReq, c.url, bodyBytes)
+			if err != nil {
+				results <- hedgeResult{candidate: c, err: err}
+				return
+			}
+			legStart := time.Now()
+			resp, err := h.clientFor(c.url).Do(req.WithContext(ctx))
+			results <- hedgeResult{candidate: c, resp: resp, err: err, latency: time.Since(legStart)}
+		}(ctx, c)
+	}
+
+	var winner, firstResponse *hedgeResult
+	var losers []*http.Response
+	received := 0
+	for received < len(candidates) {
+		result := <-results
+		received++
+		if result.err != nil {
+			if !clientGone(originalReq) {
+				h.mlClient.RecordOutcome(result.candidate.nodeID, false)
+				h.statsd.incr("upstream_errors."+result.candidate.nodeID, 1)
+			}
+			continue
+		}
+		h.costs.recordRequest(result.candidate.url)
+		h.recordUpstreamMetrics(result.candidate.url, result.resp.StatusCode, float64(result.latency.Milliseconds()))
+		success := result.resp.StatusCode >= 200 && result.resp.StatusCode < 300
+		h.mlClient.RecordOutcome(result.candidate.nodeID, success)
+		if success {
+			winner = &result
+			break
+		}
+		if firstResponse == nil {
+			firstResponse = &result
+		} else {
+			losers = append(losers, result.resp)
+		}
+	}
+
+	if winner == nil {
+		winner = firstResponse
+	} else if firstResponse != nil {
+		losers = append(losers, firstResponse.resp)
+	}
+
+	// Cancel the losers and release any responses they already produced
+	for nodeID, cancel := range cancels {
+		if winner == nil || nodeID != winner.candidate.nodeID {
+			cancel()
+		}
+	}
+	for _, resp := range losers {
+		resp.Body.Close()
+	}
+	go drainHedgeResults(results, len(candidates)-received)
+
+	if winner == nil {
+		if clientGone(originalReq) {
+			h.logger.Info("Client disconnected, hedged requests canceled")
+			return nil
+		}
+		if budgetExhausted(originalReq) {
+			h.serveBudgetExhausted(w, targetURL)
+			return nil
+		}
+		return errors.New("all hedged requests failed")
+	}
+	defer winner.resp.Body.Close()
+
+	h.recordHedgeWin(winner.candidate.nodeID)
+	h.mlClient.RecordLatency(winner.candidate.nodeID, float64(winner.latency.Milliseconds()))
+	h.recordMethodLatency(originalReq, winner.candidate.nodeID, float64(winner.latency.Milliseconds()))
+	prediction.SelectNode(winner.candidate.nodeID)
+	h.auditTarget(originalReq, winner.candidate.url, &prediction.RecommendationDetails.CostScore)
+
+	h.logger.Info("Hedged request won",
+		zap.String("node", winner.candidate.nodeID),
+		zap.Int("candidates", len(candidates)),
+		zap.Duration("rpc_latency", winner.latency))
+
+	setDecisionAge(w, prediction)
+	written, err := h.streamResponse(w, originalReq, winner.resp, bodyBytes, winner.candidate.url, winner.latency)
+	if err != nil {
+		h.logStreamError(err, winner.candidate.url, written)
+		return nil
+	}
+
+	duration := time.Since(startTime)
+	h.statsd.timing("request_duration_ms", float64(duration.Milliseconds()))
+	h.logger.Info("Request completed",
+		zap.String("target", winner.candidate.url),
+		zap.Int("status", winner.resp.StatusCode),
+		zap.Int64("response_size", written),
+		zap.Duration("total_duration", duration))
+	return nil
+}
+
+// drainHedgeResults closes responses from legs that finish after the race is decided
+func drainHedgeResults(results <-chan hedgeResult, remaining int) {
+	for i := 0; i < remaining; i++ {
+		if result := <-results; result.resp != nil {
+			result.resp.Body.Close()
+		}
+	}
+}
+
+// recordHedgeWin counts a hedged request won by nodeID
+func (h *Handler) recordHedgeWin(nodeID string) {
+	h.hedging.mutex.Lock()
+	defer h.hedging.mutex.Unlock()
+	h.hedging.requests++
+	h.hedging.wins[nodeID]++
+}
+
+// hedgeReport summarizes hedging for /stats
+func (h *Handler) hedgeReport() map[string]interface{} {
+	h.hedging.mutex.Lock()
+	defer h.hedging.mutex.Unlock()
+
+	wins := make(map[string]int64, len(h.hedging.wins))
+	for nodeID, n := range h.hedging.wins {
+		wins[nodeID] = n
+	}
+	return map[string]interface{}{
+		"requests": h.hedging.requests,
+		"wins":     wins,
+	}
+}
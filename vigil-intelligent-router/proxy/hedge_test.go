@@ -0,0 +1,81 @@
+package proxy
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/project-vigil/vigil-intelligent-router/ml"
+)
+
+func TestHedgedRequestFastestWins(t *testing.T) {
+	// The slow node holds the request until the router gives up on it. The
+	// body must be read for the server to notice the connection closing.
+	canceled := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.ReadAll(r.Body)
+		<-r.Context().Done()
+		close(canceled)
+	}))
+	t.Cleanup(slow.Close)
+
+	cfg := testConfig(t)
+	cfg.NodeURLMap = map[string]string{"slow": slow.URL, "fast": namedUpstream(t, "fast")}
+	cfg.HedgingEnabled = true
+	cfg.HedgingFanout = 2
+	backendStub(t, cfg, nil, ml.PredictionResponse{
+		RecommendedNode: "slow",
+		AllPredictions: []ml.NodePrediction{
+			{NodeID: "slow", PredictedLatencyMS: 10},
+			{NodeID: "fast", PredictedLatencyMS: 20},
+		},
+	})
+	h := newTestHandler(t, cfg, ml.Options{})
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, newRPCRequest("getSlot"))
+	if want := `{"jsonrpc":"2.0","id":1,"result":"fast"}`; rec.Body.String() != want {
+		t.Fatalf("got %d %s, want node fast", rec.Code, rec.Body.String())
+	}
+
+	select {
+	case <-canceled:
+	case <-time.After(time.Second):
+		t.Fatal("slow leg was not canceled")
+	}
+
+	report := h.hedgeReport()
+	if got := report["requests"]; got != int64(1) {
+		t.Errorf("hedged requests = %v, want 1", got)
+	}
+	if wins := report["wins"].(map[string]int64); wins["fast"] != 1 || wins["slow"] != 0 {
+		t.Errorf("wins = %v, want one for fast", wins)
+	}
+}
+
+func TestShouldHedgeDefaultMethods(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.HedgingEnabled = true
+	cfg.HedgingFanout = 2
+	h := newTestHandler(t, cfg, ml.Options{})
+
+	tests := []struct {
+		method string
+		want   bool
+	}{
+		{"getSlot", true},
+		{"getAccountInfo", true},
+		{"sendTransaction", false},
+		// Not every get* method is on the read allowlist
+		{"getMaxRetransmitSlot", false},
+		{"requestAirdrop", false},
+	}
+	for _, tt := range tests {
+		reqs := []rpcRequest{{JSONRPC: "2.0", ID: []byte("1"), Method: tt.method}}
+		if got := h.shouldHedge(reqs); got != tt.want {
+			t.Errorf("shouldHedge(%s) = %v, want %v", tt.method, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package proxy
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/project-vigil/vigil-intelligent-router/config"
+	"github.com/project-vigil/vigil-intelligent-router/ml"
+	"go.uber.org/zap"
+)
+
+// testConfig loads the default configuration with no nodes and no fallback
+func testConfig(t *testing.T) *config.Config {
+	t.Helper()
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("config.Load: %v", err)
+	}
+	cfg.NodeURLMap = map[string]string{}
+	cfg.FallbackEnabled = false
+	return cfg
+}
+
+// newTestHandler builds a Handler for cfg with an ML client using opts
+func newTestHandler(t *testing.T, cfg *config.Config, opts ml.Options) *Handler {
+	t.Helper()
+	mlClient := ml.NewClient(cfg.GetMLPredictURLs(), cfg.GetMetricsURL(), cfg.MLQueryTimeout, cfg.NodeURLMap, opts, zap.NewNop())
+	return NewHandler(mlClient, cfg, zap.NewNop())
+}
+
+// backendStub serves metrics as the Data Collector and prediction as the ML
+// service, and points cfg at itself. It returns the number of metrics fetches.
+func backendStub(t *testing.T, cfg *config.Config, metrics []ml.MetricData, prediction ml.PredictionResponse) *atomic.Int64 {
+	t.Helper()
+	var fetches atomic.Int64
+	stub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == cfg.MetricsEndpoint {
+			fetches.Add(1)
+			json.NewEncoder(w).Encode(metrics)
+			return
+		}
+		json.NewEncoder(w).Encode(prediction)
+	}))
+	t.Cleanup(stub.Close)
+
+	cfg.MLServiceURL = stub.URL
+	cfg.DataCollectorURL = stub.URL
+	return &fetches
+}
+
+// recentMetric is a healthy sample for nodeID taken now
+func recentMetric(nodeID string, latencyMS float64) ml.MetricData {
+	return ml.MetricData{
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+		NodeID:    nodeID,
+		LatencyMS: &latencyMS,
+		IsHealthy: 1,
+	}
+}
+
+// newRPCRequest builds a POST carrying a single JSON-RPC call to method
+func newRPCRequest(method string) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"`+method+`"}`))
+	r.Header.Set("Content-Type", "application/json")
+	return r
+}
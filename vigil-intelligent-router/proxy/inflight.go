@@ -0,0 +1,100 @@
+package proxy
+
+import (
+	"net/http"
+	"sync/atomic"
+
+	"github.com/project-vigil/vigil-intelligent-router/ml"
+	"go.uber.org/zap"
+)
+
+// newInflightCounters creates an in-flight counter for every configured node
+func newInflightCounters(nodeURLMap map[string]string) map[string]*atomic.Int64 {
+	counters := make(map[string]*atomic.Int64, len(nodeURLMap))
+	for nodeID := range nodeURLMap {
+		counters[nodeID] = &atomic.Int64{}
+	}
+	return counters
+}
+
+// acquireNode reserves an in-flight slot on nodeID, returning false when the
+// node is at its NODE_MAX_INFLIGHT limit. Unknown nodes are never limited.
+func (h *Handler) acquireNode(nodeID string) bool {
+	counter, ok := h.inflight[nodeID]
+	if !ok {
+		return true
+	}
+
+	limit := int64(h.config.NodeMaxInflight[nodeID])
+	for {
+		current := counter.Load()
+		if limit > 0 && current >= limit {
+			return false
+		}
+		if counter.CompareAndSwap(current, current+1) {
+			return true
+		}
+	}
+}
+
+// releaseNode frees a slot taken by acquireNode
+func (h *Handler) releaseNode(nodeID string) {
+	if counter, ok := h.inflight[nodeID]; ok {
+		counter.Add(-1)
+	}
+}
+
+// acquireGlobal admits a request under MAX_GLOBAL_INFLIGHT, returning false
+// when the router is already handling that many
+func (h *Handler) acquireGlobal() bool {
+	if h.config.MaxGlobalInflight <= 0 {
+		h.globalInflight.Add(1)
+		return true
+	}
+	if h.globalInflight.Add(1) > int64(h.config.MaxGlobalInflight) {
+		h.globalInflight.Add(-1)
+		h.globalRejected.Add(1)
+		return false
+	}
+	return true
+}
+
+// serveOverloaded rejects a request turned away by acquireGlobal
+func (h *Handler) serveOverloaded(w http.ResponseWriter) {
+	h.warnings.Warn("Rejecting request, global in-flight limit reached",
+		zap.Int("max_global_inflight", h.config.MaxGlobalInflight))
+	w.Header().Set("Retry-After", "1")
+	http.Error(w, "Router overloaded, retry later", http.StatusServiceUnavailable)
+}
+
+// inflightCounts returns the current in-flight request count per node
+func (h *Handler) inflightCounts() map[string]int64 {
+	counts := make(map[string]int64, len(h.inflight))
+	for nodeID, counter := range h.inflight {
+		counts[nodeID] = counter.Load()
+	}
+	return counts
+}
+
+// nextAvailableNode walks the predictions in score order and reserves a slot on
+// the best eligible, resolvable node that has spare capacity
+func (h *Handler) nextAvailableNode(prediction *ml.PredictionResponse) (string, string, bool) {
+	for _, node := range prediction.RankedPredictions() {
+		if node.NodeID == prediction.RecommendedNode {
+			continue
+		}
+		if h.mlClient.NodeIneligibleReason(prediction, node.NodeID) != "" {
+			continue
+		}
+		url, err := h.mlClient.GetRecommendedNodeURL(node.NodeID)
+		if err != nil {
+			continue
+		}
+		if h.acquireNode(node.NodeID) {
+			h.logger.Debug("Spilling over to node with spare capacity",
+				zap.String("node", node.NodeID))
+			return node.NodeID, url, true
+		}
+	}
+	return "", "", false
+}
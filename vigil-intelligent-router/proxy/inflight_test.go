@@ -0,0 +1,108 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/project-vigil/vigil-intelligent-router/ml"
+)
+
+// blockingUpstream is an RPC node that holds every request until release is
+// closed, then answers with its name
+func blockingUpstream(t *testing.T, name string, release <-chan struct{}) string {
+	t.Helper()
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":"` + name + `"}`))
+	}))
+	t.Cleanup(upstream.Close)
+	return upstream.URL
+}
+
+// serveAsync runs a request through h in the background
+func serveAsync(h http.Handler) <-chan *httptest.ResponseRecorder {
+	done := make(chan *httptest.ResponseRecorder, 1)
+	go func() {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, newRPCRequest("getSlot"))
+		done <- rec
+	}()
+	return done
+}
+
+func TestNodeMaxInflightSpillsOver(t *testing.T) {
+	release := make(chan struct{})
+	cfg := testConfig(t)
+	cfg.NodeURLMap = map[string]string{
+		"fast":  blockingUpstream(t, "fast", release),
+		"spare": namedUpstream(t, "spare"),
+	}
+	cfg.NodeMaxInflight = map[string]int{"fast": 1}
+	backendStub(t, cfg, nil, ml.PredictionResponse{
+		RecommendedNode: "fast",
+		AllPredictions: []ml.NodePrediction{
+			{NodeID: "fast", PredictedLatencyMS: 10},
+			{NodeID: "spare", PredictedLatencyMS: 50},
+		},
+	})
+	h := newTestHandler(t, cfg, ml.Options{})
+
+	// The first request takes fast's only slot and holds it
+	first := serveAsync(h)
+	waitFor(t, func() bool { return h.inflightCounts()["fast"] == 1 })
+
+	for i := 0; i < 2; i++ {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, newRPCRequest("getSlot"))
+		if want := `{"jsonrpc":"2.0","id":1,"result":"spare"}`; rec.Body.String() != want {
+			t.Fatalf("request %d over the limit: got %d %s, want node spare", i, rec.Code, rec.Body.String())
+		}
+	}
+
+	close(release)
+	if rec := <-first; rec.Body.String() != `{"jsonrpc":"2.0","id":1,"result":"fast"}` {
+		t.Errorf("held request: got %d %s, want node fast", rec.Code, rec.Body.String())
+	}
+	for nodeID, count := range h.inflightCounts() {
+		if count != 0 {
+			t.Errorf("%s still has %d in-flight slots taken", nodeID, count)
+		}
+	}
+}
+
+func TestMaxGlobalInflight(t *testing.T) {
+	release := make(chan struct{})
+	cfg := testConfig(t)
+	cfg.NodeURLMap = map[string]string{"a": blockingUpstream(t, "a", release)}
+	cfg.MaxGlobalInflight = 1
+	backendStub(t, cfg, nil, ml.PredictionResponse{
+		RecommendedNode: "a",
+		AllPredictions:  []ml.NodePrediction{{NodeID: "a", PredictedLatencyMS: 10}},
+	})
+	h := newTestHandler(t, cfg, ml.Options{})
+
+	first := serveAsync(h)
+	waitFor(t, func() bool { return h.globalInflight.Load() == 1 })
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, newRPCRequest("getSlot"))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status over the limit = %d, want %d", rec.Code, http.StatusServiceUnavailable)
+	}
+	if got := rec.Header().Get("Retry-After"); got != "1" {
+		t.Errorf("Retry-After = %q, want 1", got)
+	}
+	if got := h.globalRejected.Load(); got != 1 {
+		t.Errorf("rejected = %d, want 1", got)
+	}
+
+	close(release)
+	if rec := <-first; rec.Code != http.StatusOK {
+		t.Errorf("admitted request status = %d, want 200", rec.Code)
+	}
+	if got := h.globalInflight.Load(); got != 0 {
+		t.Errorf("global in-flight = %d after requests finished, want 0", got)
+	}
+}
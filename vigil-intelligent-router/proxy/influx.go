@@ -0,0 +1,91 @@
+package proxy
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// decisionMeasurement is the InfluxDB measurement routing decisions are written as
+const decisionMeasurement = "vigil_decision"
+
+var (
+	influxTagEscaper    = strings.NewReplacer(",", `\,`, "=", `\=`, " ", `\ `, "\n", `\n`)
+	influxStringEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+)
+
+// encodeDecisionLine encodes a decision as one line of InfluxDB line protocol:
+//
+//	vigil_decision,node=helius,method=getBalance,source=hybrid cost_score=12.3,latency=8.1,failure_prob=0.02,... 1700000000000000000
+//
+// The chosen node, method and routing source are tags; the chosen node's
+// prediction, the observed outcome and the request id are fields.
+func encodeDecisionLine(event *decisionEvent) ([]byte, error) {
+	var line strings.Builder
+	line.WriteString(decisionMeasurement)
+	writeInfluxTag(&line, "node", event.ChosenNode)
+	writeInfluxTag(&line, "method", event.Method)
+	writeInfluxTag(&line, "source", event.Source)
+	if event.MLRecommendedNode != "" && event.MLRecommendedNode != event.ChosenNode {
+		writeInfluxTag(&line, "ml_node", event.MLRecommendedNode)
+	}
+
+	fields := influxFields{}
+	for _, prediction := range event.Predictions {
+		if prediction.NodeID == event.ChosenNode {
+			fields.float("cost_score", prediction.CostScore)
+			fields.float("failure_prob", prediction.FailureProb)
+			break
+		}
+	}
+	fields.float("latency", event.Outcome.LatencyMS)
+	fields.float("predicted_latency", event.PredictedLatency)
+	fields.bool("success", event.Outcome.Success)
+	if event.Outcome.Status != 0 {
+		fields.int("status", int64(event.Outcome.Status))
+	}
+	if event.RequestID != "" {
+		fields.string("request_id", event.RequestID)
+	}
+
+	line.WriteByte(' ')
+	line.WriteString(strings.Join(fields, ","))
+	line.WriteByte(' ')
+	line.WriteString(strconv.FormatInt(event.at.UnixNano(), 10))
+	return []byte(line.String()), nil
+}
+
+// writeInfluxTag appends ",key=value", leaving out empty values since line
+// protocol doesn't allow them
+func writeInfluxTag(line *strings.Builder, key, value string) {
+	if value == "" {
+		return
+	}
+	line.WriteByte(',')
+	line.WriteString(key)
+	line.WriteByte('=')
+	line.WriteString(influxTagEscaper.Replace(value))
+}
+
+// influxFields collects "key=value" field pairs in line protocol syntax
+type influxFields []string
+
+// float skips NaN and infinities, which line protocol can't represent
+func (f *influxFields) float(key string, value float64) {
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return
+	}
+	*f = append(*f, key+"="+strconv.FormatFloat(value, 'f', -1, 64))
+}
+
+func (f *influxFields) int(key string, value int64) {
+	*f = append(*f, key+"="+strconv.FormatInt(value, 10)+"i")
+}
+
+func (f *influxFields) bool(key string, value bool) {
+	*f = append(*f, key+"="+strconv.FormatBool(value))
+}
+
+func (f *influxFields) string(key, value string) {
+	*f = append(*f, key+`="`+influxStringEscaper.Replace(value)+`"`)
+}
@@ -0,0 +1,87 @@
+package proxy
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/project-vigil/vigil-intelligent-router/ml"
+)
+
+func TestEncodeDecisionLine(t *testing.T) {
+	at := time.Date(2026, 1, 1, 0, 0, 0, 123000000, time.UTC)
+	tests := []struct {
+		name  string
+		event decisionEvent
+		want  string
+	}{
+		{
+			name: "full event",
+			event: decisionEvent{
+				RequestID:         "c0ffee",
+				Method:            "getBalance",
+				Source:            "hybrid",
+				MLRecommendedNode: "agave1",
+				ChosenNode:        "agave2",
+				Predictions: []ml.NodePrediction{
+					{NodeID: "agave1", CostScore: 20, FailureProb: 0.1},
+					{NodeID: "agave2", CostScore: 12.3, FailureProb: 0.02},
+				},
+				PredictedLatency: 37.5,
+				Outcome:          decisionOutcome{Success: true, Status: 200, LatencyMS: 42.1},
+				at:               at,
+			},
+			want: `vigil_decision,node=agave2,method=getBalance,source=hybrid,ml_node=agave1 ` +
+				`cost_score=12.3,failure_prob=0.02,latency=42.1,predicted_latency=37.5,success=true,status=200i,request_id="c0ffee" ` +
+				`1767225600123000000`,
+		},
+		{
+			name: "empty tags and optional fields are left out",
+			event: decisionEvent{
+				MLRecommendedNode: "agave1",
+				ChosenNode:        "agave1",
+				Outcome:           decisionOutcome{LatencyMS: 5},
+				at:                at,
+			},
+			want: `vigil_decision,node=agave1 latency=5,predicted_latency=0,success=false 1767225600123000000`,
+		},
+		{
+			name: "tags and strings are escaped",
+			event: decisionEvent{
+				Method:     "get Balance,x=1",
+				ChosenNode: "node\nA",
+				RequestID:  `say "hi" \ bye`,
+				Outcome:    decisionOutcome{LatencyMS: 1},
+				at:         at,
+			},
+			want: `vigil_decision,node=node\nA,method=get\ Balance\,x\=1 ` +
+				`latency=1,predicted_latency=0,success=false,request_id="say \"hi\" \\ bye" 1767225600123000000`,
+		},
+		{
+			name: "non-finite floats are skipped",
+			event: decisionEvent{
+				ChosenNode:       "agave1",
+				Predictions:      []ml.NodePrediction{{NodeID: "agave1", CostScore: math.Inf(1), FailureProb: math.NaN()}},
+				PredictedLatency: math.NaN(),
+				Outcome:          decisionOutcome{Success: true, LatencyMS: 3},
+				at:               at,
+			},
+			want: `vigil_decision,node=agave1 latency=3,success=true 1767225600123000000`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := encodeDecisionLine(&tt.event)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("line:\n got %s\nwant %s", got, tt.want)
+			}
+			if strings.Contains(string(got), "\n") {
+				t.Error("line contains a raw newline")
+			}
+		})
+	}
+}
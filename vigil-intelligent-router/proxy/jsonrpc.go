@@ -0,0 +1,90 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+)
+
+// Standard JSON-RPC 2.0 error codes
+const (
+	rpcInvalidRequest = -32600
+	rpcMethodNotFound = -32601
+	rpcLimitExceeded  = -32005 // widely used by node providers for rate limiting
+)
+
+// rpcRequest is the subset of a JSON-RPC request the router inspects
+type rpcRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	ID      json.RawMessage `json:"id,omitempty"`
+}
+
+// parseRPCRequests decodes a single or batch JSON-RPC payload
+func parseRPCRequests(body []byte) ([]rpcRequest, bool, error) {
+	trimmed := bytes.TrimSpace(body)
+	if len(trimmed) > 0 && trimmed[0] == '[' {
+		var batch []rpcRequest
+		if err := json.Unmarshal(trimmed, &batch); err != nil {
+			return nil, true, err
+		}
+		return batch, true, nil
+	}
+
+	var single rpcRequest
+	if err := json.Unmarshal(trimmed, &single); err != nil {
+		return nil, false, err
+	}
+	return []rpcRequest{single}, false, nil
+}
+
+// isNotification reports whether the request is a JSON-RPC notification: it
+// has no id and expects no response. An explicit "id": null is not one.
+func (r rpcRequest) isNotification() bool {
+	return len(r.ID) == 0
+}
+
+// allNotifications reports whether every parsed request is a notification
+func allNotifications(reqs []rpcRequest) bool {
+	for _, req := range reqs {
+		if !req.isNotification() {
+			return false
+		}
+	}
+	return len(reqs) > 0
+}
+
+// rpcMethods returns the method names of the parsed requests
+func rpcMethods(reqs []rpcRequest) []string {
+	methods := make([]string, 0, len(reqs))
+	for _, req := range reqs {
+		methods = append(methods, req.Method)
+	}
+	return methods
+}
+
+// rpcError is a JSON-RPC 2.0 error object
+type rpcError struct {
+	Code    int         `json:"code"`
+	Message string      `json:"message"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+// rpcErrorResponse is a JSON-RPC 2.0 error response envelope
+type rpcErrorResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Error   rpcError        `json:"error"`
+	ID      json.RawMessage `json:"id"`
+}
+
+// writeRPCError replies with a JSON-RPC error. A nil id is sent as null.
+func writeRPCError(w http.ResponseWriter, status, code int, message string, id json.RawMessage) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(rpcErrorResponse{
+		JSONRPC: "2.0",
+		Error:   rpcError{Code: code, Message: message},
+		ID:      rpcIDOrNull(id),
+	})
+}
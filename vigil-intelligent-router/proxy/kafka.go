@@ -0,0 +1,354 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"net"
+	"strconv"
+	"time"
+)
+
+// Kafka protocol API keys and the versions this producer speaks
+const (
+	kafkaAPIProduce      = 0
+	kafkaAPIMetadata     = 3
+	kafkaProduceVersion  = 3
+	kafkaMetadataVersion = 4
+)
+
+var kafkaCRCTable = crc32.MakeTable(crc32.Castagnoli)
+
+// kafkaProducer is a minimal Kafka producer: it looks up partition leaders,
+// then writes each batch uncompressed to the next partition in turn with
+// acks=1. It is used from a single goroutine and is not safe for concurrent use.
+type kafkaProducer struct {
+	brokers  []string
+	topic    string
+	clientID string
+	timeout  time.Duration
+
+	conns       map[string]net.Conn // by broker address
+	leaders     map[int32]string    // partition -> leader address
+	partitions  []int32
+	next        int
+	correlation int32
+}
+
+func newKafkaProducer(brokers []string, topic string, timeout time.Duration) *kafkaProducer {
+	return &kafkaProducer{
+		brokers:  brokers,
+		topic:    topic,
+		clientID: "vigil-router",
+		timeout:  timeout,
+		conns:    make(map[string]net.Conn),
+	}
+}
+
+// produce writes values as one record batch. On any error the connections
+// and partition metadata are discarded so the next call starts afresh.
+func (p *kafkaProducer) produce(ctx context.Context, values [][]byte) error {
+	err := p.tryProduce(ctx, values)
+	if err != nil {
+		p.reset()
+	}
+	return err
+}
+
+func (p *kafkaProducer) tryProduce(ctx context.Context, values [][]byte) error {
+	if len(p.partitions) == 0 {
+		if err := p.refreshMetadata(ctx); err != nil {
+			return err
+		}
+	}
+	partition := p.partitions[p.next%len(p.partitions)]
+	p.next++
+
+	var body kafkaWriter
+	body.int16(-1) // no transactional id
+	body.int16(1)  // acks: leader only
+	body.int32(int32(p.timeout.Milliseconds()))
+	body.int32(1)
+	body.string(p.topic)
+	body.int32(1)
+	body.int32(partition)
+	body.bytes(encodeRecordBatch(values, time.Now()))
+
+	resp, err := p.roundTrip(ctx, p.leaders[partition], kafkaAPIProduce, kafkaProduceVersion, body.buf.Bytes())
+	if err != nil {
+		return err
+	}
+
+	r := kafkaReader{buf: resp}
+	for topics := r.int32(); topics > 0; topics-- {
+		r.string()
+		for parts := r.int32(); parts > 0; parts-- {
+			index := r.int32()
+			code := r.int16()
+			r.int64() // base offset
+			r.int64() // log append time
+			if r.err == nil && code != 0 {
+				return fmt.Errorf("kafka produce to %s/%d failed with error code %d", p.topic, index, code)
+			}
+		}
+	}
+	return r.err
+}
+
+// refreshMetadata finds the leader of every partition of the topic
+func (p *kafkaProducer) refreshMetadata(ctx context.Context) error {
+	var body kafkaWriter
+	body.int32(1)
+	body.string(p.topic)
+	body.int8(0) // don't auto-create the topic
+
+	var lastErr error
+	for _, broker := range p.brokers {
+		resp, err := p.roundTrip(ctx, broker, kafkaAPIMetadata, kafkaMetadataVersion, body.buf.Bytes())
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if err := p.parseMetadata(resp); err != nil {
+			lastErr = err
+			continue
+		}
+		return nil
+	}
+	if lastErr == nil {
+		lastErr = errors.New("no kafka brokers configured")
+	}
+	return lastErr
+}
+
+func (p *kafkaProducer) parseMetadata(resp []byte) error {
+	r := kafkaReader{buf: resp}
+	r.int32() // throttle time
+
+	addrs := make(map[int32]string)
+	for n := r.int32(); n > 0 && r.err == nil; n-- {
+		id := r.int32()
+		host := r.string()
+		port := r.int32()
+		r.string() // rack
+		addrs[id] = net.JoinHostPort(host, strconv.Itoa(int(port)))
+	}
+	r.string() // cluster id
+	r.int32()  // controller id
+
+	leaders := make(map[int32]string)
+	var partitions []int32
+	for n := r.int32(); n > 0 && r.err == nil; n-- {
+		code := r.int16()
+		name := r.string()
+		r.int8() // is internal
+		if code != 0 && r.err == nil {
+			return fmt.Errorf("kafka metadata for topic %s failed with error code %d", name, code)
+		}
+		for parts := r.int32(); parts > 0 && r.err == nil; parts-- {
+			r.int16() // partition error
+			index := r.int32()
+			leader := r.int32()
+			for replicas := r.int32(); replicas > 0; replicas-- {
+				r.int32()
+			}
+			for isr := r.int32(); isr > 0; isr-- {
+				r.int32()
+			}
+			if addr, ok := addrs[leader]; ok {
+				leaders[index] = addr
+				partitions = append(partitions, index)
+			}
+		}
+	}
+	if r.err != nil {
+		return r.err
+	}
+	if len(partitions) == 0 {
+		return fmt.Errorf("kafka topic %s has no partition with a known leader", p.topic)
+	}
+	p.leaders, p.partitions = leaders, partitions
+	return nil
+}
+
+// roundTrip sends one request to addr and returns the response body after the
+// correlation id
+func (p *kafkaProducer) roundTrip(ctx context.Context, addr string, apiKey, version int16, body []byte) ([]byte, error) {
+	conn, err := p.conn(ctx, addr)
+	if err != nil {
+		return nil, err
+	}
+	deadline := time.Now().Add(p.timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	conn.SetDeadline(deadline)
+
+	p.correlation++
+	var req kafkaWriter
+	req.int32(0) // size, filled in below
+	req.int16(apiKey)
+	req.int16(version)
+	req.int32(p.correlation)
+	req.string(p.clientID)
+	req.buf.Write(body)
+	frame := req.buf.Bytes()
+	binary.BigEndian.PutUint32(frame, uint32(len(frame)-4))
+	if _, err := conn.Write(frame); err != nil {
+		return nil, err
+	}
+
+	var size [4]byte
+	if _, err := io.ReadFull(conn, size[:]); err != nil {
+		return nil, err
+	}
+	resp := make([]byte, binary.BigEndian.Uint32(size[:]))
+	if _, err := io.ReadFull(conn, resp); err != nil {
+		return nil, err
+	}
+	if len(resp) < 4 || int32(binary.BigEndian.Uint32(resp)) != p.correlation {
+		return nil, errors.New("kafka response correlation id mismatch")
+	}
+	return resp[4:], nil
+}
+
+func (p *kafkaProducer) conn(ctx context.Context, addr string) (net.Conn, error) {
+	if conn, ok := p.conns[addr]; ok {
+		return conn, nil
+	}
+	dialer := net.Dialer{Timeout: p.timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	p.conns[addr] = conn
+	return conn, nil
+}
+
+func (p *kafkaProducer) reset() {
+	for addr, conn := range p.conns {
+		conn.Close()
+		delete(p.conns, addr)
+	}
+	p.leaders, p.partitions = nil, nil
+}
+
+// encodeRecordBatch builds a v2 record batch holding values without keys
+func encodeRecordBatch(values [][]byte, now time.Time) []byte {
+	timestamp := now.UnixMilli()
+
+	var records []byte
+	for i, value := range values {
+		var record []byte
+		record = append(record, 0)                     // attributes
+		record = binary.AppendVarint(record, 0)        // timestamp delta
+		record = binary.AppendVarint(record, int64(i)) // offset delta
+		record = binary.AppendVarint(record, -1)       // null key
+		record = binary.AppendVarint(record, int64(len(value)))
+		record = append(record, value...)
+		record = binary.AppendVarint(record, 0) // no headers
+		records = binary.AppendVarint(records, int64(len(record)))
+		records = append(records, record...)
+	}
+
+	// Everything after the CRC field is covered by it
+	var tail kafkaWriter
+	tail.int16(0) // attributes: no compression
+	tail.int32(int32(len(values) - 1))
+	tail.int64(timestamp)
+	tail.int64(timestamp)
+	tail.int64(-1) // producer id
+	tail.int16(-1) // producer epoch
+	tail.int32(-1) // base sequence
+	tail.int32(int32(len(values)))
+	tail.buf.Write(records)
+
+	var batch kafkaWriter
+	batch.int64(0) // base offset
+	batch.int32(int32(4 + 1 + 4 + tail.buf.Len()))
+	batch.int32(-1) // partition leader epoch
+	batch.int8(2)   // magic
+	batch.int32(int32(crc32.Checksum(tail.buf.Bytes(), kafkaCRCTable)))
+	batch.buf.Write(tail.buf.Bytes())
+	return batch.buf.Bytes()
+}
+
+// kafkaWriter encodes big-endian Kafka protocol primitives
+type kafkaWriter struct {
+	buf bytes.Buffer
+}
+
+func (w *kafkaWriter) int8(v int8)   { w.buf.WriteByte(byte(v)) }
+func (w *kafkaWriter) int16(v int16) { w.buf.Write(binary.BigEndian.AppendUint16(nil, uint16(v))) }
+func (w *kafkaWriter) int32(v int32) { w.buf.Write(binary.BigEndian.AppendUint32(nil, uint32(v))) }
+func (w *kafkaWriter) int64(v int64) { w.buf.Write(binary.BigEndian.AppendUint64(nil, uint64(v))) }
+
+func (w *kafkaWriter) string(s string) {
+	w.int16(int16(len(s)))
+	w.buf.WriteString(s)
+}
+
+func (w *kafkaWriter) bytes(b []byte) {
+	w.int32(int32(len(b)))
+	w.buf.Write(b)
+}
+
+// kafkaReader decodes Kafka protocol primitives, remembering the first error
+type kafkaReader struct {
+	buf []byte
+	err error
+}
+
+func (r *kafkaReader) take(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n < 0 || len(r.buf) < n {
+		r.err = errors.New("truncated kafka response")
+		return nil
+	}
+	b := r.buf[:n]
+	r.buf = r.buf[n:]
+	return b
+}
+
+func (r *kafkaReader) int8() int8 {
+	if b := r.take(1); b != nil {
+		return int8(b[0])
+	}
+	return 0
+}
+
+func (r *kafkaReader) int16() int16 {
+	if b := r.take(2); b != nil {
+		return int16(binary.BigEndian.Uint16(b))
+	}
+	return 0
+}
+
+func (r *kafkaReader) int32() int32 {
+	if b := r.take(4); b != nil {
+		return int32(binary.BigEndian.Uint32(b))
+	}
+	return 0
+}
+
+func (r *kafkaReader) int64() int64 {
+	if b := r.take(8); b != nil {
+		return int64(binary.BigEndian.Uint64(b))
+	}
+	return 0
+}
+
+// string reads a (nullable) string; null reads as ""
+func (r *kafkaReader) string() string {
+	n := r.int16()
+	if n < 0 {
+		return ""
+	}
+	return string(r.take(int(n)))
+}
@@ -0,0 +1,203 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"hash/crc32"
+	"io"
+	"net"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func mustHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestKafkaCRCIsCastagnoli(t *testing.T) {
+	// Standard CRC-32C check value
+	if got := crc32.Checksum([]byte("123456789"), kafkaCRCTable); got != 0xe3069283 {
+		t.Fatalf("crc32c check value = %#x, want 0xe3069283", got)
+	}
+}
+
+func TestEncodeRecordBatch(t *testing.T) {
+	// Two keyless records "a" and "bc" at 1700000000000 ms, laid out per the
+	// v2 record batch format
+	want := mustHex(t, "000000000000000000000042ffffffff02af2b60540000000000010000018bcfe568000000018bcfe56800ffffffffffffffffffffffffffff000000020e00000001026100100000020104626300")
+
+	got := encodeRecordBatch([][]byte{[]byte("a"), []byte("bc")}, time.UnixMilli(1700000000000))
+	if !bytes.Equal(got, want) {
+		t.Fatalf("record batch mismatch\n got %x\nwant %x", got, want)
+	}
+}
+
+func TestParseMetadata(t *testing.T) {
+	// Brokers 1 (k1:9092) and 2 (k2:9093); topic "decisions" has partition 0
+	// led by broker 2 and partition 1 led by unknown broker 7
+	resp := mustHex(t, "00000000000000020000000100026b3100002384ffff0000000200026b32000023850001720001630000000100000001000000096465636973696f6e730000000002000000000000000000020000000200000002000000010000000100000002000000000001000000070000000000000000")
+
+	p := newKafkaProducer([]string{"k1:9092"}, "decisions", time.Second)
+	if err := p.parseMetadata(resp); err != nil {
+		t.Fatalf("parseMetadata: %v", err)
+	}
+	if want := map[int32]string{0: "k2:9093"}; !reflect.DeepEqual(p.leaders, want) {
+		t.Errorf("leaders = %v, want %v", p.leaders, want)
+	}
+	if want := []int32{0}; !reflect.DeepEqual(p.partitions, want) {
+		t.Errorf("partitions = %v, want %v", p.partitions, want)
+	}
+}
+
+func TestParseMetadataErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		resp string
+	}{
+		// UNKNOWN_TOPIC_OR_PARTITION (3) for "decisions", no brokers
+		{"topic error", "00000000000000000001630000000100000001000300096465636973696f6e730000000000"},
+		// Cut off inside the broker list
+		{"truncated", "00000000000000020000000100026b31"},
+		// No brokers, topic without partitions
+		{"no leaders", "00000000000000000001630000000100000001000000096465636973696f6e730000000000"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newKafkaProducer([]string{"k1:9092"}, "decisions", time.Second)
+			if err := p.parseMetadata(mustHex(t, tt.resp)); err == nil {
+				t.Fatal("expected an error")
+			}
+			if p.partitions != nil {
+				t.Errorf("partitions = %v, want none", p.partitions)
+			}
+		})
+	}
+}
+
+// stubBroker answers Metadata with itself as the leader of partition 0 and
+// accepts every Produce, passing each produced record batch to batches
+func stubBroker(t *testing.T, topic string, batches chan<- []byte) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	host, portStr, _ := net.SplitHostPort(listener.Addr().String())
+	port, _ := strconv.Atoi(portStr)
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			var size [4]byte
+			if _, err := io.ReadFull(conn, size[:]); err != nil {
+				return
+			}
+			frame := make([]byte, binary.BigEndian.Uint32(size[:]))
+			if _, err := io.ReadFull(conn, frame); err != nil {
+				return
+			}
+			r := kafkaReader{buf: frame}
+			apiKey := r.int16()
+			r.int16() // version
+			correlation := r.int32()
+			r.string() // client id
+
+			var resp kafkaWriter
+			resp.int32(0) // size, filled in below
+			resp.int32(correlation)
+			switch apiKey {
+			case kafkaAPIMetadata:
+				resp.int32(0) // throttle time
+				resp.int32(1)
+				resp.int32(1)
+				resp.string(host)
+				resp.int32(int32(port))
+				resp.int16(-1) // no rack
+				resp.string("cluster")
+				resp.int32(1)
+				resp.int32(1)
+				resp.int16(0)
+				resp.string(topic)
+				resp.int8(0)
+				resp.int32(1)
+				resp.int16(0)
+				resp.int32(0) // partition
+				resp.int32(1) // leader
+				resp.int32(0) // replicas
+				resp.int32(0) // isr
+			case kafkaAPIProduce:
+				r.int16() // transactional id
+				r.int16() // acks
+				r.int32() // timeout
+				r.int32() // topics
+				r.string()
+				r.int32() // partitions
+				r.int32()
+				batches <- r.take(int(r.int32()))
+				resp.int32(1)
+				resp.string(topic)
+				resp.int32(1)
+				resp.int32(0)
+				resp.int16(0)
+				resp.int64(0)
+				resp.int64(-1)
+				resp.int32(0) // throttle time
+			}
+			out := resp.buf.Bytes()
+			binary.BigEndian.PutUint32(out, uint32(len(out)-4))
+			if _, err := conn.Write(out); err != nil {
+				return
+			}
+		}
+	}()
+	return listener.Addr().String()
+}
+
+func TestKafkaProducerProduce(t *testing.T) {
+	batches := make(chan []byte, 1)
+	addr := stubBroker(t, "decisions", batches)
+
+	p := newKafkaProducer([]string{addr}, "decisions", time.Second)
+	defer p.reset()
+	values := [][]byte{[]byte(`{"chosen_node":"a"}`), []byte(`{"chosen_node":"b"}`)}
+	if err := p.produce(context.Background(), values); err != nil {
+		t.Fatalf("produce: %v", err)
+	}
+
+	batch := <-batches
+	r := kafkaReader{buf: batch}
+	r.int64() // base offset
+	r.int32() // length
+	r.int32() // leader epoch
+	if magic := r.int8(); magic != 2 {
+		t.Fatalf("magic = %d, want 2", magic)
+	}
+	crc := uint32(r.int32())
+	if got := crc32.Checksum(r.buf, kafkaCRCTable); got != crc {
+		t.Errorf("batch crc = %#x, want %#x", crc, got)
+	}
+	r.int16() // attributes
+	r.int32() // last offset delta
+	r.int64()
+	r.int64()
+	r.int64()
+	r.int16()
+	r.int32()
+	if count := r.int32(); count != int32(len(values)) {
+		t.Errorf("record count = %d, want %d", count, len(values))
+	}
+}
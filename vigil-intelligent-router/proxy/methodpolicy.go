@@ -0,0 +1,154 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"go.uber.org/zap"
+)
+
+// methodPermitted checks a method against ALLOWED_METHODS and DENIED_METHODS
+func (h *Handler) methodPermitted(method string) bool {
+	if len(h.config.AllowedMethods) > 0 && !slices.Contains(h.config.AllowedMethods, method) {
+		return false
+	}
+	return !slices.Contains(h.config.DeniedMethods, method)
+}
+
+// methodNotPermitted builds the error response for a rejected request
+func methodNotPermitted(req rpcRequest) rpcErrorResponse {
+	return rpcErrorResponse{
+		JSONRPC: "2.0",
+		Error:   rpcError{Code: rpcMethodNotFound, Message: "method not permitted: " + req.Method},
+		ID:      rpcIDOrNull(req.ID),
+	}
+}
+
+// enforceMethodPolicy applies the method allow/deny lists before any routing.
+// It returns ok=false once it has written a rejection. For batches under the
+// "partial" policy it instead returns the permitted requests re-encoded as the
+// new body, plus the error responses to merge into the upstream reply.
+func (h *Handler) enforceMethodPolicy(w http.ResponseWriter, reqs []rpcRequest, batch bool, parseErr error) (filtered []byte, rejected []rpcErrorResponse, ok bool) {
+	if len(h.config.AllowedMethods) == 0 && len(h.config.DeniedMethods) == 0 {
+		return nil, nil, true
+	}
+	if parseErr != nil {
+		writeRPCError(w, http.StatusBadRequest, rpcInvalidRequest, "could not determine JSON-RPC method", nil)
+		return nil, nil, false
+	}
+
+	var permitted []rpcRequest
+	for _, req := range reqs {
+		if h.methodPermitted(req.Method) {
+			permitted = append(permitted, req)
+		} else {
+			rejected = append(rejected, methodNotPermitted(req))
+		}
+	}
+	if len(rejected) == 0 {
+		return nil, nil, true
+	}
+
+	h.logger.Warn("Rejected JSON-RPC methods not permitted by policy",
+		zap.Strings("methods", rpcMethods(reqs)),
+		zap.Int("rejected", len(rejected)))
+
+	if !batch {
+		writeJSON(w, http.StatusForbidden, rejected[0])
+		return nil, nil, false
+	}
+
+	if h.config.MethodBatchPolicy == "partial" && len(permitted) > 0 {
+		body, err := json.Marshal(permitted)
+		if err == nil {
+			// Notifications get no response, not even an error
+			var errs []rpcErrorResponse
+			for _, req := range reqs {
+				if !req.isNotification() && !h.methodPermitted(req.Method) {
+					errs = append(errs, methodNotPermitted(req))
+				}
+			}
+			return body, errs, true
+		}
+	}
+
+	// Reject the whole batch, answering every element
+	responses := make([]rpcErrorResponse, 0, len(reqs))
+	for _, req := range reqs {
+		if h.methodPermitted(req.Method) {
+			resp := methodNotPermitted(req)
+			resp.Error.Message = "batch rejected: contains a method that is not permitted"
+			responses = append(responses, resp)
+		} else {
+			responses = append(responses, methodNotPermitted(req))
+		}
+	}
+	writeJSON(w, http.StatusForbidden, responses)
+	return nil, nil, false
+}
+
+// writeJSON replies with v encoded as JSON
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// batchMergeWriter buffers an upstream batch response so error responses for
+// filtered-out requests can be appended before it reaches the client
+type batchMergeWriter struct {
+	http.ResponseWriter
+	status int
+	buf    bytes.Buffer
+}
+
+func newBatchMergeWriter(w http.ResponseWriter) *batchMergeWriter {
+	return &batchMergeWriter{ResponseWriter: w}
+}
+
+func (m *batchMergeWriter) WriteHeader(status int) {
+	if m.status == 0 {
+		m.status = status
+	}
+}
+
+func (m *batchMergeWriter) Write(p []byte) (int, error) {
+	if m.status == 0 {
+		m.status = http.StatusOK
+	}
+	return m.buf.Write(p)
+}
+
+// finish appends extra to the buffered batch response and writes it out.
+// Non-2xx or non-array responses are passed through untouched. An empty body,
+// which is how upstreams answer a batch of notifications, counts as no responses.
+func (m *batchMergeWriter) finish(extra []rpcErrorResponse) {
+	if m.status == 0 {
+		return
+	}
+
+	body := m.buf.Bytes()
+	var responses []json.RawMessage
+	success := m.status >= 200 && m.status < 300
+	if success && len(bytes.TrimSpace(body)) == 0 && len(extra) > 0 {
+		body = []byte("[]")
+		m.status = http.StatusOK
+	}
+	if success && json.Unmarshal(body, &responses) == nil {
+		for _, resp := range extra {
+			if raw, err := json.Marshal(resp); err == nil {
+				responses = append(responses, raw)
+			}
+		}
+		if merged, err := json.Marshal(responses); err == nil {
+			body = merged
+		}
+	}
+
+	m.ResponseWriter.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	m.ResponseWriter.WriteHeader(m.status)
+	m.ResponseWriter.Write(body)
+}
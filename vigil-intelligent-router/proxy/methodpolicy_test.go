@@ -0,0 +1,118 @@
+package proxy
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync/atomic"
+	"testing"
+
+	"github.com/project-vigil/vigil-intelligent-router/ml"
+)
+
+// methodEchoUpstream answers each call in a single or batch request with its
+// method name as the result, counting the requests it receives
+func methodEchoUpstream(t *testing.T, hits *atomic.Int64) string {
+	t.Helper()
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		body, _ := io.ReadAll(r.Body)
+		reqs, batch, _ := parseRPCRequests(body)
+		var responses []map[string]interface{}
+		for _, req := range reqs {
+			responses = append(responses, map[string]interface{}{"jsonrpc": "2.0", "id": req.ID, "result": req.Method})
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if batch {
+			json.NewEncoder(w).Encode(responses)
+		} else {
+			json.NewEncoder(w).Encode(responses[0])
+		}
+	}))
+	t.Cleanup(upstream.Close)
+	return upstream.URL
+}
+
+func TestMethodPolicy(t *testing.T) {
+	const batch = `[{"jsonrpc":"2.0","id":1,"method":"getSlot"},{"jsonrpc":"2.0","id":2,"method":"sendTransaction"}]`
+	tests := []struct {
+		name        string
+		allowed     []string
+		denied      []string
+		batchPolicy string
+		body        string
+		wantStatus  int
+		wantBody    string
+		wantHits    int64
+	}{
+		{
+			name: "allowed", allowed: []string{"getSlot"},
+			body:       `{"jsonrpc":"2.0","id":1,"method":"getSlot"}`,
+			wantStatus: http.StatusOK, wantBody: `{"id":1,"jsonrpc":"2.0","result":"getSlot"}`, wantHits: 1,
+		},
+		{
+			name: "denied", denied: []string{"sendTransaction"},
+			body:       `{"jsonrpc":"2.0","id":7,"method":"sendTransaction"}`,
+			wantStatus: http.StatusForbidden,
+			wantBody:   `{"jsonrpc":"2.0","error":{"code":-32601,"message":"method not permitted: sendTransaction"},"id":7}`,
+		},
+		{
+			name: "missing from allowlist", allowed: []string{"getSlot"},
+			body:       `{"jsonrpc":"2.0","id":"x","method":"getBalance"}`,
+			wantStatus: http.StatusForbidden,
+			wantBody:   `{"jsonrpc":"2.0","error":{"code":-32601,"message":"method not permitted: getBalance"},"id":"x"}`,
+		},
+		{
+			name: "batch with a denied method", denied: []string{"sendTransaction"}, batchPolicy: "reject",
+			body:       batch,
+			wantStatus: http.StatusForbidden,
+			wantBody: `[{"jsonrpc":"2.0","error":{"code":-32601,"message":"batch rejected: contains a method that is not permitted"},"id":1},` +
+				`{"jsonrpc":"2.0","error":{"code":-32601,"message":"method not permitted: sendTransaction"},"id":2}]`,
+		},
+		{
+			name: "partial batch", denied: []string{"sendTransaction"}, batchPolicy: "partial",
+			body:       batch,
+			wantStatus: http.StatusOK,
+			wantBody: `[{"id":1,"jsonrpc":"2.0","result":"getSlot"},` +
+				`{"jsonrpc":"2.0","error":{"code":-32601,"message":"method not permitted: sendTransaction"},"id":2}]`,
+			wantHits: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var hits atomic.Int64
+			cfg := testConfig(t)
+			cfg.NodeURLMap = map[string]string{"a": methodEchoUpstream(t, &hits)}
+			cfg.AllowedMethods = tt.allowed
+			cfg.DeniedMethods = tt.denied
+			if tt.batchPolicy != "" {
+				cfg.MethodBatchPolicy = tt.batchPolicy
+			}
+			backendStub(t, cfg, []ml.MetricData{recentMetric("a", 10)}, ml.PredictionResponse{
+				RecommendedNode: "a",
+				AllPredictions:  []ml.NodePrediction{{NodeID: "a", PredictedLatencyMS: 10}},
+			})
+			h := newTestHandler(t, cfg, ml.Options{})
+
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, rpcCall(tt.body))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d %s, want %d", rec.Code, rec.Body.String(), tt.wantStatus)
+			}
+			var got, want interface{}
+			if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+				t.Fatalf("response %q is not JSON: %v", rec.Body.String(), err)
+			}
+			json.Unmarshal([]byte(tt.wantBody), &want)
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("body = %s, want %s", rec.Body.String(), tt.wantBody)
+			}
+			if hits.Load() != tt.wantHits {
+				t.Errorf("upstream saw %d requests, want %d", hits.Load(), tt.wantHits)
+			}
+		})
+	}
+}
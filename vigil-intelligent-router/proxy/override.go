@@ -0,0 +1,90 @@
+package proxy
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// methodOverride returns the node pinned for the request's method(s), if any.
+// Batches are only pinned when every call maps to the same node.
+func (h *Handler) methodOverride(reqs []rpcRequest) (string, bool) {
+	if len(h.config.MethodNodeOverrides) == 0 || len(reqs) == 0 {
+		return "", false
+	}
+
+	pinned := ""
+	for _, req := range reqs {
+		nodeID, ok := h.config.MethodNodeOverrides[req.Method]
+		if !ok || (pinned != "" && nodeID != pinned) {
+			return "", false
+		}
+		pinned = nodeID
+	}
+	return pinned, true
+}
+
+// routeToPinnedNode forwards the request to a pinned node, bypassing ML
+// selection. It returns false without writing a response when the node is
+// excluded (blocklisted, draining, in maintenance, SLA-demoted), known to be
+// unhealthy, at its in-flight limit or cannot be reached, so normal routing
+// can take over.
+func (h *Handler) routeToPinnedNode(w http.ResponseWriter, r *http.Request, nodeID string, bodyBytes []byte, startTime time.Time) bool {
+	if reason := h.mlClient.ExclusionReason(nodeID); reason != "" {
+		h.logger.Info("Pinned node is excluded, using normal routing",
+			zap.String("node", nodeID),
+			zap.String("reason", reason))
+		return false
+	}
+
+	targetURL, err := h.mlClient.GetRecommendedNodeURL(nodeID)
+	if err != nil {
+		h.logger.Warn("Pinned node has no URL mapping", zap.String("node", nodeID), zap.Error(err))
+		return false
+	}
+
+	// Unknown health (no metrics for the node) does not block the pin
+	health, err := h.pinnedNodeHealth(r)
+	if err != nil {
+		h.logger.Warn("Could not check pinned node health, routing anyway",
+			zap.String("node", nodeID),
+			zap.Error(err))
+	} else if healthy, known := health[nodeID]; known && !healthy {
+		h.logger.Warn("Pinned node is unhealthy, using normal routing",
+			zap.String("node", nodeID))
+		return false
+	}
+
+	// NODE_MAX_INFLIGHT_<ID> applies to pinned traffic too
+	if !h.acquireNode(nodeID) {
+		h.logger.Info("Pinned node at in-flight capacity, using normal routing",
+			zap.String("node", nodeID),
+			zap.Int("limit", h.config.NodeMaxInflight[nodeID]))
+		return false
+	}
+	defer h.releaseNode(nodeID)
+
+	h.logger.Info("Routing to pinned node",
+		zap.String("node", nodeID),
+		zap.String("url", targetURL))
+
+	if err := h.forwardRequest(w, r, targetURL, bodyBytes, startTime); err != nil {
+		h.mlClient.RecordOutcome(nodeID, false)
+		return false
+	}
+	return true
+}
+
+// pinnedNodeHealth returns the node health seen by the latest recommendation.
+// Metrics are only fetched, within the request's lifetime, before any
+// recommendation has had them.
+func (h *Handler) pinnedNodeHealth(r *http.Request) (map[string]bool, error) {
+	if health, ok := h.mlClient.CachedNodeHealth(); ok {
+		return health, nil
+	}
+	ctx, cancel := context.WithTimeout(h.mlContext(r.Context(), r), h.config.MLQueryTimeout)
+	defer cancel()
+	return h.mlClient.GetNodeHealth(ctx)
+}
@@ -0,0 +1,53 @@
+package proxy
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/project-vigil/vigil-intelligent-router/ml"
+)
+
+func TestMethodNodeOverride(t *testing.T) {
+	tests := []struct {
+		name           string
+		archiveHealthy int
+		want           string
+	}{
+		{"healthy pinned node", 1, "archive"},
+		{"unhealthy pinned node", 0, "a"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig(t)
+			cfg.NodeURLMap = map[string]string{"a": namedUpstream(t, "a"), "archive": namedUpstream(t, "archive")}
+			cfg.MethodNodeOverrides = map[string]string{"getBlock": "archive"}
+			archive := recentMetric("archive", 10)
+			archive.IsHealthy = tt.archiveHealthy
+			fetches := backendStub(t, cfg, []ml.MetricData{recentMetric("a", 10), archive}, ml.PredictionResponse{
+				RecommendedNode: "a",
+				AllPredictions: []ml.NodePrediction{
+					{NodeID: "a", PredictedLatencyMS: 10},
+					{NodeID: "archive", PredictedLatencyMS: 500},
+				},
+			})
+			h := newTestHandler(t, cfg, ml.Options{})
+
+			// Unpinned methods follow the ML and leave health cached behind them
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, newRPCRequest("getSlot"))
+			if want := `{"jsonrpc":"2.0","id":1,"result":"a"}`; rec.Body.String() != want {
+				t.Fatalf("getSlot: got %s, want node a", rec.Body.String())
+			}
+			before := fetches.Load()
+
+			rec = httptest.NewRecorder()
+			h.ServeHTTP(rec, newRPCRequest("getBlock"))
+			if want := `{"jsonrpc":"2.0","id":1,"result":"` + tt.want + `"}`; rec.Body.String() != want {
+				t.Errorf("getBlock: got %s, want node %s", rec.Body.String(), tt.want)
+			}
+			if tt.want == "archive" && fetches.Load() != before {
+				t.Errorf("pinned request fetched metrics %d times, want the cached health", fetches.Load()-before)
+			}
+		})
+	}
+}
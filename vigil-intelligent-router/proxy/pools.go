@@ -0,0 +1,63 @@
+package proxy
+
+import (
+	"github.com/project-vigil/vigil-intelligent-router/ml"
+	"go.uber.org/zap"
+)
+
+// poolBest returns the best-scoring eligible node in pool, if any
+func (h *Handler) poolBest(prediction *ml.PredictionResponse, pool string) (ml.NodePrediction, bool) {
+	var best ml.NodePrediction
+	found := false
+	for _, node := range prediction.AllPredictions {
+		if h.config.NodePools[node.NodeID] != pool {
+			continue
+		}
+		if h.mlClient.NodeIneligibleReason(prediction, node.NodeID) != "" {
+			continue
+		}
+		if !found || node.CostScore < best.CostScore {
+			best = node
+			found = true
+		}
+	}
+	return best, found
+}
+
+// applyNodePools routes within the first pool in NODE_POOL_ORDER whose best
+// node is eligible and predicted under NODE_POOL_LATENCY_THRESHOLD_MS. When
+// every pool is degraded, the last pool with an eligible node is used.
+// Nodes outside any pool are only used when no pool has an eligible node.
+func (h *Handler) applyNodePools(prediction *ml.PredictionResponse) {
+	if len(h.config.NodePools) == 0 {
+		return
+	}
+
+	var chosen ml.NodePrediction
+	var chosenPool string
+	found := false
+	for _, pool := range h.config.NodePoolOrder {
+		best, ok := h.poolBest(prediction, pool)
+		if !ok {
+			continue
+		}
+		chosen, chosenPool, found = best, pool, true
+		threshold := h.config.NodePoolLatencyThresholdMS
+		if threshold <= 0 || best.PredictedLatencyMS <= threshold {
+			break
+		}
+		h.logger.Debug("Node pool degraded, trying next pool",
+			zap.String("pool", pool),
+			zap.String("best_node", best.NodeID),
+			zap.Float64("predicted_latency_ms", best.PredictedLatencyMS))
+	}
+	if !found || chosen.NodeID == prediction.RecommendedNode {
+		return
+	}
+
+	h.logger.Debug("Routing within node pool",
+		zap.String("pool", chosenPool),
+		zap.String("node", chosen.NodeID),
+		zap.String("scored_best", prediction.RecommendedNode))
+	prediction.SelectNode(chosen.NodeID)
+}
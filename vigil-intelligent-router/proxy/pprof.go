@@ -0,0 +1,27 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/pprof"
+
+	"github.com/project-vigil/vigil-intelligent-router/config"
+)
+
+// RegisterPprof mounts the net/http/pprof handlers under /debug/pprof/,
+// protected by the admin token
+func RegisterPprof(mux *http.ServeMux, cfg *config.Config) {
+	adminOnly := func(handler http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if requireAdmin(cfg, w, r) {
+				handler(w, r)
+			}
+		}
+	}
+
+	// pprof.Index also serves named profiles such as heap and goroutine
+	mux.HandleFunc("/debug/pprof/", adminOnly(pprof.Index))
+	mux.HandleFunc("/debug/pprof/cmdline", adminOnly(pprof.Cmdline))
+	mux.HandleFunc("/debug/pprof/profile", adminOnly(pprof.Profile))
+	mux.HandleFunc("/debug/pprof/symbol", adminOnly(pprof.Symbol))
+	mux.HandleFunc("/debug/pprof/trace", adminOnly(pprof.Trace))
+}
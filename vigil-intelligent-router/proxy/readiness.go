@@ -0,0 +1,88 @@
+package proxy
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/project-vigil/vigil-intelligent-router/config"
+	"github.com/project-vigil/vigil-intelligent-router/ml"
+	"go.uber.org/zap"
+)
+
+// ReadinessHandler reports whether the router should receive traffic. It is
+// not ready while fewer than MIN_HEALTHY_NODES nodes are healthy, so
+// orchestration can send traffic elsewhere.
+func ReadinessHandler(mlClient *ml.Client, cfg *config.Config, logger *zap.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+
+		if r.Method == http.MethodOptions {
+			WritePreflight(w, r, cfg.CORSMaxAge)
+			return
+		}
+
+		response := map[string]interface{}{
+			"status": "ready",
+			"time":   time.Now().UTC().Format(time.RFC3339),
+		}
+		if cfg.MinHealthyNodes <= 0 {
+			writeJSON(w, http.StatusOK, response)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), cfg.MLQueryTimeout)
+		defer cancel()
+
+		healthy, err := mlClient.HealthyNodeCount(ctx)
+		response["min_healthy_nodes"] = cfg.MinHealthyNodes
+		if err != nil {
+			logger.Warn("Readiness check could not fetch node health", zap.Error(err))
+			response["status"] = "not_ready"
+			response["error"] = "node health unavailable"
+			writeJSON(w, http.StatusServiceUnavailable, response)
+			return
+		}
+
+		response["healthy_nodes"] = healthy
+		if healthy < cfg.MinHealthyNodes {
+			response["status"] = "not_ready"
+			writeJSON(w, http.StatusServiceUnavailable, response)
+			return
+		}
+		writeJSON(w, http.StatusOK, response)
+	}
+}
+
+// belowMinHealthy reports whether requests should be refused because the
+// latest metrics show fewer than MIN_HEALTHY_NODES healthy nodes
+func (h *Handler) belowMinHealthy() (int, bool) {
+	if h.config.MinHealthyNodes <= 0 || !h.config.MinHealthyNodesReject {
+		return 0, false
+	}
+	healthy, known := h.mlClient.LastHealthyNodeCount()
+	return healthy, known && healthy < h.config.MinHealthyNodes
+}
+
+// serveInsufficientHealthy replies 503 when too few nodes are healthy to serve
+func (h *Handler) serveInsufficientHealthy(w http.ResponseWriter, healthy int) {
+	h.warnings.Warn("Refusing request, too few healthy nodes",
+		zap.Int("healthy_nodes", healthy),
+		zap.Int("min_healthy_nodes", h.config.MinHealthyNodes))
+	writeJSON(w, http.StatusServiceUnavailable, map[string]interface{}{
+		"error":             "insufficient healthy nodes",
+		"healthy_nodes":     healthy,
+		"min_healthy_nodes": h.config.MinHealthyNodes,
+	})
+}
+
+// serveAllAnomalous tells the client service is degraded because the ML
+// flagged every node anomalous
+func (h *Handler) serveAllAnomalous(w http.ResponseWriter) {
+	writeJSON(w, http.StatusServiceUnavailable, map[string]interface{}{
+		"error":  "degraded service: all nodes flagged anomalous",
+		"policy": h.config.AllAnomalousPolicy,
+	})
+}
@@ -0,0 +1,116 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/big"
+
+	"go.uber.org/zap"
+)
+
+// maxIDCheckBytes caps how much of a response is buffered for id validation.
+// Solana nodes put "id" after "result", so larger responses are not checked.
+const maxIDCheckBytes = 1 << 20
+
+// cappedBuffer keeps up to limit bytes and silently discards the rest, so it can
+// sit behind an io.TeeReader without ever failing the client copy
+type cappedBuffer struct {
+	buf       bytes.Buffer
+	limit     int
+	truncated bool
+}
+
+func newCappedBuffer(limit int) *cappedBuffer {
+	return &cappedBuffer{limit: limit}
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	if room := b.limit - b.buf.Len(); room < len(p) {
+		b.truncated = true
+		if room > 0 {
+			b.buf.Write(p[:room])
+		}
+		return len(p), nil
+	}
+	return b.buf.Write(p)
+}
+
+// expectedResponseID returns the id of a single (non-batch) request when id
+// validation is enabled and the request carries an id
+func (h *Handler) expectedResponseID(bodyBytes []byte) (json.RawMessage, bool) {
+	if !h.config.ValidateRPCID {
+		return nil, false
+	}
+	reqs, batch, err := parseRPCRequests(bodyBytes)
+	if err != nil || batch || len(reqs) != 1 || len(reqs[0].ID) == 0 {
+		return nil, false
+	}
+	return reqs[0].ID, true
+}
+
+// checkResponseID compares the upstream response id against the request id and
+// flags mismatches, which point at a misbehaving or caching upstream
+func (h *Handler) checkResponseID(expected json.RawMessage, capture *cappedBuffer, targetURL string) {
+	if capture.truncated {
+		h.logger.Debug("Response too large for id validation",
+			zap.String("target", targetURL))
+		return
+	}
+
+	var resp struct {
+		ID json.RawMessage `json:"id"`
+	}
+	if err := json.Unmarshal(capture.buf.Bytes(), &resp); err != nil {
+		// Non-JSON bodies (e.g. HTTP errors) are not an id mismatch
+		return
+	}
+
+	if !sameRPCID(expected, resp.ID) {
+		h.idMismatches.Add(1)
+		h.warnings.Warn("Upstream response id does not match request id",
+			zap.String("target", targetURL),
+			zap.ByteString("expected_id", expected),
+			zap.ByteString("response_id", resp.ID))
+	}
+}
+
+// rpcIDOrNull returns id unchanged for echoing in a response, or null when the
+// request had none. The raw bytes are kept so ids round-trip exactly.
+func rpcIDOrNull(id json.RawMessage) json.RawMessage {
+	if len(id) == 0 {
+		return json.RawMessage("null")
+	}
+	return id
+}
+
+// canonicalRPCID reduces an id to a comparable form. Strings compare by their
+// decoded value and numbers by numeric value, so "1.0" matches 1 but "1" (a
+// string) does not. Absent ids compare equal to null.
+func canonicalRPCID(id json.RawMessage) string {
+	trimmed := bytes.TrimSpace(id)
+	if len(trimmed) == 0 || string(trimmed) == "null" {
+		return "null"
+	}
+	switch c := trimmed[0]; {
+	case c == '"':
+		var s string
+		if err := json.Unmarshal(trimmed, &s); err == nil {
+			return "s:" + s
+		}
+	case c == '-' || (c >= '0' && c <= '9'):
+		if n, ok := new(big.Rat).SetString(string(trimmed)); ok {
+			return "n:" + n.RatString()
+		}
+	}
+	// Objects and arrays are not valid ids; compare them byte for byte
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, trimmed); err != nil {
+		return "raw:" + string(trimmed)
+	}
+	return "raw:" + buf.String()
+}
+
+// sameRPCID reports whether two raw ids identify the same request
+func sameRPCID(a, b json.RawMessage) bool {
+	return canonicalRPCID(a) == canonicalRPCID(b)
+}
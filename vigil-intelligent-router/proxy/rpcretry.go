@@ -0,0 +1,155 @@
+package proxy
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/project-vigil/vigil-intelligent-router/ml"
+	"go.uber.org/zap"
+)
+
+// responseBuffer captures a response so it can be inspected before it is
+// passed on to the client
+type responseBuffer struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func newResponseBuffer() *responseBuffer {
+	return &responseBuffer{header: make(http.Header)}
+}
+
+func (b *responseBuffer) Header() http.Header {
+	return b.header
+}
+
+func (b *responseBuffer) WriteHeader(status int) {
+	if b.status == 0 {
+		b.status = status
+	}
+}
+
+func (b *responseBuffer) Write(p []byte) (int, error) {
+	if b.status == 0 {
+		b.status = http.StatusOK
+	}
+	return b.body.Write(p)
+}
+
+// flushTo writes the captured response to w
+func (b *responseBuffer) flushTo(w http.ResponseWriter) {
+	for key, values := range b.header {
+		for _, value := range values {
+			w.Header().Add(key, value)
+		}
+	}
+	if b.status == 0 {
+		b.status = http.StatusOK
+	}
+	w.WriteHeader(b.status)
+	w.Write(b.body.Bytes())
+}
+
+// rpcRetryEnabled reports whether the request may be retried on a retryable
+// JSON-RPC error. Only single calls expecting a response qualify.
+func (h *Handler) rpcRetryEnabled(reqs []rpcRequest, batch bool) bool {
+	return len(h.config.RetryableRPCCodes) > 0 &&
+		!batch && len(reqs) == 1 && !reqs[0].isNotification()
+}
+
+// retryableRPCCode returns the JSON-RPC error code of a buffered 200 response
+// when it is listed in RETRYABLE_RPC_CODES
+func (h *Handler) retryableRPCCode(buf *responseBuffer) (int, bool) {
+	if buf.status != http.StatusOK {
+		return 0, false
+	}
+
+	var body io.Reader = bytes.NewReader(buf.body.Bytes())
+	if buf.header.Get("Content-Encoding") == "gzip" {
+		gz, err := gzip.NewReader(body)
+		if err != nil {
+			return 0, false
+		}
+		defer gz.Close()
+		body = gz
+	}
+
+	var resp struct {
+		Error *rpcError `json:"error"`
+	}
+	if err := json.NewDecoder(body).Decode(&resp); err != nil || resp.Error == nil {
+		return 0, false
+	}
+	return resp.Error.Code, slices.Contains(h.config.RetryableRPCCodes, resp.Error.Code)
+}
+
+// nextRetryNode reserves a slot on the best eligible node not tried yet
+func (h *Handler) nextRetryNode(prediction *ml.PredictionResponse, tried map[string]bool) (string, string, bool) {
+	for _, node := range prediction.RankedPredictions() {
+		if tried[node.NodeID] || h.mlClient.NodeIneligibleReason(prediction, node.NodeID) != "" {
+			continue
+		}
+		url, err := h.mlClient.GetRecommendedNodeURL(node.NodeID)
+		if err != nil {
+			continue
+		}
+		if h.acquireNode(node.NodeID) {
+			return node.NodeID, url, true
+		}
+	}
+	return "", "", false
+}
+
+// forwardWithRPCRetry forwards like forwardRequestWithCalibration, but buffers
+// the response and fails over to the next-best node when the upstream answers
+// 200 with a code from RETRYABLE_RPC_CODES. As with other failover, each node is
+// tried once and attempts are paced by FAILOVER_BACKOFF_MS within the request
+// timeout. The last response is returned to the client when no node is left.
+func (h *Handler) forwardWithRPCRetry(w http.ResponseWriter, r *http.Request, targetURL string, bodyBytes []byte, startTime time.Time, prediction *ml.PredictionResponse) error {
+	tried := make(map[string]bool)
+	var last *responseBuffer
+
+	for attempt := 1; ; attempt++ {
+		tried[prediction.RecommendedNode] = true
+
+		buf := newResponseBuffer()
+		if err := h.forwardRequestWithCalibration(buf, r, targetURL, bodyBytes, startTime, prediction); err != nil {
+			if last == nil {
+				return err
+			}
+			// The retry node was unreachable; the earlier error is still a valid answer
+			last.flushTo(w)
+			return nil
+		}
+		last = buf
+
+		code, retryable := h.retryableRPCCode(buf)
+		if !retryable || clientGone(r) || !h.failoverTimeLeft(startTime) {
+			buf.flushTo(w)
+			return nil
+		}
+
+		nodeID, url, ok := h.nextRetryNode(prediction, tried)
+		if !ok {
+			buf.flushTo(w)
+			return nil
+		}
+		defer h.releaseNode(nodeID)
+		h.waitFailoverBackoff(r, startTime)
+
+		h.logger.Info("Retrying request on another node after retryable JSON-RPC error",
+			zap.String("node", prediction.RecommendedNode),
+			zap.Int("rpc_code", code),
+			zap.String("retry_node", nodeID),
+			zap.Int("attempt", attempt))
+		prediction.SelectNode(nodeID)
+		prediction.MarkDecided()
+		targetURL = url
+	}
+}
@@ -0,0 +1,79 @@
+package proxy
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/project-vigil/vigil-intelligent-router/ml"
+)
+
+// rpcErrorUpstream answers every request with a JSON-RPC error and counts them
+func rpcErrorUpstream(t *testing.T, code int, hits *atomic.Int64) string {
+	t.Helper()
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","id":1,"error":{"code":%d,"message":"node unavailable"}}`, code)
+	}))
+	t.Cleanup(upstream.Close)
+	return upstream.URL
+}
+
+func TestRetryableRPCCodeFailsOver(t *testing.T) {
+	var behindHits, missingHits atomic.Int64
+	cfg := testConfig(t)
+	cfg.NodeURLMap = map[string]string{
+		"behind":  rpcErrorUpstream(t, -32005, &behindHits),
+		"missing": rpcErrorUpstream(t, -32004, &missingHits),
+		"healthy": namedUpstream(t, "healthy"),
+	}
+	cfg.RetryableRPCCodes = []int{-32005, -32004}
+	backendStub(t, cfg, nil, ml.PredictionResponse{
+		RecommendedNode: "behind",
+		AllPredictions: []ml.NodePrediction{
+			{NodeID: "behind", PredictedLatencyMS: 10},
+			{NodeID: "missing", PredictedLatencyMS: 20},
+			{NodeID: "healthy", PredictedLatencyMS: 30},
+		},
+	})
+	h := newTestHandler(t, cfg, ml.Options{})
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, newRPCRequest("getBlock"))
+	if want := `{"jsonrpc":"2.0","id":1,"result":"healthy"}`; rec.Body.String() != want {
+		t.Fatalf("got %d %s, want node healthy", rec.Code, rec.Body.String())
+	}
+	if behindHits.Load() != 1 || missingHits.Load() != 1 {
+		t.Errorf("erroring nodes hit %d and %d times, want once each", behindHits.Load(), missingHits.Load())
+	}
+}
+
+func TestRetryableRPCCodeLastResponseWhenNodesRunOut(t *testing.T) {
+	var hits atomic.Int64
+	cfg := testConfig(t)
+	cfg.NodeURLMap = map[string]string{
+		"a": rpcErrorUpstream(t, -32005, &hits),
+		"b": rpcErrorUpstream(t, -32005, &hits),
+	}
+	cfg.RetryableRPCCodes = []int{-32005}
+	backendStub(t, cfg, nil, ml.PredictionResponse{
+		RecommendedNode: "a",
+		AllPredictions: []ml.NodePrediction{
+			{NodeID: "a", PredictedLatencyMS: 10},
+			{NodeID: "b", PredictedLatencyMS: 20},
+		},
+	})
+	h := newTestHandler(t, cfg, ml.Options{})
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, newRPCRequest("getBlock"))
+	if rec.Code != http.StatusOK || hits.Load() != 2 {
+		t.Fatalf("got %d after %d attempts, want the error passed on after 2", rec.Code, hits.Load())
+	}
+	if want := `{"jsonrpc":"2.0","id":1,"error":{"code":-32005,"message":"node unavailable"}}`; rec.Body.String() != want {
+		t.Errorf("body = %s, want the last node's error", rec.Body.String())
+	}
+}
@@ -0,0 +1,119 @@
+package proxy
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// maxShadowForwardInflight bounds concurrent shadow copies; samples beyond it
+// are skipped rather than queued
+const maxShadowForwardInflight = 32
+
+// shadowForwardStats records how the shadow node handles its copies
+type shadowForwardStats struct {
+	counter  atomic.Int64
+	inflight atomic.Int64
+	skipped  atomic.Int64
+
+	mutex  sync.Mutex
+	tally  latencyTally
+	errors int64
+}
+
+// startShadowForward sends a copy of the request to SHADOW_FORWARD_NODE in
+// the background for SHADOW_FORWARD_PERCENT of read requests. The copy's
+// response is discarded; only its latency and success are recorded, so the
+// client never waits on or sees the shadow node. Writes are never copied.
+func (h *Handler) startShadowForward(r *http.Request, reqs []rpcRequest, parseErr error, bodyBytes []byte) {
+	nodeID := h.config.ShadowForwardNode
+	if nodeID == "" || h.config.ShadowForwardPercent <= 0 || !readOnlyRequest(reqs, parseErr) {
+		return
+	}
+	if !sampleTurn(&h.shadowForward.counter, h.config.ShadowForwardPercent) {
+		return
+	}
+	if h.shadowForward.inflight.Add(1) > maxShadowForwardInflight {
+		h.shadowForward.inflight.Add(-1)
+		h.shadowForward.skipped.Add(1)
+		return
+	}
+
+	targetURL := h.config.NodeURLMap[nodeID]
+	req, err := h.newUpstreamRequest(r, targetURL, bodyBytes)
+	if err != nil {
+		h.shadowForward.inflight.Add(-1)
+		h.recordShadowForward(0, false, err)
+		return
+	}
+
+	go func() {
+		defer h.shadowForward.inflight.Add(-1)
+
+		// Detached from the client so the copy outlives a fast primary response
+		ctx, cancel := context.WithTimeout(context.Background(), h.config.RequestTimeout)
+		defer cancel()
+
+		start := time.Now()
+		resp, err := h.clientFor(targetURL).Do(req.WithContext(ctx))
+		if err != nil {
+			h.recordShadowForward(0, false, err)
+			return
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		latency := time.Since(start)
+		if err != nil {
+			h.recordShadowForward(0, false, err)
+			return
+		}
+
+		success := resp.StatusCode >= 200 && resp.StatusCode < 300 && rpcResponseOK(body)
+		h.recordShadowForward(float64(latency.Milliseconds()), success, nil)
+		h.logger.Debug("Shadow request completed",
+			zap.String("node", nodeID),
+			zap.Int("status", resp.StatusCode),
+			zap.Bool("success", success),
+			zap.Duration("latency", latency))
+	}()
+}
+
+// recordShadowForward tallies one shadow copy; err is set when no response arrived
+func (h *Handler) recordShadowForward(latencyMS float64, success bool, err error) {
+	if err != nil {
+		h.logger.Debug("Shadow request failed",
+			zap.String("node", h.config.ShadowForwardNode),
+			zap.Error(err))
+	}
+
+	h.shadowForward.mutex.Lock()
+	defer h.shadowForward.mutex.Unlock()
+
+	h.shadowForward.tally.requests++
+	if err != nil {
+		h.shadowForward.errors++
+		return
+	}
+	if success {
+		h.shadowForward.tally.successes++
+		h.shadowForward.tally.latencySumMS += latencyMS
+	}
+}
+
+// shadowForwardReport summarizes shadow forwarding for /stats
+func (h *Handler) shadowForwardReport() map[string]interface{} {
+	h.shadowForward.mutex.Lock()
+	defer h.shadowForward.mutex.Unlock()
+
+	report := h.shadowForward.tally.report()
+	report["node"] = h.config.ShadowForwardNode
+	report["percent"] = h.config.ShadowForwardPercent
+	report["errors"] = h.shadowForward.errors
+	report["skipped"] = h.shadowForward.skipped.Load()
+	return report
+}
@@ -0,0 +1,240 @@
+package proxy
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/project-vigil/vigil-intelligent-router/config"
+)
+
+// maxSignatureEntries bounds the signature affinity table; expired entries are
+// swept once it is reached
+const maxSignatureEntries = 100000
+
+// signatureStatusMethods look up a transaction by its signature
+var signatureStatusMethods = map[string]bool{
+	"getSignatureStatuses": true,
+	"confirmTransaction":   true,
+	"getTransaction":       true,
+}
+
+// signatureEntry is the node a transaction was submitted to
+type signatureEntry struct {
+	nodeID  string
+	expires time.Time
+}
+
+// signatureAffinity remembers which node each submitted transaction was sent
+// to, so status queries for it go to the node most likely to know about it
+type signatureAffinity struct {
+	mutex   sync.Mutex
+	entries map[string]signatureEntry
+	ttl     time.Duration
+}
+
+// newSignatureAffinity returns nil unless SIGNATURE_AFFINITY_ENABLED is set
+func newSignatureAffinity(cfg *config.Config) *signatureAffinity {
+	if !cfg.SignatureAffinityEnabled {
+		return nil
+	}
+	return &signatureAffinity{
+		entries: make(map[string]signatureEntry),
+		ttl:     cfg.SignatureAffinityTTL,
+	}
+}
+
+func (s *signatureAffinity) remember(signature, nodeID string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := time.Now()
+	if len(s.entries) >= maxSignatureEntries {
+		for sig, entry := range s.entries {
+			if now.After(entry.expires) {
+				delete(s.entries, sig)
+			}
+		}
+		if len(s.entries) >= maxSignatureEntries {
+			return
+		}
+	}
+	s.entries[signature] = signatureEntry{nodeID: nodeID, expires: now.Add(s.ttl)}
+}
+
+func (s *signatureAffinity) lookup(signature string) (string, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	entry, ok := s.entries[signature]
+	if !ok {
+		return "", false
+	}
+	if time.Now().After(entry.expires) {
+		delete(s.entries, signature)
+		return "", false
+	}
+	return entry.nodeID, true
+}
+
+// signatureNode returns the node the request's transactions were submitted
+// to. Every call must be a status query and every signature must map to the
+// same node.
+func (h *Handler) signatureNode(reqs []rpcRequest) (string, bool) {
+	if h.signatures == nil || len(reqs) == 0 {
+		return "", false
+	}
+
+	pinned := ""
+	for _, req := range reqs {
+		signatures := statusSignatures(req)
+		if len(signatures) == 0 {
+			return "", false
+		}
+		for _, signature := range signatures {
+			nodeID, ok := h.signatures.lookup(signature)
+			if !ok || (pinned != "" && nodeID != pinned) {
+				return "", false
+			}
+			pinned = nodeID
+		}
+	}
+	return pinned, true
+}
+
+// rememberSignatures records nodeID for every transaction the request submitted
+func (h *Handler) rememberSignatures(reqs []rpcRequest, nodeID string) {
+	if h.signatures == nil || nodeID == "" {
+		return
+	}
+	for _, req := range reqs {
+		if signature, ok := transactionSignature(req); ok {
+			h.signatures.remember(signature, nodeID)
+		}
+	}
+}
+
+// statusSignatures returns the signatures a status query asks about
+func statusSignatures(req rpcRequest) []string {
+	if !signatureStatusMethods[req.Method] {
+		return nil
+	}
+	var params []json.RawMessage
+	if err := json.Unmarshal(req.Params, &params); err != nil || len(params) == 0 {
+		return nil
+	}
+
+	// getSignatureStatuses takes a list, the others a single signature
+	var signatures []string
+	if err := json.Unmarshal(params[0], &signatures); err == nil {
+		return signatures
+	}
+	var signature string
+	if err := json.Unmarshal(params[0], &signature); err == nil && signature != "" {
+		return []string{signature}
+	}
+	return nil
+}
+
+// transactionSignature returns the first signature of the transaction sent
+// by a sendTransaction call, which is the transaction's id
+func transactionSignature(req rpcRequest) (string, bool) {
+	if req.Method != "sendTransaction" {
+		return "", false
+	}
+	var params []json.RawMessage
+	if err := json.Unmarshal(req.Params, &params); err != nil || len(params) == 0 {
+		return "", false
+	}
+	var encoded string
+	if err := json.Unmarshal(params[0], &encoded); err != nil {
+		return "", false
+	}
+	var options struct {
+		Encoding string `json:"encoding"`
+	}
+	if len(params) > 1 {
+		json.Unmarshal(params[1], &options)
+	}
+
+	var tx []byte
+	var err error
+	if options.Encoding == "base64" {
+		tx, err = base64.StdEncoding.DecodeString(encoded)
+	} else {
+		tx, err = decodeBase58(encoded)
+	}
+	if err != nil {
+		return "", false
+	}
+
+	// A transaction starts with a compact-u16 signature count, then the signatures
+	count, n := decodeShortVec(tx)
+	if n == 0 || count == 0 || len(tx) < n+64 {
+		return "", false
+	}
+	return encodeBase58(tx[n : n+64]), true
+}
+
+// decodeShortVec reads Solana's compact-u16 length prefix, returning the value
+// and the bytes it used (0 if malformed)
+func decodeShortVec(b []byte) (int, int) {
+	value := 0
+	for i := 0; i < 3 && i < len(b); i++ {
+		value |= int(b[i]&0x7f) << (7 * i)
+		if b[i]&0x80 == 0 {
+			return value, i + 1
+		}
+	}
+	return 0, 0
+}
+
+const base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+var errInvalidBase58 = errors.New("invalid base58 string")
+
+func decodeBase58(s string) ([]byte, error) {
+	value := new(big.Int)
+	radix := big.NewInt(58)
+	for _, c := range []byte(s) {
+		digit := strings.IndexByte(base58Alphabet, c)
+		if digit < 0 {
+			return nil, errInvalidBase58
+		}
+		value.Mul(value, radix)
+		value.Add(value, big.NewInt(int64(digit)))
+	}
+
+	// Each leading '1' encodes a leading zero byte
+	zeros := 0
+	for zeros < len(s) && s[zeros] == '1' {
+		zeros++
+	}
+	return append(make([]byte, zeros), value.Bytes()...), nil
+}
+
+func encodeBase58(b []byte) string {
+	value := new(big.Int).SetBytes(b)
+	radix := big.NewInt(58)
+	mod := new(big.Int)
+
+	var out []byte
+	for value.Sign() > 0 {
+		value.DivMod(value, radix, mod)
+		out = append(out, base58Alphabet[mod.Int64()])
+	}
+	for _, c := range b {
+		if c != 0 {
+			break
+		}
+		out = append(out, '1')
+	}
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+	return string(out)
+}
@@ -0,0 +1,149 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/project-vigil/vigil-intelligent-router/ml"
+)
+
+// A legacy System Program transfer of 1000 lamports, signed with the ed25519
+// key from seed 0x01..0x20, and its signature
+const (
+	testTransactionHex = "018651174720da8fea834ce9df90d9889e127ee90c25be27e3770b58206c7db0e2e2088d1561427bc9ab70675738d41c68d68f334c86dd31ee2b338e018d0a860a" +
+		"0100010379b5562e8fe654f94078b112e8a98ba7901f853ae695bed7e0e3910bad049664a0a1a2a3a4a5a6a7a8a9aaabacadaeafb0b1b2b3b4b5b6b7b8b9babbbcbdbebf" +
+		"0000000000000000000000000000000000000000000000000000000000000000404142434445464748494a4b4c4d4e4f505152535455565758595a5b5c5d5e5f" +
+		"01020200010c02000000e803000000000000"
+	testTransactionBase58 = "5Du2vom4m83kYgErBeUcYUqmoAne8B8sP5Ru3Nwq41SPp3cgPvJGoYdXHm3DCNkzMuV1wBHq6FuuCg3FBvpCQzW6v9cVdc8SVajtbng1WPWLz2LChx3sATSFdTErc1LYXjgZ9UFX7xM1Fu3zQG61kzhBo8kYXGz7cFTeKKCdFmyXrQthNss7wRZXVhWkxUkhs8bktGMopKePPwX6gsHEB8okb3Bzd95LH2ApahrdNDMcXYxS3L2QD51oorhj9zhwEGLukVY6KYQXuhn1xvoikCc3n7Ry3hCRrQB6F"
+	testSignature         = "3gkm7QDJQ3hon9Q6khmaFihCtPt3iD4jj8n6d8g4g64Ssmde7ZqCHb9CnbcNhbyWSmcHeq14mSPc8uotcepKaxDo"
+)
+
+func TestBase58(t *testing.T) {
+	tests := []struct {
+		hex     string
+		encoded string
+	}{
+		{"", ""},
+		{"00", "1"},
+		{"0000287fb4cd", "11233QC4"},
+		{hex.EncodeToString([]byte("Hello World!")), "2NEpo7TZRRrLZSi2U"},
+		{testTransactionHex, testTransactionBase58},
+	}
+	for _, tt := range tests {
+		raw, _ := hex.DecodeString(tt.hex)
+		if got := encodeBase58(raw); got != tt.encoded {
+			t.Errorf("encodeBase58(%s) = %s, want %s", tt.hex, got, tt.encoded)
+		}
+		decoded, err := decodeBase58(tt.encoded)
+		if err != nil || !bytes.Equal(decoded, raw) {
+			t.Errorf("decodeBase58(%s) = %x, %v; want %s", tt.encoded, decoded, err, tt.hex)
+		}
+	}
+
+	for _, invalid := range []string{"0", "O", "I", "l", "abc+"} {
+		if _, err := decodeBase58(invalid); err == nil {
+			t.Errorf("decodeBase58(%q) accepted an invalid string", invalid)
+		}
+	}
+}
+
+func TestDecodeShortVec(t *testing.T) {
+	tests := []struct {
+		in    []byte
+		value int
+		n     int
+	}{
+		{[]byte{0x00}, 0, 1},
+		{[]byte{0x01, 0xff}, 1, 1},
+		{[]byte{0x7f}, 127, 1},
+		{[]byte{0x80, 0x01}, 128, 2},
+		{[]byte{0xff, 0x7f}, 16383, 2},
+		{[]byte{0x80, 0x80, 0x01}, 16384, 3},
+		{[]byte{0xff, 0xff, 0x03}, 65535, 3},
+		// Truncated or longer than three bytes
+		{[]byte{}, 0, 0},
+		{[]byte{0x80}, 0, 0},
+		{[]byte{0x80, 0x80, 0x80, 0x01}, 0, 0},
+	}
+	for _, tt := range tests {
+		if value, n := decodeShortVec(tt.in); value != tt.value || n != tt.n {
+			t.Errorf("decodeShortVec(%x) = %d, %d; want %d, %d", tt.in, value, n, tt.value, tt.n)
+		}
+	}
+}
+
+func TestTransactionSignature(t *testing.T) {
+	raw, _ := hex.DecodeString(testTransactionHex)
+	tests := []struct {
+		name   string
+		params string
+	}{
+		{"base58", `["` + testTransactionBase58 + `"]`},
+		{"base64", `["` + base64.StdEncoding.EncodeToString(raw) + `",{"encoding":"base64"}]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := rpcRequest{Method: "sendTransaction", Params: json.RawMessage(tt.params)}
+			if got, ok := transactionSignature(req); !ok || got != testSignature {
+				t.Errorf("transactionSignature = %q, %v; want %s", got, ok, testSignature)
+			}
+		})
+	}
+}
+
+func TestSignatureAffinityRoutesStatusToSender(t *testing.T) {
+	// The ML service changes its mind between the send and the status query
+	cfg := testConfig(t)
+	var recommended atomic.Value
+	recommended.Store("a")
+	stub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == cfg.MetricsEndpoint {
+			w.Write([]byte(`[]`))
+			return
+		}
+		node := recommended.Load().(string)
+		json.NewEncoder(w).Encode(ml.PredictionResponse{
+			RecommendedNode: node,
+			AllPredictions:  []ml.NodePrediction{{NodeID: node, PredictedLatencyMS: 10}},
+		})
+	}))
+	t.Cleanup(stub.Close)
+
+	cfg.MLServiceURL = stub.URL
+	cfg.DataCollectorURL = stub.URL
+	cfg.NodeURLMap = map[string]string{"a": namedUpstream(t, "a"), "b": namedUpstream(t, "b")}
+	cfg.SignatureAffinityEnabled = true
+	h := newTestHandler(t, cfg, ml.Options{})
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, rpcCall(`{"jsonrpc":"2.0","id":1,"method":"sendTransaction","params":["`+testTransactionBase58+`"]}`))
+	if want := `{"jsonrpc":"2.0","id":1,"result":"a"}`; rec.Body.String() != want {
+		t.Fatalf("sendTransaction: got %d %s, want node a", rec.Code, rec.Body.String())
+	}
+
+	recommended.Store("b")
+	for _, query := range []string{
+		`{"jsonrpc":"2.0","id":2,"method":"getSignatureStatuses","params":[["` + testSignature + `"]]}`,
+		`{"jsonrpc":"2.0","id":3,"method":"getTransaction","params":["` + testSignature + `"]}`,
+	} {
+		rec = httptest.NewRecorder()
+		h.ServeHTTP(rec, rpcCall(query))
+		if want := `{"jsonrpc":"2.0","id":1,"result":"a"}`; rec.Body.String() != want {
+			t.Errorf("status query %s: got %s, want node a", query, rec.Body.String())
+		}
+	}
+
+	// Signatures it has not seen follow the ML recommendation
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, rpcCall(`{"jsonrpc":"2.0","id":4,"method":"getTransaction","params":["`+encodeBase58(make([]byte, 64))+`"]}`))
+	if want := `{"jsonrpc":"2.0","id":1,"result":"b"}`; rec.Body.String() != want {
+		t.Errorf("unknown signature: got %s, want node b", rec.Body.String())
+	}
+}
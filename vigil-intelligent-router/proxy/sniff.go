@@ -0,0 +1,39 @@
+package proxy
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+)
+
+// sniffBytes is how much of the body is inspected before reading the rest
+const sniffBytes = 512
+
+// bodyLooksLikeJSON peeks at the start of a POST body and reports whether its
+// first non-whitespace byte can begin a JSON-RPC object or batch. Empty and
+// all-whitespace prefixes pass so the later checks can report them. The peeked
+// bytes are kept: r.Body is replaced with a reader that returns them first.
+func bodyLooksLikeJSON(r *http.Request) bool {
+	if r.Method == http.MethodGet || r.Body == nil {
+		return true
+	}
+
+	buffered := bufio.NewReaderSize(r.Body, sniffBytes)
+	r.Body = struct {
+		io.Reader
+		io.Closer
+	}{buffered, r.Body}
+
+	prefix, _ := buffered.Peek(sniffBytes)
+	for _, b := range prefix {
+		switch b {
+		case ' ', '\t', '\r', '\n':
+			continue
+		case '{', '[':
+			return true
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,313 @@
+package proxy
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/project-vigil/vigil-intelligent-router/config"
+	"go.uber.org/zap"
+)
+
+// maxStaleBodyBytes caps the size of a response kept for STALE_ON_ERROR
+const maxStaleBodyBytes = 1 << 20
+
+// writeRPCMethods change chain state, so their responses are never replayed
+var writeRPCMethods = map[string]bool{
+	"sendTransaction": true,
+	"requestAirdrop":  true,
+}
+
+// staleEntry is the last good response to one request. ids are the JSON-RPC
+// ids of the request it answered.
+type staleEntry struct {
+	body        []byte
+	contentType string
+	ids         []json.RawMessage
+	storedAt    time.Time
+}
+
+// staleCache keeps recent good responses to read-only requests, keyed by a
+// hash of the calls they answer
+type staleCache struct {
+	mutex      sync.Mutex
+	entries    map[string]staleEntry
+	ttl        time.Duration
+	maxEntries int
+}
+
+// newStaleCache returns nil unless STALE_ON_ERROR_ENABLED is set
+func newStaleCache(cfg *config.Config) *staleCache {
+	if !cfg.StaleOnErrorEnabled {
+		return nil
+	}
+	return &staleCache{
+		entries:    make(map[string]staleEntry),
+		ttl:        cfg.StaleOnErrorTTL,
+		maxEntries: cfg.StaleOnErrorMaxEntries,
+	}
+}
+
+// staleKey hashes the calls in a request body without their ids, so clients
+// numbering their requests differently share an entry. It also returns the
+// request ids in order.
+func staleKey(body []byte) (string, []json.RawMessage, bool) {
+	reqs, batch, err := parseRPCRequests(body)
+	if err != nil {
+		return "", nil, false
+	}
+
+	type call struct {
+		Method       string          `json:"method"`
+		Params       json.RawMessage `json:"params"`
+		Notification bool            `json:"notification"`
+	}
+	calls := make([]call, 0, len(reqs))
+	ids := make([]json.RawMessage, 0, len(reqs))
+	for _, req := range reqs {
+		calls = append(calls, call{Method: req.Method, Params: req.Params, Notification: req.isNotification()})
+		ids = append(ids, req.ID)
+	}
+	encoded, err := json.Marshal(struct {
+		Batch bool   `json:"batch"`
+		Calls []call `json:"calls"`
+	}{batch, calls})
+	if err != nil {
+		return "", nil, false
+	}
+	sum := sha256.Sum256(encoded)
+	return hex.EncodeToString(sum[:]), ids, true
+}
+
+// staleResponse is a JSON-RPC response replayed from the stale cache
+type staleResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   json.RawMessage `json:"error,omitempty"`
+	ID      json.RawMessage `json:"id"`
+}
+
+// withRequestIDs rewrites a cached response so it answers the request being
+// served: each response id from the cached request is replaced by the id at
+// the same position in ids. Batch responses may come in any order, so they are
+// matched by id rather than position.
+func withRequestIDs(entry staleEntry, ids []json.RawMessage) ([]byte, bool) {
+	replacements := make(map[string]json.RawMessage, len(ids))
+	for i, id := range entry.ids {
+		if _, ok := replacements[canonicalRPCID(id)]; !ok && i < len(ids) {
+			replacements[canonicalRPCID(id)] = rpcIDOrNull(ids[i])
+		}
+	}
+
+	trimmed := bytes.TrimSpace(entry.body)
+	batch := len(trimmed) > 0 && trimmed[0] == '['
+	var responses []staleResponse
+	if batch {
+		if err := json.Unmarshal(trimmed, &responses); err != nil {
+			return nil, false
+		}
+	} else {
+		var single staleResponse
+		if err := json.Unmarshal(trimmed, &single); err != nil {
+			return nil, false
+		}
+		responses = []staleResponse{single}
+	}
+
+	for i := range responses {
+		id, ok := replacements[canonicalRPCID(responses[i].ID)]
+		if !ok {
+			return nil, false
+		}
+		responses[i].ID = id
+	}
+
+	if batch {
+		body, err := json.Marshal(responses)
+		return body, err == nil
+	}
+	body, err := json.Marshal(responses[0])
+	return body, err == nil
+}
+
+func (c *staleCache) store(key string, entry staleEntry) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if _, ok := c.entries[key]; !ok && len(c.entries) >= c.maxEntries {
+		now := time.Now()
+		for k, e := range c.entries {
+			if now.Sub(e.storedAt) > c.ttl {
+				delete(c.entries, k)
+			}
+		}
+		// Still full: drop an arbitrary entry to make room
+		for k := range c.entries {
+			if len(c.entries) < c.maxEntries {
+				break
+			}
+			delete(c.entries, k)
+		}
+	}
+	c.entries[key] = entry
+}
+
+func (c *staleCache) load(key string) (staleEntry, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		return staleEntry{}, false
+	}
+	if time.Since(entry.storedAt) > c.ttl {
+		delete(c.entries, key)
+		return staleEntry{}, false
+	}
+	return entry, true
+}
+
+// readOnlyRequest reports whether every call in the request is a read
+func readOnlyRequest(reqs []rpcRequest, parseErr error) bool {
+	if parseErr != nil || len(reqs) == 0 {
+		return false
+	}
+	for _, req := range reqs {
+		if writeRPCMethods[req.Method] {
+			return false
+		}
+	}
+	return true
+}
+
+// rpcResponseOK reports whether a JSON-RPC response (single or batch) carries
+// no error objects
+func rpcResponseOK(body []byte) bool {
+	type rpcResult struct {
+		Error json.RawMessage `json:"error"`
+	}
+	var responses []rpcResult
+	trimmed := bytes.TrimSpace(body)
+	if len(trimmed) > 0 && trimmed[0] == '[' {
+		if err := json.Unmarshal(trimmed, &responses); err != nil {
+			return false
+		}
+	} else {
+		var single rpcResult
+		if err := json.Unmarshal(trimmed, &single); err != nil {
+			return false
+		}
+		responses = []rpcResult{single}
+	}
+	for _, response := range responses {
+		if len(response.Error) > 0 && string(response.Error) != "null" {
+			return false
+		}
+	}
+	return true
+}
+
+// staleRecorder copies a response so it can be cached once complete
+type staleRecorder struct {
+	http.ResponseWriter
+	status   int
+	body     bytes.Buffer
+	overflow bool
+}
+
+func (s *staleRecorder) WriteHeader(status int) {
+	if s.status == 0 {
+		s.status = status
+	}
+	s.ResponseWriter.WriteHeader(status)
+}
+
+func (s *staleRecorder) Write(p []byte) (int, error) {
+	if s.status == 0 {
+		s.status = http.StatusOK
+	}
+	if !s.overflow {
+		if s.body.Len()+len(p) > maxStaleBodyBytes {
+			s.overflow = true
+			s.body.Reset()
+		} else {
+			s.body.Write(p)
+		}
+	}
+	return s.ResponseWriter.Write(p)
+}
+
+func (s *staleRecorder) Flush() {
+	if flusher, ok := s.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// captureForStale records successful responses to read-only requests for
+// STALE_ON_ERROR. The returned writer must be used for the rest of the
+// request and finish called once the response is written.
+func (h *Handler) captureForStale(w http.ResponseWriter, reqs []rpcRequest, parseErr error, bodyBytes []byte) (http.ResponseWriter, func()) {
+	if h.stale == nil || !readOnlyRequest(reqs, parseErr) {
+		return w, func() {}
+	}
+
+	recorder := &staleRecorder{ResponseWriter: w}
+	finish := func() {
+		header := recorder.Header()
+		if recorder.status != http.StatusOK || recorder.overflow ||
+			header.Get("X-Vigil-Stale") != "" || header.Get("Content-Encoding") != "" {
+			return
+		}
+		body := recorder.body.Bytes()
+		if !rpcResponseOK(body) {
+			return
+		}
+		key, ids, ok := staleKey(bodyBytes)
+		if !ok {
+			return
+		}
+		h.stale.store(key, staleEntry{
+			body:        append([]byte(nil), body...),
+			contentType: header.Get("Content-Type"),
+			ids:         ids,
+			storedAt:    time.Now(),
+		})
+	}
+	return recorder, finish
+}
+
+// serveStale answers with the last good response to the same calls, with the
+// ids of this request and marked with X-Vigil-Stale. It returns false when
+// nothing fresh enough is cached.
+func (h *Handler) serveStale(w http.ResponseWriter, bodyBytes []byte) bool {
+	if h.stale == nil {
+		return false
+	}
+	key, ids, ok := staleKey(bodyBytes)
+	if !ok {
+		return false
+	}
+	entry, ok := h.stale.load(key)
+	if !ok {
+		return false
+	}
+	body, ok := withRequestIDs(entry, ids)
+	if !ok {
+		return false
+	}
+
+	age := time.Since(entry.storedAt)
+	h.warnings.Warn("All upstreams failed, serving stale cached response",
+		zap.Duration("age", age))
+	if entry.contentType != "" {
+		w.Header().Set("Content-Type", entry.contentType)
+	}
+	w.Header().Set("X-Vigil-Stale", "true")
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+	return true
+}
@@ -0,0 +1,94 @@
+package proxy
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/project-vigil/vigil-intelligent-router/ml"
+)
+
+func rpcCall(body string) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	return r
+}
+
+func TestServeStaleWhenUpstreamsFail(t *testing.T) {
+	// The node echoes the request id, as a real one would
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		reqs, _, _ := parseRPCRequests(body)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"jsonrpc":"2.0","result":{"slot":42},"id":` + string(reqs[0].ID) + `}`))
+	}))
+	cfg := testConfig(t)
+	cfg.NodeURLMap = map[string]string{"a": upstream.URL}
+	cfg.StaleOnErrorEnabled = true
+	cfg.StaleOnErrorTTL = time.Minute
+	cfg.StaleOnErrorMaxEntries = 10
+	backendStub(t, cfg, nil, ml.PredictionResponse{
+		RecommendedNode: "a",
+		AllPredictions:  []ml.NodePrediction{{NodeID: "a", PredictedLatencyMS: 10}},
+	})
+	h := newTestHandler(t, cfg, ml.Options{})
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, rpcCall(`{"jsonrpc":"2.0","id":7,"method":"getSlot","params":[{"commitment":"finalized"}]}`))
+	if rec.Code != http.StatusOK || rec.Header().Get("X-Vigil-Stale") != "" {
+		t.Fatalf("fresh request: got %d stale=%q", rec.Code, rec.Header().Get("X-Vigil-Stale"))
+	}
+
+	upstream.Close()
+
+	// Same call under another id, with the params spaced differently
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, rpcCall(`{"id":"abc","jsonrpc":"2.0","method":"getSlot","params":[{"commitment": "finalized"}]}`))
+	if rec.Code != http.StatusOK || rec.Header().Get("X-Vigil-Stale") != "true" {
+		t.Fatalf("with upstream down: got %d stale=%q %s", rec.Code, rec.Header().Get("X-Vigil-Stale"), rec.Body.String())
+	}
+	if want := `{"jsonrpc":"2.0","result":{"slot":42},"id":"abc"}`; rec.Body.String() != want {
+		t.Errorf("stale body = %s, want %s", rec.Body.String(), want)
+	}
+
+	// A different call has nothing cached
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, rpcCall(`{"jsonrpc":"2.0","id":7,"method":"getSlot"}`))
+	if rec.Header().Get("X-Vigil-Stale") != "" {
+		t.Errorf("uncached call served stale: %s", rec.Body.String())
+	}
+}
+
+func TestWithRequestIDsBatch(t *testing.T) {
+	cachedReq := `[{"jsonrpc":"2.0","id":1,"method":"getSlot"},{"jsonrpc":"2.0","method":"getHealth"},{"jsonrpc":"2.0","id":2,"method":"getBlockHeight"}]`
+	req := `[{"jsonrpc":"2.0","id":"x","method":"getSlot"},{"jsonrpc":"2.0","method":"getHealth"},{"jsonrpc":"2.0","id":null,"method":"getBlockHeight"}]`
+	cachedKey, cachedIDs, ok := staleKey([]byte(cachedReq))
+	if !ok {
+		t.Fatal("staleKey rejected the cached request")
+	}
+	key, ids, _ := staleKey([]byte(req))
+	if key != cachedKey {
+		t.Fatal("requests differing only in ids have different keys")
+	}
+
+	// The upstream answered out of order
+	entry := staleEntry{
+		body: []byte(`[{"jsonrpc":"2.0","result":100,"id":2},{"jsonrpc":"2.0","result":5,"id":1}]`),
+		ids:  cachedIDs,
+	}
+	body, ok := withRequestIDs(entry, ids)
+	if !ok {
+		t.Fatal("withRequestIDs failed")
+	}
+	var got []staleResponse
+	if err := json.Unmarshal(body, &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || string(got[0].ID) != "null" || string(got[1].ID) != `"x"` {
+		t.Errorf("rewritten batch = %s", body)
+	}
+}
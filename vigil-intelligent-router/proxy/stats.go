@@ -0,0 +1,104 @@
+package proxy
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+)
+
+// stats collects the router's runtime routing statistics
+func (h *Handler) stats() map[string]interface{} {
+	stats := map[string]interface{}{
+		"disagreement":      h.mlClient.GetDisagreementStats(),
+		"rpc_id_mismatches": h.idMismatches.Load(),
+		"inflight":          h.inflightCounts(),
+		"global_inflight":   h.globalInflight.Load(),
+		"cold_connections":  h.coldRequests.Load(),
+	}
+	if h.config.MaxGlobalInflight > 0 {
+		stats["global_inflight_rejected"] = h.globalRejected.Load()
+	}
+	if h.config.SLALatencyMS > 0 {
+		stats["sla"] = h.mlClient.GetSLAStatus()
+	}
+	if h.config.ShadowMLServiceURL != "" {
+		stats["shadow_ml"] = h.mlClient.GetShadowStats()
+	}
+	if h.config.ReliabilityWeighting {
+		stats["reliability_weights"] = h.mlClient.GetReliabilityWeights()
+	}
+	if h.config.HedgingEnabled {
+		stats["hedging"] = h.hedgeReport()
+	}
+	if h.config.CanaryNode != "" {
+		stats["canary"] = h.canaryReport()
+	}
+	if h.config.ShadowForwardNode != "" {
+		stats["shadow_forward"] = h.shadowForwardReport()
+	}
+	if len(h.decisions) > 0 {
+		report := make(map[string]map[string]int64, len(h.decisions))
+		for name, exporter := range h.decisions {
+			report[name] = exporter.report()
+		}
+		stats["decision_export"] = report
+	}
+	if len(h.config.ExplanationCategories) > 0 {
+		stats["explanations"] = h.explanationReport()
+	}
+	return stats
+}
+
+// NodeSummary reports node state for /health from cached state only; it
+// never queries the collector or the ML service
+func (h *Handler) NodeSummary() map[string]interface{} {
+	summary := map[string]interface{}{
+		"total": len(h.config.NodeURLMap),
+	}
+	if healthy, known := h.mlClient.LastHealthyNodeCount(); known {
+		summary["healthy"] = healthy
+	}
+
+	h.lastNodeMutex.Lock()
+	if h.lastNode != "" {
+		summary["current_best"] = h.lastNode
+	}
+	h.lastNodeMutex.Unlock()
+
+	if reachable, known := h.mlClient.MLServiceReachable(); known {
+		summary["ml_service_reachable"] = reachable
+	}
+
+	// SLA demotions are the router's only per-node trip-and-reset state
+	if h.config.SLALatencyMS > 0 {
+		demoted := make(map[string]float64)
+		for nodeID, remaining := range h.mlClient.SLADemotions() {
+			demoted[nodeID] = math.Ceil(remaining.Seconds())
+		}
+		summary["sla_demoted_reset_in_seconds"] = demoted
+	}
+
+	// Failures among each node's recent requests, which drive the observed
+	// failure penalty and show a node heading for demotion
+	if failures := h.mlClient.RecentFailures(); len(failures) > 0 {
+		summary["recent_failures"] = failures
+	}
+	return summary
+}
+
+// StatsHandler serves routing statistics as JSON
+func (h *Handler) StatsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+		w.Header().Set("Content-Type", "application/json")
+
+		if r.Method == http.MethodOptions {
+			WritePreflight(w, r, h.config.CORSMaxAge)
+			return
+		}
+
+		json.NewEncoder(w).Encode(h.stats())
+	}
+}
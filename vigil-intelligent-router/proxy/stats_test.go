@@ -0,0 +1,39 @@
+package proxy
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/project-vigil/vigil-intelligent-router/ml"
+)
+
+func TestNodeSummaryFailuresAndDemotions(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.NodeURLMap = map[string]string{"a": "http://a.invalid", "b": "http://b.invalid"}
+	cfg.SLALatencyMS = 100
+	h := newTestHandler(t, cfg, ml.Options{
+		SLALatencyMS: 100,
+		SLAWindow:    time.Minute,
+		SLACooldown:  time.Minute,
+	})
+
+	if _, ok := h.NodeSummary()["recent_failures"]; ok {
+		t.Fatal("recent_failures reported before any request")
+	}
+
+	for _, success := range []bool{false, true, false, false} {
+		h.mlClient.RecordOutcome("a", success)
+	}
+	h.mlClient.RecordOutcome("b", true)
+	h.mlClient.RecordLatency("a", 500)
+
+	summary := h.NodeSummary()
+	if got, want := summary["recent_failures"], map[string]int{"a": 3, "b": 0}; !reflect.DeepEqual(got, want) {
+		t.Errorf("recent_failures = %v, want %v", got, want)
+	}
+	demoted := summary["sla_demoted_reset_in_seconds"].(map[string]float64)
+	if len(demoted) != 1 || demoted["a"] != 60 {
+		t.Errorf("sla_demoted_reset_in_seconds = %v, want a in 60s", demoted)
+	}
+}
@@ -0,0 +1,141 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+
+	"github.com/project-vigil/vigil-intelligent-router/config"
+	"go.uber.org/zap"
+)
+
+// statsdMaxPacket keeps each datagram under a typical Ethernet MTU
+const statsdMaxPacket = 1432
+
+// statsdSink aggregates counters and timers in memory and pushes them to a
+// StatsD endpoint over UDP on every flush. A nil sink discards everything.
+type statsdSink struct {
+	conn   net.Conn
+	prefix string
+	logger *zap.Logger
+
+	mutex    sync.Mutex
+	counters map[string]int64
+	timers   map[string][]float64
+}
+
+// newStatsdSink dials STATSD_ADDR, returning nil when it is unset or unusable
+func newStatsdSink(cfg *config.Config, logger *zap.Logger) *statsdSink {
+	if cfg.StatsDAddr == "" {
+		return nil
+	}
+	conn, err := net.Dial("udp", cfg.StatsDAddr)
+	if err != nil {
+		logger.Error("Failed to set up StatsD sink, metrics push disabled",
+			zap.String("addr", cfg.StatsDAddr),
+			zap.Error(err))
+		return nil
+	}
+	prefix := strings.TrimSuffix(cfg.StatsDPrefix, ".")
+	if prefix != "" {
+		prefix += "."
+	}
+	return &statsdSink{
+		conn:     conn,
+		prefix:   prefix,
+		logger:   logger,
+		counters: make(map[string]int64),
+		timers:   make(map[string][]float64),
+	}
+}
+
+// incr adds n to the named counter
+func (s *statsdSink) incr(name string, n int64) {
+	if s == nil {
+		return
+	}
+	s.mutex.Lock()
+	s.counters[name] += n
+	s.mutex.Unlock()
+}
+
+// timing records a duration sample for the named timer
+func (s *statsdSink) timing(name string, ms float64) {
+	if s == nil {
+		return
+	}
+	s.mutex.Lock()
+	s.timers[name] = append(s.timers[name], ms)
+	s.mutex.Unlock()
+}
+
+// flush sends everything recorded since the last flush, packing as many lines
+// into each datagram as fit
+func (s *statsdSink) flush(ctx context.Context) {
+	s.mutex.Lock()
+	counters, timers := s.counters, s.timers
+	s.counters = make(map[string]int64)
+	s.timers = make(map[string][]float64)
+	s.mutex.Unlock()
+
+	var packet strings.Builder
+	send := func() {
+		if packet.Len() == 0 {
+			return
+		}
+		if _, err := s.conn.Write([]byte(packet.String())); err != nil {
+			s.logger.Debug("Failed to push StatsD metrics", zap.Error(err))
+		}
+		packet.Reset()
+	}
+	add := func(line string) {
+		if packet.Len() > 0 && packet.Len()+1+len(line) > statsdMaxPacket {
+			send()
+		}
+		if packet.Len() > 0 {
+			packet.WriteByte('\n')
+		}
+		packet.WriteString(line)
+	}
+
+	for name, value := range counters {
+		add(fmt.Sprintf("%s%s:%d|c", s.prefix, name, value))
+	}
+	for name, samples := range timers {
+		for _, ms := range samples {
+			add(fmt.Sprintf("%s%s:%g|ms", s.prefix, name, ms))
+		}
+	}
+	send()
+}
+
+// StartMetricsPush schedules periodic StatsD flushes when STATSD_ADDR is set
+func (h *Handler) StartMetricsPush(tasks *TaskManager) {
+	if h.statsd == nil {
+		return
+	}
+	tasks.Every("statsd_flush", h.config.StatsDFlushInterval, h.statsd.flush)
+}
+
+// nodeMetricName names a node for metric keys; URLs that are not configured
+// nodes (fallback, last resort) are grouped together
+func (h *Handler) nodeMetricName(targetURL string) string {
+	if nodeID, ok := h.costs.urlToNode[targetURL]; ok {
+		return nodeID
+	}
+	return "other"
+}
+
+// recordUpstreamMetrics emits the per-request counters and timers for a
+// response from targetURL
+func (h *Handler) recordUpstreamMetrics(targetURL string, status int, latencyMS float64) {
+	if h.statsd == nil {
+		return
+	}
+	node := h.nodeMetricName(targetURL)
+	h.statsd.incr("requests."+node, 1)
+	h.statsd.incr(fmt.Sprintf("responses.%dxx", status/100), 1)
+	h.statsd.timing("upstream_latency_ms."+node, latencyMS)
+}
@@ -0,0 +1,126 @@
+package proxy
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/project-vigil/vigil-intelligent-router/ml"
+	"go.uber.org/zap"
+)
+
+// statsdListener returns a UDP listener standing in for a StatsD server
+func statsdListener(t *testing.T) net.PacketConn {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// readPackets returns the lines of every datagram received until the
+// listener has been quiet for a short while
+func readPackets(t *testing.T, conn net.PacketConn) (lines []string, packets int) {
+	t.Helper()
+	buf := make([]byte, 65536)
+	for {
+		conn.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			return lines, packets
+		}
+		if n > statsdMaxPacket {
+			t.Errorf("datagram of %d bytes exceeds %d", n, statsdMaxPacket)
+		}
+		packets++
+		lines = append(lines, strings.Split(string(buf[:n]), "\n")...)
+	}
+}
+
+func TestStatsdFlushEmitsPackets(t *testing.T) {
+	listener := statsdListener(t)
+	cfg := testConfig(t)
+	cfg.StatsDAddr = listener.LocalAddr().String()
+	cfg.StatsDPrefix = "vigil."
+
+	sink := newStatsdSink(cfg, zap.NewNop())
+	sink.incr("ml_errors", 1)
+	sink.incr("ml_errors", 2)
+	sink.timing("request_duration_ms", 12)
+	sink.timing("request_duration_ms", 7.5)
+	sink.flush(context.Background())
+
+	lines, _ := readPackets(t, listener)
+	sort.Strings(lines)
+	want := []string{"vigil.ml_errors:3|c", "vigil.request_duration_ms:12|ms", "vigil.request_duration_ms:7.5|ms"}
+	if strings.Join(lines, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("lines = %q, want %q", lines, want)
+	}
+
+	// Nothing is resent on the next flush
+	sink.flush(context.Background())
+	if lines, _ := readPackets(t, listener); len(lines) != 0 {
+		t.Fatalf("second flush sent %q", lines)
+	}
+}
+
+func TestStatsdFlushSplitsLargePayloads(t *testing.T) {
+	listener := statsdListener(t)
+	cfg := testConfig(t)
+	cfg.StatsDAddr = listener.LocalAddr().String()
+
+	sink := newStatsdSink(cfg, zap.NewNop())
+	for i := 0; i < 500; i++ {
+		sink.timing("upstream_latency_ms.some_long_node_name", float64(i))
+	}
+	sink.flush(context.Background())
+
+	lines, packets := readPackets(t, listener)
+	if len(lines) != 500 {
+		t.Fatalf("received %d lines, want 500", len(lines))
+	}
+	if packets < 2 {
+		t.Fatalf("received %d datagrams, want the payload split", packets)
+	}
+}
+
+func TestStatsdRecordsForwardedRequests(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `{"jsonrpc":"2.0","id":1,"result":1}`)
+	}))
+	defer upstream.Close()
+
+	listener := statsdListener(t)
+	cfg := testConfig(t)
+	cfg.StatsDAddr = listener.LocalAddr().String()
+	cfg.StatsDPrefix = ""
+	cfg.NodeURLMap = map[string]string{"node_a": upstream.URL}
+	h := newTestHandler(t, cfg, ml.Options{})
+
+	r := newRPCRequest("getSlot")
+	body := []byte(`{"jsonrpc":"2.0","id":1,"method":"getSlot"}`)
+	if err := h.forwardRequest(httptest.NewRecorder(), r, upstream.URL, body, time.Now()); err != nil {
+		t.Fatalf("forwardRequest: %v", err)
+	}
+	h.statsd.flush(context.Background())
+
+	lines, _ := readPackets(t, listener)
+	names := make(map[string]bool)
+	for _, line := range lines {
+		name, _, _ := strings.Cut(line, ":")
+		names[name] = true
+	}
+	for _, name := range []string{"requests.node_a", "responses.2xx", "upstream_latency_ms.node_a", "request_duration_ms"} {
+		if !names[name] {
+			t.Errorf("missing metric %s in %q", name, lines)
+		}
+	}
+}
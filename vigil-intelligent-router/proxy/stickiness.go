@@ -0,0 +1,50 @@
+package proxy
+
+import (
+	"github.com/project-vigil/vigil-intelligent-router/ml"
+	"go.uber.org/zap"
+)
+
+// applyStickiness keeps routing to the previously chosen node unless the new
+// winner beats it by more than SwitchMargin after the StickinessBonus. This
+// stops closely matched nodes from flapping on every request.
+func (h *Handler) applyStickiness(prediction *ml.PredictionResponse) {
+	if h.config.StickinessBonus <= 0 && h.config.SwitchMargin <= 0 {
+		return
+	}
+
+	h.lastNodeMutex.Lock()
+	lastNode := h.lastNode
+	h.lastNodeMutex.Unlock()
+
+	if lastNode == "" || lastNode == prediction.RecommendedNode {
+		return
+	}
+	if h.mlClient.NodeIneligibleReason(prediction, lastNode) != "" {
+		return
+	}
+
+	for _, node := range prediction.AllPredictions {
+		if node.NodeID != lastNode {
+			continue
+		}
+		stickyScore := node.CostScore - h.config.StickinessBonus
+		improvement := stickyScore - prediction.RecommendationDetails.CostScore
+		if improvement <= h.config.SwitchMargin {
+			h.logger.Debug("Sticking with previous node",
+				zap.String("node", lastNode),
+				zap.String("challenger", prediction.RecommendedNode),
+				zap.Float64("improvement", improvement),
+				zap.Float64("switch_margin", h.config.SwitchMargin))
+			prediction.SelectNode(lastNode)
+		}
+		return
+	}
+}
+
+// rememberChosenNode records the node a request was routed to
+func (h *Handler) rememberChosenNode(nodeID string) {
+	h.lastNodeMutex.Lock()
+	h.lastNode = nodeID
+	h.lastNodeMutex.Unlock()
+}
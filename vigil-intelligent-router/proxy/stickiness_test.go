@@ -0,0 +1,86 @@
+package proxy
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/project-vigil/vigil-intelligent-router/ml"
+)
+
+func TestStickinessUntilSwitchMargin(t *testing.T) {
+	// Predicted latencies per request; a and b trade the lead by 2ms until b
+	// pulls clearly ahead
+	rounds := []struct {
+		a, b float64
+		want string
+	}{
+		{10, 12, "a"},
+		{12, 10, "a"},
+		{10, 12, "a"},
+		{13, 10, "a"},
+		{30, 10, "b"},
+		{10, 12, "b"},
+		{12, 10, "b"},
+	}
+
+	cfg := testConfig(t)
+	cfg.NodeURLMap = map[string]string{"a": namedUpstream(t, "a"), "b": namedUpstream(t, "b")}
+	cfg.StickinessBonus = 1
+	cfg.SwitchMargin = 2
+
+	var round atomic.Int64
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == cfg.MetricsEndpoint {
+			json.NewEncoder(w).Encode([]ml.MetricData{})
+			return
+		}
+		scores := rounds[round.Load()]
+		recommended := "a"
+		if scores.b < scores.a {
+			recommended = "b"
+		}
+		json.NewEncoder(w).Encode(ml.PredictionResponse{
+			RecommendedNode: recommended,
+			AllPredictions: []ml.NodePrediction{
+				{NodeID: "a", PredictedLatencyMS: scores.a},
+				{NodeID: "b", PredictedLatencyMS: scores.b},
+			},
+		})
+	}))
+	t.Cleanup(backend.Close)
+	cfg.MLServiceURL = backend.URL
+	cfg.DataCollectorURL = backend.URL
+	h := newTestHandler(t, cfg, ml.Options{})
+
+	for i, tt := range rounds {
+		round.Store(int64(i))
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, newRPCRequest("getSlot"))
+		if want := `{"jsonrpc":"2.0","id":1,"result":"` + tt.want + `"}`; rec.Body.String() != want {
+			t.Fatalf("round %d (a=%v b=%v): got %d %s, want node %s", i, tt.a, tt.b, rec.Code, rec.Body.String(), tt.want)
+		}
+	}
+}
+
+func TestStickinessDisabledFollowsScores(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.NodeURLMap = map[string]string{"a": namedUpstream(t, "a"), "b": namedUpstream(t, "b")}
+	h := newTestHandler(t, cfg, ml.Options{})
+	h.rememberChosenNode("a")
+
+	prediction := &ml.PredictionResponse{
+		RecommendedNode: "b",
+		AllPredictions: []ml.NodePrediction{
+			{NodeID: "a", PredictedLatencyMS: 11, CostScore: 11},
+			{NodeID: "b", PredictedLatencyMS: 10, CostScore: 10},
+		},
+	}
+	h.applyStickiness(prediction)
+	if prediction.RecommendedNode != "b" {
+		t.Errorf("recommended %s with stickiness off, want b", prediction.RecommendedNode)
+	}
+}
@@ -0,0 +1,98 @@
+package proxy
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// TaskManager runs periodic background tasks under a shared context. Each task
+// has at most one run in flight: a tick that arrives while the previous run is
+// still going is skipped and logged as an overrun.
+type TaskManager struct {
+	ctx           context.Context
+	jitterPercent float64
+	logger        *zap.Logger
+	wg            sync.WaitGroup
+}
+
+// NewTaskManager creates a task manager whose tasks stop when ctx is canceled.
+// Each wait between runs is randomized within ±jitterPercent of the interval
+// so that router instances don't poll in lockstep.
+func NewTaskManager(ctx context.Context, jitterPercent float64, logger *zap.Logger) *TaskManager {
+	return &TaskManager{ctx: ctx, jitterPercent: jitterPercent, logger: logger}
+}
+
+// jitteredInterval returns interval moved by a random amount within ±percent
+func jitteredInterval(interval time.Duration, percent float64) time.Duration {
+	if percent <= 0 {
+		return interval
+	}
+	offset := (rand.Float64()*2 - 1) * percent / 100
+	return time.Duration(float64(interval) * (1 + offset))
+}
+
+// Every runs fn immediately and then every interval until the context is canceled
+func (m *TaskManager) Every(name string, interval time.Duration, fn func(ctx context.Context)) {
+	var running atomic.Bool
+
+	run := func() {
+		if !running.CompareAndSwap(false, true) {
+			m.logger.Warn("Background task overran its interval, skipping run",
+				zap.String("task", name),
+				zap.Duration("interval", interval))
+			return
+		}
+		m.wg.Add(1)
+		go func() {
+			defer m.wg.Done()
+			defer running.Store(false)
+
+			start := time.Now()
+			fn(m.ctx)
+			if elapsed := time.Since(start); elapsed > interval {
+				m.logger.Warn("Background task took longer than its interval",
+					zap.String("task", name),
+					zap.Duration("elapsed", elapsed),
+					zap.Duration("interval", interval))
+			}
+		}()
+	}
+
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		timer := time.NewTimer(jitteredInterval(interval, m.jitterPercent))
+		defer timer.Stop()
+
+		run()
+		for {
+			select {
+			case <-m.ctx.Done():
+				return
+			case <-timer.C:
+				run()
+				timer.Reset(jitteredInterval(interval, m.jitterPercent))
+			}
+		}
+	}()
+}
+
+// Go runs a long-lived task once; fn must return when ctx is canceled
+func (m *TaskManager) Go(name string, fn func(ctx context.Context)) {
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		fn(m.ctx)
+		m.logger.Debug("Background task stopped", zap.String("task", name))
+	}()
+}
+
+// Wait blocks until every task loop and in-flight run has returned
+func (m *TaskManager) Wait() {
+	m.wg.Wait()
+}
@@ -0,0 +1,145 @@
+package proxy
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/project-vigil/vigil-intelligent-router/config"
+	"github.com/project-vigil/vigil-intelligent-router/ml"
+)
+
+// tenant is a configured tenant together with its rate limiter
+type tenant struct {
+	name    string
+	policy  config.TenantPolicy
+	limiter *tokenBucket       // nil when unlimited
+	scoring *ml.ScoringWeights // nil when using the global weights
+}
+
+// allow reports whether the tenant may make another request now
+func (t *tenant) allow() bool {
+	return t.limiter == nil || t.limiter.allow()
+}
+
+// newTenants builds the runtime tenant table from configuration, resolving
+// scoring presets against the global weights
+func newTenants(cfg *config.Config, weights ml.ScoringWeights) map[string]*tenant {
+	tenants := make(map[string]*tenant, len(cfg.Tenants))
+	for name, policy := range cfg.Tenants {
+		t := &tenant{name: name, policy: policy}
+		if policy.RateLimitRPS > 0 {
+			t.limiter = newTokenBucket(policy.RateLimitRPS, policy.RateLimitBurst)
+		}
+		if preset := policy.Scoring; preset != nil {
+			scoring := weights
+			override(&scoring.SuccessRateWeight, preset.SuccessRateWeight)
+			override(&scoring.BlockGapPenalty, preset.BlockGapPenalty)
+			override(&scoring.UnhealthyPenalty, preset.UnhealthyPenalty)
+			override(&scoring.PredictionWeight, preset.PredictionWeight)
+			override(&scoring.RecentWeight, preset.RecentWeight)
+			t.scoring = &scoring
+		}
+		tenants[name] = t
+	}
+	return tenants
+}
+
+// override replaces *dst with *value when value is set
+func override(dst, value *float64) {
+	if value != nil {
+		*dst = *value
+	}
+}
+
+// resolveTenant identifies the caller from X-Vigil-Tenant, or failing that by
+// matching X-API-Key against tenant keys. A tenant named in the header that
+// has an api_key must also present it; otherwise authenticated is false. It
+// returns a nil tenant for unknown callers, who get the default policy.
+func (h *Handler) resolveTenant(r *http.Request) (t *tenant, authenticated bool) {
+	key := r.Header.Get("X-API-Key")
+	if name := strings.ToLower(r.Header.Get("X-Vigil-Tenant")); name != "" {
+		if t, ok := h.tenants[name]; ok {
+			if t.policy.APIKey != "" && !keyMatches(key, t.policy.APIKey) {
+				return nil, false
+			}
+			return t, true
+		}
+	}
+	if key != "" {
+		for _, t := range h.tenants {
+			if t.policy.APIKey != "" && keyMatches(key, t.policy.APIKey) {
+				return t, true
+			}
+		}
+	}
+	return nil, true
+}
+
+// keyMatches compares API keys in constant time
+func keyMatches(key, want string) bool {
+	return subtle.ConstantTimeCompare([]byte(key), []byte(want)) == 1
+}
+
+type tenantContextKey struct{}
+
+// withTenant attaches the resolved tenant to the request context
+func withTenant(ctx context.Context, t *tenant) context.Context {
+	return context.WithValue(ctx, tenantContextKey{}, t)
+}
+
+// tenantFrom returns the tenant attached to the request, if any
+func tenantFrom(r *http.Request) *tenant {
+	t, _ := r.Context().Value(tenantContextKey{}).(*tenant)
+	return t
+}
+
+// fallbackURL returns the fallback RPC URL for the request's tenant
+func (h *Handler) fallbackURL(r *http.Request) string {
+	if t := tenantFrom(r); t != nil && t.policy.FallbackRPCURL != "" {
+		return t.policy.FallbackRPCURL
+	}
+	return h.config.FallbackRPCURL
+}
+
+// tokenBucket is a simple thread-safe token-bucket rate limiter
+type tokenBucket struct {
+	mutex  sync.Mutex
+	rate   float64 // tokens added per second
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	capacity := float64(burst)
+	if capacity <= 0 {
+		capacity = rate
+	}
+	if capacity < 1 {
+		capacity = 1
+	}
+	return &tokenBucket{rate: rate, burst: capacity, tokens: capacity, last: time.Now()}
+}
+
+// allow takes a token if one is available
+func (b *tokenBucket) allow() bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	now := time.Now()
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.last = now
+
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
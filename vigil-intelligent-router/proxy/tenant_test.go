@@ -0,0 +1,199 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/project-vigil/vigil-intelligent-router/config"
+	"github.com/project-vigil/vigil-intelligent-router/ml"
+)
+
+// namedUpstream is an RPC node that answers with its own name
+func namedUpstream(t *testing.T, name string) string {
+	t.Helper()
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":"` + name + `"}`))
+	}))
+	t.Cleanup(upstream.Close)
+	return upstream.URL
+}
+
+func float(v float64) *float64 { return &v }
+
+func TestResolveTenant(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.Tenants = map[string]config.TenantPolicy{
+		"analytics": {APIKey: "k1"},
+		"wallet":    {},
+	}
+	h := newTestHandler(t, cfg, ml.Options{})
+
+	tests := []struct {
+		name          string
+		tenant, key   string
+		want          string
+		authenticated bool
+	}{
+		{"keyed tenant with key", "analytics", "k1", "analytics", true},
+		{"keyed tenant without key", "analytics", "", "", false},
+		{"keyed tenant with wrong key", "analytics", "k2", "", false},
+		{"tenant name is case-insensitive", "Analytics", "k1", "analytics", true},
+		{"key alone", "", "k1", "analytics", true},
+		{"unkeyed tenant", "wallet", "", "wallet", true},
+		{"unknown tenant", "nobody", "", "", true},
+		{"unknown key", "", "k2", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newRPCRequest("getSlot")
+			if tt.tenant != "" {
+				r.Header.Set("X-Vigil-Tenant", tt.tenant)
+			}
+			if tt.key != "" {
+				r.Header.Set("X-API-Key", tt.key)
+			}
+			got, authenticated := h.resolveTenant(r)
+			if authenticated != tt.authenticated {
+				t.Fatalf("authenticated = %v, want %v", authenticated, tt.authenticated)
+			}
+			name := ""
+			if got != nil {
+				name = got.name
+			}
+			if name != tt.want {
+				t.Errorf("tenant = %q, want %q", name, tt.want)
+			}
+		})
+	}
+}
+
+func TestTenantSpoofingRejected(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.Tenants = map[string]config.TenantPolicy{"analytics": {APIKey: "k1"}}
+	h := newTestHandler(t, cfg, ml.Options{})
+
+	r := newRPCRequest("getSlot")
+	r.Header.Set("X-Vigil-Tenant", "analytics")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, r)
+
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestTenantFallbackURLs(t *testing.T) {
+	cfg := testConfig(t)
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+	cfg.MLServiceURL = down.URL
+	cfg.DataCollectorURL = down.URL
+	cfg.FallbackEnabled = true
+	cfg.FallbackRPCURL = namedUpstream(t, "default")
+	cfg.Tenants = map[string]config.TenantPolicy{
+		"analytics": {APIKey: "k1", FallbackRPCURL: namedUpstream(t, "analytics")},
+		"wallet":    {FallbackRPCURL: namedUpstream(t, "wallet")},
+	}
+	h := newTestHandler(t, cfg, ml.Options{})
+
+	for _, tt := range []struct{ tenant, key, want string }{
+		{"analytics", "k1", "analytics"},
+		{"wallet", "", "wallet"},
+		{"", "", "default"},
+	} {
+		r := newRPCRequest("getSlot")
+		if tt.tenant != "" {
+			r.Header.Set("X-Vigil-Tenant", tt.tenant)
+		}
+		if tt.key != "" {
+			r.Header.Set("X-API-Key", tt.key)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, r)
+
+		want := `{"jsonrpc":"2.0","id":1,"result":"` + tt.want + `"}`
+		if rec.Code != http.StatusOK || rec.Body.String() != want {
+			t.Errorf("tenant %q: got %d %s, want the %s fallback", tt.tenant, rec.Code, rec.Body.String(), tt.want)
+		}
+	}
+}
+
+func TestTenantRateLimits(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.Tenants = map[string]config.TenantPolicy{
+		"analytics": {RateLimitRPS: 0.001, RateLimitBurst: 2},
+		"wallet":    {RateLimitRPS: 0.001, RateLimitBurst: 1},
+		"unlimited": {},
+	}
+	h := newTestHandler(t, cfg, ml.Options{})
+
+	allowed := func(name string, n int) int {
+		count := 0
+		for i := 0; i < n; i++ {
+			r := newRPCRequest("getSlot")
+			r.Header.Set("X-Vigil-Tenant", name)
+			if t, _ := h.resolveTenant(r); t.allow() {
+				count++
+			}
+		}
+		return count
+	}
+	if got := allowed("analytics", 5); got != 2 {
+		t.Errorf("analytics allowed %d of 5, want 2", got)
+	}
+	if got := allowed("wallet", 5); got != 1 {
+		t.Errorf("wallet allowed %d of 5, want 1", got)
+	}
+	if got := allowed("unlimited", 5); got != 5 {
+		t.Errorf("unlimited allowed %d of 5, want 5", got)
+	}
+
+	r := newRPCRequest("getSlot")
+	r.Header.Set("X-Vigil-Tenant", "wallet")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, r)
+	if rec.Code != http.StatusTooManyRequests {
+		t.Errorf("status over the limit = %d, want %d", rec.Code, http.StatusTooManyRequests)
+	}
+}
+
+func TestTenantScoringPreset(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.NodeURLMap = map[string]string{
+		"a": namedUpstream(t, "a"),
+		"b": namedUpstream(t, "b"),
+	}
+	// a predicts fast but has been slow lately; b the other way round
+	backendStub(t, cfg,
+		[]ml.MetricData{recentMetric("a", 100), recentMetric("b", 20)},
+		ml.PredictionResponse{
+			RecommendedNode: "a",
+			AllPredictions: []ml.NodePrediction{
+				{NodeID: "a", PredictedLatencyMS: 10},
+				{NodeID: "b", PredictedLatencyMS: 30},
+			},
+		})
+	cfg.Tenants = map[string]config.TenantPolicy{
+		"trusting": {Scoring: &config.TenantScoring{PredictionWeight: float(1), RecentWeight: float(0)}},
+	}
+	h := newTestHandler(t, cfg, ml.Options{})
+
+	for _, tt := range []struct{ tenant, want string }{
+		{"trusting", "a"},
+		{"", "b"},
+	} {
+		r := newRPCRequest("getSlot")
+		if tt.tenant != "" {
+			r.Header.Set("X-Vigil-Tenant", tt.tenant)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, r)
+
+		want := `{"jsonrpc":"2.0","id":1,"result":"` + tt.want + `"}`
+		if rec.Body.String() != want {
+			t.Errorf("tenant %q: got %d %s, want node %s", tt.tenant, rec.Code, rec.Body.String(), tt.want)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package proxy
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/project-vigil/vigil-intelligent-router/config"
+	"go.uber.org/zap"
+)
+
+// newUpstreamClient builds the HTTP client used to reach RPC nodes
+func newUpstreamClient(cfg *config.Config, tlsConfig *tls.Config) *http.Client {
+	// RequestTimeout bounds the whole exchange; the phase timeouts let dead
+	// nodes fail fast while still allowing slow streaming bodies
+	dialer := &net.Dialer{
+		Timeout:   cfg.UpstreamDialTimeout,
+		KeepAlive: 30 * time.Second,
+	}
+	return &http.Client{
+		Timeout: cfg.RequestTimeout,
+		Transport: &http.Transport{
+			DialContext:           dialer.DialContext,
+			TLSClientConfig:       tlsConfig,
+			TLSHandshakeTimeout:   cfg.UpstreamTLSTimeout,
+			ResponseHeaderTimeout: cfg.UpstreamResponseHeaderTimeout,
+			MaxIdleConns:          100,
+			MaxIdleConnsPerHost:   20,
+			IdleConnTimeout:       90 * time.Second,
+			ForceAttemptHTTP2:     true,
+		},
+	}
+}
+
+// buildTLSConfig returns the TLS settings for an upstream, or nil for Go's defaults
+func buildTLSConfig(skipVerify bool, rootCAs *x509.CertPool) *tls.Config {
+	if !skipVerify && rootCAs == nil {
+		return nil
+	}
+	return &tls.Config{
+		RootCAs:            rootCAs,
+		InsecureSkipVerify: skipVerify, // #nosec G402 -- explicitly opted into via config
+	}
+}
+
+// loadCAPool returns the system roots extended with the certificates in caFile
+func loadCAPool(caFile string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA file: %w", err)
+	}
+
+	pool, err := x509.SystemCertPool()
+	if err != nil || pool == nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no valid certificates found in %s", caFile)
+	}
+	return pool, nil
+}
+
+// buildUpstreamClients creates the default upstream client plus dedicated clients
+// for nodes whose TLS settings differ from the global ones, keyed by node URL
+func buildUpstreamClients(cfg *config.Config, logger *zap.Logger) (*http.Client, map[string]*http.Client) {
+	var rootCAs *x509.CertPool
+	if cfg.UpstreamCAFile != "" {
+		pool, err := loadCAPool(cfg.UpstreamCAFile)
+		if err != nil {
+			logger.Error("Failed to load upstream CA bundle, using system roots",
+				zap.String("ca_file", cfg.UpstreamCAFile),
+				zap.Error(err))
+		} else {
+			rootCAs = pool
+			logger.Info("Loaded upstream CA bundle", zap.String("ca_file", cfg.UpstreamCAFile))
+		}
+	}
+
+	if cfg.UpstreamInsecureSkipVerify {
+		logger.Warn("TLS VERIFICATION DISABLED for all upstream RPC nodes; connections are vulnerable to interception")
+	}
+
+	defaultClient := newUpstreamClient(cfg, buildTLSConfig(cfg.UpstreamInsecureSkipVerify, rootCAs))
+
+	perNode := make(map[string]*http.Client)
+	for nodeID, skip := range cfg.NodeInsecureSkipVerify {
+		url, ok := cfg.NodeURLMap[nodeID]
+		if !ok || skip == cfg.UpstreamInsecureSkipVerify {
+			continue
+		}
+		if skip {
+			logger.Warn("TLS VERIFICATION DISABLED for upstream node; connections are vulnerable to interception",
+				zap.String("node", nodeID))
+		}
+		perNode[url] = newUpstreamClient(cfg, buildTLSConfig(skip, rootCAs))
+	}
+
+	return defaultClient, perNode
+}
+
+// clientFor returns the HTTP client to use for the given upstream URL
+func (h *Handler) clientFor(targetURL string) *http.Client {
+	if client, ok := h.nodeClients[targetURL]; ok {
+		return client
+	}
+	return h.httpClient
+}
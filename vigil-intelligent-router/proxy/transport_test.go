@@ -0,0 +1,129 @@
+package proxy
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/project-vigil/vigil-intelligent-router/config"
+	"github.com/project-vigil/vigil-intelligent-router/ml"
+	"go.uber.org/zap"
+)
+
+// newH2Stub starts a TLS server that speaks HTTP/2 and returns it with a
+// pool trusting its certificate
+func newH2Stub(t *testing.T, handler http.HandlerFunc) (*httptest.Server, *x509.CertPool) {
+	t.Helper()
+	stub := httptest.NewUnstartedServer(handler)
+	stub.EnableHTTP2 = true
+	stub.StartTLS()
+	t.Cleanup(stub.Close)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(stub.Certificate())
+	return stub, pool
+}
+
+func TestUpstreamClientNegotiatesHTTP2(t *testing.T) {
+	stub, pool := newH2Stub(t, func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.Proto)
+	})
+
+	client := newUpstreamClient(testConfig(t), buildTLSConfig(false, pool))
+	resp, err := client.Get(stub.URL)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+
+	if resp.ProtoMajor != 2 || string(body) != "HTTP/2.0" {
+		t.Fatalf("negotiated %s (server saw %s), want HTTP/2.0", resp.Proto, body)
+	}
+}
+
+func TestForwardRequestStreamsOverHTTP2(t *testing.T) {
+	stub, pool := newH2Stub(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"jsonrpc":"2.0","id":1,"result":[`)
+		for i := 0; i < 3; i++ {
+			fmt.Fprintf(w, "%d,", i)
+			w.(http.Flusher).Flush()
+			time.Sleep(5 * time.Millisecond)
+		}
+		io.WriteString(w, `3]}`)
+	})
+
+	cfg := testConfig(t)
+	h := newTestHandler(t, cfg, ml.Options{})
+	h.httpClient = newUpstreamClient(cfg, buildTLSConfig(false, pool))
+
+	body := []byte(`{"jsonrpc":"2.0","id":1,"method":"getSlot"}`)
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(string(body)))
+	w := httptest.NewRecorder()
+	if err := h.forwardRequest(w, r, stub.URL, body, time.Now()); err != nil {
+		t.Fatalf("forwardRequest: %v", err)
+	}
+
+	if want := `{"jsonrpc":"2.0","id":1,"result":[0,1,2,3]}`; w.Body.String() != want {
+		t.Fatalf("body = %q, want %q", w.Body.String(), want)
+	}
+}
+
+func TestUpstreamTLSVerification(t *testing.T) {
+	stub := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "ok")
+	}))
+	defer stub.Close()
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: stub.Certificate().Raw})
+	if err := os.WriteFile(caFile, certPEM, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		configure func(cfg *config.Config)
+		wantOK    bool
+	}{
+		{"verified by default", func(cfg *config.Config) {}, false},
+		{"global skip verify", func(cfg *config.Config) {
+			cfg.UpstreamInsecureSkipVerify = true
+		}, true},
+		{"per-node skip verify", func(cfg *config.Config) {
+			cfg.NodeInsecureSkipVerify = map[string]bool{"self_hosted": true}
+		}, true},
+		{"per-node skip for another node", func(cfg *config.Config) {
+			cfg.NodeInsecureSkipVerify = map[string]bool{"other": true}
+		}, false},
+		{"custom CA bundle", func(cfg *config.Config) {
+			cfg.UpstreamCAFile = caFile
+		}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig(t)
+			cfg.NodeURLMap = map[string]string{"self_hosted": stub.URL, "other": "https://other.invalid"}
+			tt.configure(cfg)
+
+			h := &Handler{}
+			h.httpClient, h.nodeClients = buildUpstreamClients(cfg, zap.NewNop())
+			resp, err := h.clientFor(stub.URL).Get(stub.URL)
+			if err == nil {
+				resp.Body.Close()
+			}
+			if ok := err == nil; ok != tt.wantOK {
+				t.Fatalf("connected = %v (err %v), want %v", ok, err, tt.wantOK)
+			}
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+// nodeVersions holds the last getVersion result per node
+type nodeVersions struct {
+	mutex    sync.RWMutex
+	versions map[string]string
+}
+
+// getVersionResponse is the subset of a getVersion reply we use
+type getVersionResponse struct {
+	Result struct {
+		SolanaCore string `json:"solana-core"`
+	} `json:"result"`
+}
+
+// StartVersionChecks schedules getVersion polling of every node. It does
+// nothing when no METHOD_MIN_VERSION_* requirement is configured.
+func (h *Handler) StartVersionChecks(tasks *TaskManager) {
+	if len(h.config.MethodMinVersions) == 0 {
+		return
+	}
+	tasks.Every("version_check", h.config.VersionCheckInterval, h.refreshVersions)
+}
+
+// refreshVersions queries getVersion on every node, keeping the previous
+// value for nodes that fail to answer
+func (h *Handler) refreshVersions(ctx context.Context) {
+	body := []byte(`{"jsonrpc":"2.0","id":1,"method":"getVersion"}`)
+	for nodeID, url := range h.config.NodeURLMap {
+		reqCtx, cancel := context.WithTimeout(ctx, h.config.RequestTimeout)
+		version, err := h.fetchVersion(reqCtx, url, body)
+		cancel()
+		if err != nil {
+			h.logger.Debug("getVersion failed", zap.String("node", nodeID), zap.Error(err))
+			continue
+		}
+
+		h.versions.mutex.Lock()
+		previous := h.versions.versions[nodeID]
+		h.versions.versions[nodeID] = version
+		h.versions.mutex.Unlock()
+
+		if previous != version {
+			h.logger.Info("Node version detected",
+				zap.String("node", nodeID),
+				zap.String("version", version))
+		}
+	}
+}
+
+func (h *Handler) fetchVersion(ctx context.Context, url string, body []byte) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	h.setUpstreamAuth(req, url)
+
+	resp, err := h.clientFor(url).Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var parsed getVersionResponse
+	if err := json.NewDecoder(resp.Body).Decode(&parsed); err != nil {
+		return "", err
+	}
+	return parsed.Result.SolanaCore, nil
+}
+
+// versionExclusions returns the nodes whose known version is older than a
+// minimum required by any of the request's methods. Nodes with an unknown
+// version are not excluded.
+func (h *Handler) versionExclusions(reqs []rpcRequest) map[string]string {
+	if len(h.config.MethodMinVersions) == 0 {
+		return nil
+	}
+
+	h.versions.mutex.RLock()
+	defer h.versions.mutex.RUnlock()
+
+	excluded := make(map[string]string)
+	for _, req := range reqs {
+		required, ok := h.config.MethodMinVersions[req.Method]
+		if !ok {
+			continue
+		}
+		for nodeID, version := range h.versions.versions {
+			if version != "" && compareVersions(version, required) < 0 {
+				excluded[nodeID] = "version_unsupported"
+			}
+		}
+	}
+	return excluded
+}
+
+// compareVersions compares dotted numeric versions such as "1.18.22",
+// returning -1, 0 or 1. Missing or non-numeric parts count as zero.
+func compareVersions(a, b string) int {
+	partsA := strings.Split(a, ".")
+	partsB := strings.Split(b, ".")
+	for i := 0; i < len(partsA) || i < len(partsB); i++ {
+		var x, y int
+		if i < len(partsA) {
+			x, _ = strconv.Atoi(partsA[i])
+		}
+		if i < len(partsB) {
+			y, _ = strconv.Atoi(partsB[i])
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
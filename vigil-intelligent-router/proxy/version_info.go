@@ -0,0 +1,33 @@
+package proxy
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/project-vigil/vigil-intelligent-router/config"
+)
+
+// BuildInfo describes the running build
+type BuildInfo struct {
+	Version   string `json:"version"`
+	GitCommit string `json:"git_commit"`
+	BuildTime string `json:"build_time"`
+	GoVersion string `json:"go_version"`
+}
+
+// VersionHandler serves the build information as JSON
+func VersionHandler(cfg *config.Config, info BuildInfo) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+		w.Header().Set("Content-Type", "application/json")
+
+		if r.Method == http.MethodOptions {
+			WritePreflight(w, r, cfg.CORSMaxAge)
+			return
+		}
+
+		json.NewEncoder(w).Encode(info)
+	}
+}
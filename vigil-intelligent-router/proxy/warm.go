@@ -0,0 +1,94 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+)
+
+// keepWarmBody is the cheap call used to keep node connections open
+var keepWarmBody = []byte(`{"jsonrpc":"2.0","id":1,"method":"getHealth"}`)
+
+// traceColdConnection records on the returned flag whether req ends up on a
+// newly dialed connection rather than a reused idle one. Such requests pay
+// for connection setup (and the TLS handshake) on top of the node's latency.
+func traceColdConnection(req *http.Request) (*http.Request, *atomic.Bool) {
+	cold := &atomic.Bool{}
+	trace := &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			cold.Store(!info.Reused)
+		},
+	}
+	return req.WithContext(httptrace.WithClientTrace(req.Context(), trace)), cold
+}
+
+// excludeColdLatency reports whether a request's latency should be left out
+// of calibration and other latency tracking under EXCLUDE_COLD_CONNECTIONS
+func (h *Handler) excludeColdLatency(nodeID string, cold *atomic.Bool) bool {
+	if !cold.Load() {
+		return false
+	}
+	h.coldRequests.Add(1)
+	if !h.config.ExcludeColdConnections {
+		return false
+	}
+	h.logger.Debug("Request used a new connection, latency not recorded",
+		zap.String("node", nodeID))
+	return true
+}
+
+// StartKeepWarm schedules keepalive requests to every node with
+// NODE_KEEP_WARM_<NODE_ID> set, so its connection never sits idle long enough
+// to be closed
+func (h *Handler) StartKeepWarm(tasks *TaskManager) {
+	if len(h.warmNodes()) == 0 {
+		return
+	}
+	tasks.Every("keep_warm", h.config.KeepWarmInterval, h.keepWarm)
+}
+
+func (h *Handler) warmNodes() map[string]string {
+	nodes := make(map[string]string)
+	for nodeID, warm := range h.config.NodeKeepWarm {
+		if warm {
+			nodes[nodeID] = h.config.NodeURLMap[nodeID]
+		}
+	}
+	return nodes
+}
+
+// keepWarm sends one keepalive request to each node that should stay warm
+func (h *Handler) keepWarm(ctx context.Context) {
+	for nodeID, url := range h.warmNodes() {
+		reqCtx, cancel := context.WithTimeout(ctx, h.config.RequestTimeout)
+		err := h.sendKeepWarm(reqCtx, url)
+		cancel()
+		if err != nil {
+			h.logger.Debug("Keep-warm request failed", zap.String("node", nodeID), zap.Error(err))
+		}
+	}
+}
+
+func (h *Handler) sendKeepWarm(ctx context.Context, url string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(keepWarmBody))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	h.setUpstreamAuth(req, url)
+
+	resp, err := h.clientFor(url).Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// The connection only returns to the idle pool once the body is read
+	_, err = io.Copy(io.Discard, io.LimitReader(resp.Body, maxDrainBytes))
+	return err
+}
@@ -0,0 +1,64 @@
+package proxy
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/project-vigil/vigil-intelligent-router/ml"
+	"go.uber.org/zap"
+)
+
+// inWarmup reports whether the router is still inside its startup warm-up
+// window, logging the transition out of it once
+func (h *Handler) inWarmup() bool {
+	if h.config.WarmupPeriod <= 0 || h.warmupDone.Load() {
+		return false
+	}
+	if time.Since(h.startedAt) < h.config.WarmupPeriod {
+		return true
+	}
+	if h.warmupDone.CompareAndSwap(false, true) {
+		h.logger.Info("Warm-up period complete, switching to hybrid routing",
+			zap.Duration("warmup", h.config.WarmupPeriod))
+	}
+	return false
+}
+
+// routeWarmup sends the request to the stable node during warm-up. When the
+// stable node is one the ML scored, calibration samples are still recorded so
+// hybrid routing starts with a baseline. It returns false without writing a
+// response when no stable target is available or it cannot be reached.
+func (h *Handler) routeWarmup(w http.ResponseWriter, r *http.Request, bodyBytes []byte, startTime time.Time, prediction *ml.PredictionResponse) bool {
+	nodeID, targetURL, ok := h.warmupTarget(r)
+	if !ok {
+		return false
+	}
+	if nodeID == "" {
+		h.logger.Debug("Warm-up routing to fallback", zap.String("url", targetURL))
+		return h.forwardRequest(w, r, targetURL, bodyBytes, startTime) == nil
+	}
+
+	h.logger.Debug("Warm-up routing to stable node", zap.String("node", nodeID))
+	if prediction.SelectNode(nodeID) {
+		prediction.MarkDecided()
+		return h.forwardRequestWithCalibration(w, r, targetURL, bodyBytes, startTime, prediction) == nil
+	}
+	return h.forwardRequest(w, r, targetURL, bodyBytes, startTime) == nil
+}
+
+// warmupTarget returns where warm-up traffic goes: WARMUP_NODE when it has a
+// URL mapping, otherwise the fallback or last-resort URL with no node ID
+func (h *Handler) warmupTarget(r *http.Request) (string, string, bool) {
+	if h.config.WarmupNode != "" {
+		if targetURL, err := h.mlClient.GetRecommendedNodeURL(h.config.WarmupNode); err == nil {
+			return h.config.WarmupNode, targetURL, true
+		}
+	}
+	switch {
+	case h.config.FallbackEnabled:
+		return "", h.fallbackURL(r), true
+	case h.config.LastResortNodeURL != "":
+		return "", h.config.LastResortNodeURL, true
+	}
+	return "", "", false
+}